@@ -9,23 +9,49 @@ import (
 	"github.com/gofiber/fiber/v2/log"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 
+	"gorm.io/gorm"
+
 	"jaQC-Go-API/utils"
 )
 
-type MainDatabase struct{ utils.SQLiteClient }
+/* THE CLIENT IS CHOSEN BY MAIN_DB_CONFIG; *gorm.DB IS SET ON Connect() SO REPOS CAN CALL MDB.Raw(...) ETC. */
+type MainDatabase struct {
+	utils.DBClient
+	*gorm.DB
+}
+
+var MDB MainDatabase
+
+/* DEFAULTS TO SQLITE IN DATA_DIR; main.go OVERRIDES FROM --db-driver / --db-dsn */
+var MAIN_DB_CONFIG = utils.DBConfig{
+	Driver: utils.DB_DRIVER_SQLITE,
+	Dir:    DATA_DIR,
+	Name:   MAIN_DB_NAME,
+}
 
-var MDB MainDatabase = MainDatabase{SQLiteClient: utils.SQLiteClient{}}
+func (mdb *MainDatabase) Connect() (err error) {
+
+	if err = mdb.DBClient.Connect(); err != nil {
+		return
+	}
+
+	mdb.DB = mdb.DBClient.Gorm()
+	return
+}
 
 var JWT utils.JWTConfiguration
 
 var EML utils.EmailConfiguration
 
+/* UPLOADS WAITING ON AN IMPORT JOB; REMOVED WHEN THE JOB ENDS */
+const IMPORT_FILE_DIR = DATA_DIR + "/imports"
+
 /* FILE SYSTEM **************************************************************************/
 func ConfigureFileSystem(clean bool) (err error) {
 	// log.Info("CONFIGURING FILE SYSTEM...")
 
 	if clean {
-		/* ARCHIVE DATA_DIR */
+		/* ARCHIVE DATASET AND BIN FILES; THE MAIN DATABASE IS MIGRATED DOWN BY ConfigureMainDatabase() */
 		if err = ArchiveAPIDirectories(); err != nil {
 			log.Info(err)
 		}
@@ -73,6 +99,12 @@ func ConfirmAPIDirectories() (err error) {
 	}
 	log.Info("ConfirmAPIDirectories( ) : ", BIN_FILE_DIR)
 
+	/* ENSURE THE IMPORT UPLOADS ROOT EXISTS */
+	if err = utils.ConfirmDirectory(IMPORT_FILE_DIR); err != nil {
+		return utils.LogErr(err)
+	}
+	log.Info("ConfirmAPIDirectories( ) : ", IMPORT_FILE_DIR)
+
 	return
 }
 
@@ -101,10 +133,6 @@ func ArchiveAPIDirectories() (err error) {
 			return 
 		}
 	
-		if arc_err = utils.ArchiveDirectoryContents(DATA_DIR+"/"+MAIN_DB_NAME, arc+"/"+MAIN_DB_NAME); arc_err != nil {
-			err = arc_err
-			return 
-		}
 
 		log.Info("ARCHIVE API DIRECTORIES : OK : ", arc)
 		return
@@ -116,55 +144,44 @@ func ArchiveAPIDirectories() (err error) {
 }
 
 /* MAIN DATABASE **********************************************************************/
-func ConfigureMainDatabase(dir, name string, clean bool) (err error) {
+func ConfigureMainDatabase(cfg utils.DBConfig, clean bool, target int64, dryRun bool) (err error) {
 	/* MIGRATE MAIN DB & CONNECT -> DISCONNECT MUST BE HANDLED EXPLICITLY */
 
-	if err = utils.ConfirmDirectory(dir); err != nil {
+	if MDB.DBClient, err = utils.ConfigureDBClient(cfg); err != nil {
 		return
 	}
 
-	if MDB.SQLiteClient, err = utils.ConfigureDBSQLiteClient(dir, name); err != nil {
+	if err = MDB.Connect(); err != nil {
 		return
 	}
 
-	if err = MDB.Connect(); err != nil {
+	/* CLEAN MIGRATES DOWN TO NOTHING ON EVERY DRIVER; ConfigureFileSystem() ONLY ARCHIVES DATASET AND BIN FILES */
+	if clean && !dryRun {
+		log.Info("CLEAN : DROPPING MAIN DATABASE SCHEMA : ", MDB.ConnName())
+		if _, err = utils.Migrate(MDB.DB, MAIN_DB_MIGRATIONS, 0, false); err != nil {
+			return
+		}
+	}
+
+	/* BRING THE SCHEMA TO target; SEE migrations.go */
+	ran, err := utils.Migrate(MDB.DB, MAIN_DB_MIGRATIONS, target, dryRun)
+	if err != nil {
 		return
 	}
 
-	/* CREATE OR MIGRATE TABLE MODELS */
-	if MDB.ConnectionOK() {
-		if err = MDB.AutoMigrate(
-	
-			/* TABLES */
-			User{},
-			// Gizmo{},
-			// Calibration{},
-			// Dataset{},
-			// Process{},
-			// Variate{},
-			Aggregate{},
-			// Correlate{},
-	
-		); err != nil {
-			log.Fatal(err)
-		}
-	} else {
-		if err = MDB.Migrator().CreateTable(
-	
-			/* TABLES */
-			User{},
-			// Gizmo{},
-			// Calibration{},
-			// Dataset{},
-			// Process{},
-			// Variate{},
-			Aggregate{},
-			// Correlate{},
-	
-		); err != nil {
-			log.Fatal(err)
+	if dryRun {
+		for _, m := range ran {
+			log.Info(fmt.Sprintf("MIGRATION DRY RUN : %04d %s : OK", m.Version, m.Name))
 		}
+		log.Info(fmt.Sprintf("MIGRATION DRY RUN : %d MIGRATIONS WOULD RUN; NOTHING CHANGED", len(ran)))
+		return
+	}
+
+	version, err := utils.SchemaVersion(MDB.DB)
+	if err != nil {
+		return
 	}
+	log.Info("MAIN DATABASE SCHEMA VERSION : ", version)
 
 	if ( clean ) {
 		urinp := UserRegistrationInput{ Password: SPR_PW }
@@ -180,18 +197,26 @@ func ConfigureMainDatabase(dir, name string, clean bool) (err error) {
 		}
 	}
 
-	log.Info("MAIN DATABASE CONFIGURED : ", MDB.Conn)
+	log.Info("MAIN DATABASE CONFIGURED : ", MDB.Driver(), " : ", MDB.ConnName())
 	return 
 }
 
 var TBL_USERS = (User{}).TableName()
-// var TBL_GIZMOS = (Gizmo{}).TableName()
-// var TBL_CALS = (Calibration{}).TableName()
-// var TBL_DATS = (Dataset{}).TableName()
-// var TBL_PROCS = (Process{}).TableName()
-// var TBL_VARS = (Variate{}).TableName()
+var TBL_GIZMOS = (Gizmo{}).TableName()
+var TBL_CALS = (Calibration{}).TableName()
+var TBL_DATS = (Dataset{}).TableName()
+var TBL_PROCS = (Process{}).TableName()
+var TBL_VARS = (Variate{}).TableName()
 var TBL_AGGS = (Aggregate{}).TableName()
-// var TBL_CRLTS = (Correlate{}).TableName()
+var TBL_CRLTS = (Correlate{}).TableName()
+var TBL_JOBS = (Job{}).TableName()
+var TBL_CAPS = (Capability{}).TableName()
+var TBL_SCORES = (ScoreRuleSet{}).TableName()
+var TBL_NOTES = (Notification{}).TableName()
+var TBL_NOTE_RECS = (NotificationRecipient{}).TableName()
+
+/* DATASET DATABASE TABLES */
+var TBL_SAMPLES = (Sample{}).TableName()
 
 func ConfigureCORS(app *fiber.App, origins, headers, methods string, cred bool) {
 	app.Use(cors.New(cors.Config{
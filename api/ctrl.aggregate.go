@@ -0,0 +1,33 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func HandleGetAggregateList(c *fiber.Ctx) (err error) {
+
+	aq := AggregateQuery{}
+	if err = c.QueryParser(&aq); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	if aq.Summary {
+		if aq.Bins > AGG_SUMMARY_MAX_BINS {
+			return c.Status(fiber.StatusBadRequest).SendString(fmt.Sprintf("bins must be at most %d", AGG_SUMMARY_MAX_BINS))
+		}
+		sums, sum_err := aq.GetAggregateSummary()
+		if sum_err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString(sum_err.Error())
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"summary": sums})
+	}
+
+	aggs, next, err := aq.GetAggregateList()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"aggregates": aggs, "next_cursor": next})
+}
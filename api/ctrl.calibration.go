@@ -0,0 +1,260 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"jaQC-Go-API/utils"
+)
+
+const JOB_TYPE_RECAL string = "recalibrate"
+
+func (cinp *CalibrationInput) Validate() (err error) {
+
+	cinp.Channel = strings.TrimSpace(cinp.Channel)
+	if cinp.Channel == "" {
+		return fmt.Errorf("calibration channel is required")
+	}
+
+	if cinp.EffectiveFrom <= 0 {
+		return fmt.Errorf("calibration effective_from is required")
+	}
+
+	switch cinp.Method {
+	case CAL_METHOD_POLY:
+		if len(cinp.Coefficients) == 0 {
+			return fmt.Errorf("polynomial calibration needs at least one coefficient")
+		}
+	case CAL_METHOD_TABLE:
+		if err = utils.ValidateLookupTable(cinp.TableX, cinp.TableY); err != nil {
+			return
+		}
+	default:
+		return fmt.Errorf("invalid calibration method: %s", cinp.Method)
+	}
+	return
+}
+
+func (cinp *CalibrationInput) Calibration() Calibration {
+	return Calibration{
+		Channel:       cinp.Channel,
+		Unit:          cinp.Unit,
+		Method:        cinp.Method,
+		Coefficients:  cinp.Coefficients,
+		TableX:        cinp.TableX,
+		TableY:        cinp.TableY,
+		EffectiveFrom: cinp.EffectiveFrom,
+		Certificate:   cinp.Certificate,
+		PerformedBy:   cinp.PerformedBy,
+	}
+}
+
+/* 
+CONVERTS raw TO ENGINEERING UNITS USING THE CALIBRATION VALID AT EACH SAMPLE'S TIMESTAMP
+cals MUST BE IN-FORCE AND SORTED BY EffectiveFrom ( GetCalibrationListByChannel )
+SAMPLES BEFORE THE FIRST CALIBRATION ARE PASSED THROUGH AND COUNTED IN uncal
+*/
+func ApplyCalibrations(cals []Calibration, raw utils.TSXY) (eng utils.TSXY, uncal int) {
+
+	eng.X = raw.X
+	eng.Y = make([]float32, len(raw.Y))
+
+	for i, x := range raw.X {
+		cal := CalibrationAt(cals, x)
+		if cal == nil {
+			eng.Y[i] = raw.Y[i]
+			uncal++
+			continue
+		}
+		eng.Y[i] = float32(cal.Apply(float64(raw.Y[i])))
+	}
+	return
+}
+
+/* THE CALIBRATION IN FORCE AT x; cals SORTED BY EffectiveFrom; nil BEFORE THE FIRST */
+func CalibrationAt(cals []Calibration, x int64) *Calibration {
+	/* INDEX OF THE FIRST CALIBRATION THAT TAKES EFFECT AFTER x */
+	c := sort.Search(len(cals), func(j int) bool { return cals[j].EffectiveFrom > x })
+	if c == 0 {
+		return nil
+	}
+	return &cals[c-1]
+}
+
+/* THE TIME RANGE [ start, end ) A CALIBRATION APPLIES TO; end == 0 MEANS OPEN ENDED */
+func (cal *Calibration) Window() (start, end int64, err error) {
+
+	cals, err := GetCalibrationListByChannel(cal.GID, cal.Channel)
+	if err != nil {
+		return
+	}
+
+	start = cal.EffectiveFrom
+	for _, c := range cals {
+		if c.EffectiveFrom > cal.EffectiveFrom {
+			end = c.EffectiveFrom
+			break
+		}
+	}
+	return
+}
+
+/* RE-RUNS ANALYTICS FOR EVERYTHING THE CALIBRATION TOUCHES AS A BACKGROUND JOB */
+func (cal *Calibration) StartReprocess(uid, from int64) (job *Job, err error) {
+
+	start, end, err := cal.Window()
+	if err != nil {
+		return
+	}
+
+	/* A CORRECTION MAY MOVE EffectiveFrom EARLIER THAN THE ORIGINAL */
+	if from > 0 && from < start {
+		start = from
+	}
+
+	label := fmt.Sprintf("gizmo %d / %s : calibration %d", cal.GID, cal.Channel, cal.ID)
+	return StartJob(JOB_TYPE_RECAL, label, uid, func(job *Job) (ref string, err error) {
+		if err = ReprocessChannelWindow(job, cal.GID, cal.Channel, start, end); err != nil {
+			return
+		}
+		ref = fmt.Sprintf("calibrations/%d", cal.ID)
+		return
+	})
+}
+
+/* RECOMPUTES ANALYTICS FOR ONE GIZMO CHANNEL OVER [ start, end ) */
+func ReprocessChannelWindow(job *Job, gid int64, channel string, start, end int64) (err error) {
+
+	vrts, err := GetVariateListByGizmoChannel(gid, channel)
+	if err != nil {
+		return
+	}
+
+	for i, vrt := range vrts {
+
+		/* ONLY VARIATES SET UP FOR CLUSTERING HAVE AGGREGATES TO REBUILD */
+		if _, cfg_err := vrt.ClusterConfig(); cfg_err != nil {
+			continue
+		}
+
+		done := float32(i)
+		progress := func(curr, end float32) { job.Progress(done+curr/end, float32(len(vrts))) }
+		if _, err = vrt.Recluster(job.Context(), job.Owner, start, end, progress); err != nil {
+			return
+		}
+	}
+
+	job.Progress(1, 1)
+	return
+}
+
+/* PARSES :id AND RETURNS THE CALIBRATION */
+func paramCalibration(c *fiber.Ctx) (cal Calibration, err error) {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		err = fiber.NewError(fiber.StatusBadRequest, "invalid calibration id")
+		return
+	}
+	if cal, err = GetCalibrationByID(int64(id)); err != nil {
+		err = fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+	return
+}
+
+func HandleGetGizmoCalibrationList(c *fiber.Ctx) (err error) {
+	giz, err := paramGizmo(c)
+	if err != nil {
+		return
+	}
+
+	cals, err := GetCalibrationListByGizmo(giz.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"calibrations": cals})
+}
+
+func HandleGetCalibration(c *fiber.Ctx) (err error) {
+	cal, err := paramCalibration(c)
+	if err != nil {
+		return
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"calibration": cal})
+}
+
+func HandleCreateCalibration(c *fiber.Ctx) (err error) {
+
+	giz, err := paramGizmo(c)
+	if err != nil {
+		return
+	}
+
+	cinp := CalibrationInput{}
+	if err = utils.ParseRequestBody(c, &cinp); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err = cinp.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	cal := cinp.Calibration()
+	cal.GID = giz.ID
+	if err = cal.Create(LocalsUserID(c)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"calibration": cal})
+}
+
+/* SUPERSEDES :id WITH THE POSTED CALIBRATION AND REPROCESSES THE AFFECTED DATA */
+func HandleCorrectCalibration(c *fiber.Ctx) (err error) {
+
+	cal, err := paramCalibration(c)
+	if err != nil {
+		return
+	}
+	if cal.SupersededBy != 0 {
+		return c.Status(fiber.StatusConflict).SendString(
+			fmt.Sprintf("calibration %d was already corrected by %d", cal.ID, cal.SupersededBy))
+	}
+
+	cinp := CalibrationInput{}
+	if err = utils.ParseRequestBody(c, &cinp); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	cinp.Channel = cal.Channel
+	if err = cinp.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	uid := LocalsUserID(c)
+	corr := cinp.Calibration()
+	if err = cal.Correct(&corr, uid); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	job, err := corr.StartReprocess(uid, cal.EffectiveFrom)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"calibration": corr, "job": job})
+}
+
+func HandleReprocessCalibration(c *fiber.Ctx) (err error) {
+
+	cal, err := paramCalibration(c)
+	if err != nil {
+		return
+	}
+
+	job, err := cal.StartReprocess(LocalsUserID(c), 0)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"job": job})
+}
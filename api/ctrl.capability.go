@@ -0,0 +1,133 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+
+	"jaQC-Go-API/utils"
+)
+
+func (cinp *CapabilityInput) Config() (cfg utils.CapabilityConfig, err error) {
+	if cinp.End != 0 && cinp.End <= cinp.Start {
+		err = fmt.Errorf("capability end must be after start")
+		return
+	}
+	cfg = utils.CapabilityConfig{
+		Subgroup:   cinp.Subgroup,
+		Confidence: cinp.Confidence,
+	}
+	if err = cfg.Validate(); err != nil {
+		return
+	}
+	cfg.Defaults()
+	return
+}
+
+/* CAPABILITY OF THE VARIATE'S CALIBRATED SAMPLES OVER [ start, end ), CLIPPED TO THE PROCESS WINDOW; NOT STORED */
+func (vrt *Variate) Capability(cfg utils.CapabilityConfig, start, end int64) (pcap Capability, err error) {
+
+	if !vrt.Spec.Set() {
+		err = fmt.Errorf("variate %d has no spec limits", vrt.ID)
+		return
+	}
+
+	proc, err := GetProcessByID(vrt.PID)
+	if err != nil {
+		return
+	}
+	start, end, ok := proc.Window(start, end)
+	if !ok {
+		err = fmt.Errorf("capability window is outside process %d", proc.ID)
+		return
+	}
+
+	ts, err := vrt.GetTSXY(start, end)
+	if err != nil {
+		return
+	}
+
+	pcap = Capability{
+		PID:        vrt.PID,
+		VID:        vrt.ID,
+		Start:      start,
+		End:        end,
+		Subgroup:   cfg.Subgroup,
+		Confidence: cfg.Confidence,
+		Spec:       vrt.Spec,
+	}
+	pcap.Capability, err = utils.ComputeCapability(ts, vrt.Spec, cfg)
+	return
+}
+
+/* HANDLERS ******************************************************************************/
+func HandleGetVariateCapabilityList(c *fiber.Ctx) (err error) {
+
+	vrt, err := paramVariate(c)
+	if err != nil {
+		return
+	}
+
+	caps, err := GetCapabilityListByVariate(vrt.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	if caps == nil {
+		caps = []Capability{}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"capabilities": caps})
+}
+
+/* COMPUTES AND STORES A CAPABILITY REPORT */
+func HandleCreateVariateCapability(c *fiber.Ctx) (err error) {
+
+	vrt, err := paramVariate(c)
+	if err != nil {
+		return
+	}
+
+	cinp := CapabilityInput{}
+	if err = utils.ParseRequestBody(c, &cinp); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	cfg, err := cinp.Config()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	/* TOO FEW SAMPLES, NO VARIATION OR NO SPEC ARE THE CALLER'S TO FIX */
+	pcap, err := vrt.Capability(cfg, cinp.Start, cinp.End)
+	if err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+	}
+	if err = pcap.Create(LocalsUserID(c)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"capability": pcap})
+}
+
+/* STORED CAPABILITIES KEEP THE SPEC THEY WERE COMPUTED AGAINST */
+func HandleUpdateVariateSpec(c *fiber.Ctx) (err error) {
+
+	vrt, err := paramVariate(c)
+	if err != nil {
+		return
+	}
+
+	spec := utils.Spec{}
+	if err = utils.ParseRequestBody(c, &spec); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err = spec.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	vrt.Spec = spec
+	if err = vrt.Update(LocalsUserID(c)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"variate": vrt})
+}
@@ -0,0 +1,475 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"jaQC-Go-API/utils"
+)
+
+const JOB_TYPE_CLUSTER string = "cluster"
+
+/* CHECK FOR CANCEL AND REPORT PROGRESS EVERY N SAMPLES */
+const CLUSTER_PROGRESS_SAMPLES int64 = 10000
+
+/* LONGEST SERIES GET /changepoints SEGMENTS; PELT GOES QUADRATIC WHEN FEW CHANGES PRUNE IT */
+const CHANGE_MAX_POINTS int = 20000
+
+var errChangePoints = errors.New("change point window holds too many samples")
+
+func ClusterStreamsMapRead(vid int64) (cs *ClusterStream, ok bool) {
+	ClusterStreamsRWMutex.Lock()
+	cs, ok = ClusterStreams[vid]
+	ClusterStreamsRWMutex.Unlock()
+	return
+}
+func ClusterStreamsMapRemove(vid int64) {
+	ClusterStreamsRWMutex.Lock()
+	delete(ClusterStreams, vid)
+	ClusterStreamsRWMutex.Unlock()
+}
+
+/* STORES cs UNLESS A STREAM FOR THE VARIATE ALREADY EXISTS; RETURNS THE ONE IN THE MAP */
+func ClusterStreamsMapWrite(cs *ClusterStream) *ClusterStream {
+	ClusterStreamsRWMutex.Lock()
+	defer ClusterStreamsRWMutex.Unlock()
+	if live, ok := ClusterStreams[cs.ID]; ok {
+		return live
+	}
+	ClusterStreams[cs.ID] = cs
+	return cs
+}
+
+/* THE VARIATE'S CLUSTER SETTINGS WITH ZERO FIELDS FILLED FROM ITS EXPECTED RANGE AND SAMPLE RATE */
+func (vrt *Variate) ClusterConfig() (cfg utils.ClusterConfig, err error) {
+
+	cfg = vrt.Cluster
+	if cfg.MinSize == 0 {
+		cfg.MinSize = CLUSTER_MIN_SIZE
+	}
+	if cfg.MaxSize == 0 {
+		cfg.MaxSize = CLUSTER_MAX_SIZE
+	}
+	if span := vrt.ExpectMax - vrt.ExpectMin; cfg.MaxDevi == 0 && span > 0 {
+		cfg.MaxDevi = span * CLUSTER_DEVI_FRACTION
+	}
+	if cfg.MaxGap == 0 && vrt.SampleRate > 0 {
+		cfg.MaxGap = int64(CLUSTER_GAP_PERIODS * 1000 / vrt.SampleRate)
+	}
+
+	if err = cfg.Validate(); err != nil {
+		err = fmt.Errorf("variate %d: %s; set cluster.max_devi or an expected range", vrt.ID, err.Error())
+	}
+	return
+}
+
+func NewClusterStream(vrt Variate, proc Process, owner int64) (cs *ClusterStream, err error) {
+
+	cfg, err := vrt.ClusterConfig()
+	if err != nil {
+		return
+	}
+	if err = vrt.Filters.Validate(); err != nil {
+		err = fmt.Errorf("variate %d: %s", vrt.ID, err.Error())
+		return
+	}
+	rs, err := GetLatestScoreRuleSet(vrt.ID)
+	if err != nil {
+		return
+	}
+
+	cs = &ClusterStream{
+		Variate: vrt,
+		Proc:    proc,
+		Owner:   owner,
+		cfg:     cfg,
+		rs:      rs,
+		mut:     &sync.Mutex{},
+	}
+	cs.clr = &utils.Clusterer{Config: cfg, OnClose: cs.onClose}
+	cs.flt = vrt.Filters.New()
+
+	/* WITHOUT LIVE SPC THE AGGREGATES STAY VALID UNTIL AN SPC JOB JUDGES THEM */
+	if spc_err := cs.watchSPC(); spc_err != nil {
+		utils.LogErr(spc_err)
+	}
+	return
+}
+
+/* SAMPLES OUTSIDE THE PROCESS WINDOW ARE IGNORED; SPC SEES THEM UNFILTERED, AS SPC JOBS DO */
+func (cs *ClusterStream) Push(x int64, y float32) {
+	if x < cs.Proc.Start || (cs.Proc.End != 0 && x >= cs.Proc.End) {
+		return
+	}
+	cs.mut.Lock()
+	cs.pushSPC(x, y)
+	cs.flt.Push(x, float64(y), cs.cluster)
+	cs.mut.Unlock()
+}
+
+/* RECEIVES FILTERED SAMPLES, UNDER cs.mut */
+func (cs *ClusterStream) cluster(x int64, y float64) {
+	cs.clr.Push(x, float32(y))
+}
+
+/* CLOSES THE OPEN CLUSTER; RETURNS THE FIRST ERROR WRITING ANY AGGREGATE */
+func (cs *ClusterStream) Flush() (err error) {
+	cs.mut.Lock()
+	cs.flt.Flush(cs.cluster)
+	cs.clr.Flush()
+	err = cs.err
+	cs.mut.Unlock()
+	return
+}
+
+/* CALLED BY THE Clusterer, UNDER cs.mut */
+func (cs *ClusterStream) onClose(st utils.ClusterStats) {
+
+	agg := cs.Aggregate(st)
+	cs.judgeSPC(&agg)
+	if err := agg.Create(cs.Owner); err != nil {
+		if cs.err == nil {
+			cs.err = err
+		}
+		utils.LogErr(err)
+		return
+	}
+	cs.Count++
+
+	src := fmt.Sprintf("variates/%d", cs.ID)
+	for _, ussn := range UserSessionsMapCopy() {
+		ussn.WSSendClusterMessage(src, agg)
+	}
+}
+
+/*
+WITH A SCORE RULE SET THE SCORE AND VERDICT ARE ITS OWN; SEE utils.ScoreRules
+OTHERWISE THE SCORE IS 1 FOR A FLAT, QUIET CLUSTER AND FALLS TO 0 AT THE CONFIGURED LIMITS
+*/
+func (cs *ClusterStream) Aggregate(st utils.ClusterStats) Aggregate {
+
+	agg := Aggregate{
+		PID:   cs.PID,
+		VID:   cs.ID,
+		ADate: time.Now().UTC().UnixMilli(),
+		Start: st.Start,
+		End:   st.End,
+		Size:  st.Size,
+		Min:   st.Min,
+		Max:   st.Max,
+		Mean:  st.Mean,
+		Slope: st.Slope,
+		Devi:  st.Devi,
+		Valid: true,
+	}
+	if cs.rs != nil {
+		cs.rs.Score(&agg)
+		return agg
+	}
+
+	score := float64(1)
+	if cs.cfg.MaxDevi > 0 {
+		score = 1 - float64(st.Devi/cs.cfg.MaxDevi)
+	}
+	if cs.cfg.MaxSlope > 0 {
+		score = math.Min(score, 1-math.Abs(float64(st.Slope/cs.cfg.MaxSlope)))
+	}
+	agg.Score = float32(math.Max(0, math.Min(1, score)))
+	return agg
+}
+
+/*
+FEEDS NEWLY WRITTEN RAW SAMPLES OF A DATASET CHANNEL TO THE LIVE STREAM OF EVERY VARIATE RECORDING IT
+VARIATES WITHOUT A USABLE CLUSTER CONFIG ARE SKIPPED
+*/
+func FeedClusterStreams(did int64, channel string, raw utils.TSXY, owner int64) (err error) {
+
+	vrts, err := GetVariateListByDatasetChannel(did, channel)
+	if err != nil {
+		return
+	}
+
+	for _, vrt := range vrts {
+
+		cs, ok := ClusterStreamsMapRead(vrt.ID)
+		if !ok {
+			proc, proc_err := GetProcessByID(vrt.PID)
+			if proc_err != nil {
+				return proc_err
+			}
+			if cs, err = NewClusterStream(vrt, proc, owner); err != nil {
+				err = nil
+				continue
+			}
+			cs = ClusterStreamsMapWrite(cs)
+		}
+
+		cals, cal_err := GetCalibrationListByChannel(cs.Proc.GID, channel)
+		if cal_err != nil {
+			return cal_err
+		}
+		eng, _ := ApplyCalibrations(cals, raw)
+		for i, x := range eng.X {
+			cs.Push(x, eng.Y[i])
+		}
+	}
+	return
+}
+
+/* CLOSES AND REMOVES THE LIVE STREAMS match SELECTS; nil SELECTS ALL ( SHUTDOWN ) */
+func FlushClusterStreams(match func(cs *ClusterStream) bool) (err error) {
+
+	ClusterStreamsRWMutex.Lock()
+	flush := []*ClusterStream{}
+	for vid, cs := range ClusterStreams {
+		if match == nil || match(cs) {
+			flush = append(flush, cs)
+			delete(ClusterStreams, vid)
+		}
+	}
+	ClusterStreamsRWMutex.Unlock()
+
+	for _, cs := range flush {
+		if fl_err := cs.Flush(); fl_err != nil && err == nil {
+			err = fl_err
+		}
+	}
+	return
+}
+
+/* REBUILDS THE VARIATE'S AGGREGATES OVER [ start, end ) FROM ITS CALIBRATED SAMPLES; end == 0 MEANS OPEN ENDED */
+func (vrt *Variate) Recluster(ctx context.Context, owner, start, end int64, progress func(curr, end float32)) (count int64, err error) {
+
+	proc, err := GetProcessByID(vrt.PID)
+	if err != nil {
+		return
+	}
+
+	/* CLUSTERS NEVER CROSS THE PROCESS WINDOW */
+	start, end, ok := proc.Window(start, end)
+	if !ok {
+		return
+	}
+
+	cs, err := NewClusterStream(*vrt, proc, owner)
+	if err != nil {
+		return
+	}
+
+	ds, err := GetDatasetByID(proc.DID)
+	if err != nil {
+		return
+	}
+	ddb, err := ds.Open()
+	if err != nil {
+		return
+	}
+
+	cals, err := GetCalibrationListByChannel(proc.GID, vrt.Channel)
+	if err != nil {
+		return
+	}
+
+	/* A LIVE STREAM WOULD WRITE OVER THE WINDOW WHILE WE REBUILD IT */
+	if err = FlushClusterStreams(func(live *ClusterStream) bool { return live.ID == vrt.ID }); err != nil {
+		return
+	}
+	if start, end, err = DeleteVariateAggregates(vrt.ID, start, end); err != nil {
+		return
+	}
+
+	last := end
+	if last == 0 {
+		last = ds.LastX
+	}
+
+	var n int64
+	err = ddb.StreamSamples(vrt.Channel, start, end, vrt.QualityMask(), func(x int64, raw float32, q uint8) error {
+		y := raw
+		if cal := CalibrationAt(cals, x); cal != nil {
+			y = float32(cal.Apply(float64(raw)))
+		}
+		cs.Push(x, y)
+
+		n++
+		if n%CLUSTER_PROGRESS_SAMPLES == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if progress != nil && last > start {
+				progress(float32(x-start), float32(last-start))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	if err = cs.Flush(); err != nil {
+		return
+	}
+	count = cs.Count
+
+	/* RUN RULES NEED WHAT CAME BEFORE THE WINDOW, SO JUDGE THE WHOLE PROCESS */
+	if vrt.SPC.Enabled() {
+		if _, spc_err := vrt.ApplySPC(ctx, owner, 0, 0, nil); spc_err != nil {
+			utils.LogErr(spc_err)
+		}
+	}
+	return
+}
+
+/*
+BOUNDARIES FOR THE VARIATE'S CALIBRATED SAMPLES OVER [ start, end ); ANY METHOD BUT cusum IS pelt
+MORE THAN CHANGE_MAX_POINTS SAMPLES IS AN errChangePoints
+*/
+func (vrt *Variate) ChangePoints(cpq ChangePointQuery) (cps []utils.ChangePoint, err error) {
+
+	ts, err := vrt.GetTSXY(cpq.Start, cpq.End)
+	if err != nil {
+		return
+	}
+	if len(ts.X) > CHANGE_MAX_POINTS {
+		err = fmt.Errorf("%w; %d samples, limit %d", errChangePoints, len(ts.X), CHANGE_MAX_POINTS)
+		return
+	}
+
+	if cpq.Method == utils.CLUSTER_METHOD_CUSUM {
+		cps = utils.CUSUM(ts, cpq.Threshold, cpq.Drift, cpq.MinSize)
+	} else {
+		cps = utils.PELT(ts, cpq.Penalty, cpq.MinSize)
+	}
+	return
+}
+
+/* HANDLERS ******************************************************************************/
+func HandleGetVariateChangePoints(c *fiber.Ctx) (err error) {
+
+	vrt, err := paramVariate(c)
+	if err != nil {
+		return
+	}
+
+	cpq := ChangePointQuery{}
+	if err = c.QueryParser(&cpq); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	switch cpq.Method {
+	case "", utils.CLUSTER_METHOD_PELT, utils.CLUSTER_METHOD_CUSUM:
+	default:
+		return c.Status(fiber.StatusBadRequest).SendString("invalid change point method: " + cpq.Method)
+	}
+	if cpq.Threshold < 0 || cpq.Drift < 0 || cpq.Penalty < 0 || cpq.MinSize < 0 {
+		return c.Status(fiber.StatusBadRequest).SendString("change point parameters must not be negative")
+	}
+
+	cps, err := vrt.ChangePoints(cpq)
+	if errors.Is(err, errChangePoints) {
+		return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	if cps == nil {
+		cps = []utils.ChangePoint{}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"change_points": cps})
+}
+
+func HandleUpdateVariateCluster(c *fiber.Ctx) (err error) {
+
+	vrt, err := paramVariate(c)
+	if err != nil {
+		return
+	}
+
+	cfg := utils.ClusterConfig{}
+	if err = utils.ParseRequestBody(c, &cfg); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	vrt.Cluster = cfg
+	if _, err = vrt.ClusterConfig(); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err = vrt.Update(LocalsUserID(c)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	/* THE NEXT SAMPLES START A STREAM WITH THE NEW SETTINGS */
+	if err = FlushClusterStreams(func(cs *ClusterStream) bool { return cs.ID == vrt.ID }); err != nil {
+		utils.LogErr(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"variate": vrt})
+}
+
+/* AGGREGATES ARE NOT REBUILT; POST /api/variates/:id/clusters PICKS UP THE NEW CHAIN */
+func HandleUpdateVariateFilters(c *fiber.Ctx) (err error) {
+
+	vrt, err := paramVariate(c)
+	if err != nil {
+		return
+	}
+
+	finp := FiltersInput{}
+	if err = utils.ParseRequestBody(c, &finp); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err = finp.Filters.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	vrt.Filters = finp.Filters
+	if err = vrt.Update(LocalsUserID(c)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	/* THE NEXT SAMPLES START A STREAM WITH THE NEW CHAIN */
+	if err = FlushClusterStreams(func(cs *ClusterStream) bool { return cs.ID == vrt.ID }); err != nil {
+		utils.LogErr(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"variate": vrt})
+}
+
+func HandleStartClustering(c *fiber.Ctx) (err error) {
+
+	vrt, err := paramVariate(c)
+	if err != nil {
+		return
+	}
+
+	cinp := ClusterInput{}
+	if err = utils.ParseRequestBody(c, &cinp); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if cinp.End != 0 && cinp.End <= cinp.Start {
+		return c.Status(fiber.StatusBadRequest).SendString("cluster end must be after start")
+	}
+	if _, err = vrt.ClusterConfig(); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	label := fmt.Sprintf("variate %d : clustering", vrt.ID)
+	job, err := StartJob(JOB_TYPE_CLUSTER, label, LocalsUserID(c), func(job *Job) (ref string, err error) {
+		if _, err = vrt.Recluster(job.Context(), job.Owner, cinp.Start, cinp.End, job.Progress); err != nil {
+			return
+		}
+		ref = fmt.Sprintf("aggregates?vid=%d", vrt.ID)
+		return
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"job": job})
+}
@@ -0,0 +1,270 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"jaQC-Go-API/utils"
+)
+
+const JOB_TYPE_CORRELATE string = "correlate"
+const CORR_DEFAULT_STEP = int64(1000)
+const CORR_MAX_BUCKETS = int64(1000000)
+const CORR_GAP_STEPS = int64(10)       // LONGEST GAP INTERPOLATED BY DEFAULT
+const CORR_MAX_LAG_STEPS = int64(1000) // EACH LAG STEP IS ANOTHER PASS OVER THE GRID, EACH WAY
+
+/* FILLS DEFAULTS FROM THE PROCESS AND CHECKS THE GRID SIZE */
+func (cinp *CorrelationInput) Validate(proc Process) (err error) {
+
+	if cinp.Start == 0 {
+		cinp.Start = proc.Start
+	}
+	if cinp.End == 0 {
+		cinp.End = proc.End
+	}
+	if cinp.End == 0 {
+		cinp.End = time.Now().UTC().UnixMilli()
+	}
+	if cinp.Step == 0 {
+		cinp.Step = CORR_DEFAULT_STEP
+	}
+	if cinp.MaxGap == 0 {
+		cinp.MaxGap = CORR_GAP_STEPS * cinp.Step
+	}
+
+	if cinp.Step < 0 || cinp.MaxLag < 0 {
+		return fmt.Errorf("step and max_lag must be positive")
+	}
+	if cinp.End <= cinp.Start {
+		return fmt.Errorf("correlation window end is before start")
+	}
+	buckets := (cinp.End - cinp.Start) / cinp.Step
+	if buckets > CORR_MAX_BUCKETS {
+		return fmt.Errorf("correlation window is too large for step %d ms", cinp.Step)
+	}
+	if lags := cinp.MaxLag / cinp.Step; lags > min(CORR_MAX_LAG_STEPS, buckets/2) {
+		return fmt.Errorf("max_lag must be at most %d steps and half the window", CORR_MAX_LAG_STEPS)
+	}
+	rsc := cinp.Resample()
+	return rsc.Validate()
+}
+
+func (cinp *CorrelationInput) Resample() utils.ResampleConfig {
+	return utils.ResampleConfig{
+		Start:  cinp.Start,
+		Step:   cinp.Step,
+		N:      int((cinp.End - cinp.Start) / cinp.Step),
+		Interp: cinp.Interp,
+		Agg:    cinp.Agg,
+		MaxGap: cinp.MaxGap,
+	}
+}
+
+/* COMPUTES EVERY VARIATE PAIR AND REPLACES THE PROCESS'S CORRELATES */
+func (cinp *CorrelationInput) Correlate(job *Job, proc Process) (err error) {
+
+	vrts, err := GetVariateListByProcess(proc.ID)
+	if err != nil {
+		return
+	}
+	if len(vrts) < 2 {
+		return fmt.Errorf("process %d needs at least 2 variates to correlate", proc.ID)
+	}
+
+	/* PUT EVERY VARIATE ON THE SAME GRID */
+	rsc := cinp.Resample()
+	start, end := rsc.Span()
+	grid := make([][]float64, len(vrts))
+	for i, vrt := range vrts {
+		ts, ts_err := vrt.GetTSXY(start, end)
+		if ts_err != nil {
+			return ts_err
+		}
+		grid[i] = utils.Resample(ts, rsc)
+	}
+
+	maxLag := int(cinp.MaxLag / cinp.Step)
+	pairs := float32(len(vrts) * (len(vrts) - 1) / 2)
+	done := float32(0)
+
+	crlts := []Correlate{}
+	for a := 0; a < len(vrts); a++ {
+		for b := a + 1; b < len(vrts); b++ {
+
+			if err = job.Context().Err(); err != nil {
+				return
+			}
+
+			base := Correlate{
+				PID:   proc.ID,
+				VIDA:  vrts[a].ID,
+				VIDB:  vrts[b].ID,
+				Start: cinp.Start,
+				End:   cinp.End,
+				Step:  cinp.Step,
+			}
+			base.CreatedBy = job.Owner
+
+			xs, ys := utils.PairwiseComplete(grid[a], grid[b])
+			if r, r_err := utils.Pearson(xs, ys); r_err == nil {
+				crlts = append(crlts, base.with(CORR_METHOD_PEARSON, 0, r, len(xs)))
+			}
+			if r, r_err := utils.Spearman(xs, ys); r_err == nil {
+				crlts = append(crlts, base.with(CORR_METHOD_SPEARMAN, 0, r, len(xs)))
+			}
+
+			if maxLag > 0 {
+				lags, rs, ns := utils.CrossCorrelation(grid[a], grid[b], maxLag)
+				best := -1
+				for i := range rs {
+					if best < 0 || math.Abs(rs[i]) > math.Abs(rs[best]) {
+						best = i
+					}
+				}
+				if best >= 0 {
+					crlts = append(crlts, base.with(CORR_METHOD_XCORR, int64(lags[best])*cinp.Step, rs[best], ns[best]))
+				}
+			}
+
+			done++
+			job.Progress(done, pairs)
+		}
+	}
+
+	return ReplaceProcessCorrelates(proc.ID, crlts)
+}
+
+func (crlt Correlate) with(method string, lag int64, r float64, n int) Correlate {
+	crlt.Method = method
+	crlt.Lag = lag
+	crlt.Coefficient = r
+	crlt.PValue = utils.CorrelationPValue(r, n)
+	crlt.N = int64(n)
+	return crlt
+}
+
+/* BUILDS A SYMMETRIC MATRIX FROM THE STORED CORRELATES; MISSING PAIRS ARE NaN -> null */
+func GetCorrelationMatrix(proc Process, method string) (mat CorrelationMatrix, err error) {
+
+	vrts, err := GetVariateListByProcess(proc.ID)
+	if err != nil {
+		return
+	}
+	crlts, err := GetCorrelateListByProcess(proc.ID, method)
+	if err != nil {
+		return
+	}
+
+	mat.Method = method
+	idx := make(map[int64]int)
+	for i, vrt := range vrts {
+		idx[vrt.ID] = i
+		mat.VIDs = append(mat.VIDs, vrt.ID)
+		mat.Names = append(mat.Names, vrt.Name)
+	}
+
+	n := len(vrts)
+	mat.Coefficients = make([][]float64, n)
+	mat.PValues = make([][]float64, n)
+	mat.Lags = make([][]int64, n)
+	for i := 0; i < n; i++ {
+		mat.Coefficients[i] = make([]float64, n)
+		mat.PValues[i] = make([]float64, n)
+		mat.Lags[i] = make([]int64, n)
+		for j := 0; j < n; j++ {
+			if i != j {
+				mat.Coefficients[i][j] = math.NaN()
+				mat.PValues[i][j] = math.NaN()
+			}
+		}
+		mat.Coefficients[i][i] = 1
+	}
+
+	for _, crlt := range crlts {
+		a, a_ok := idx[crlt.VIDA]
+		b, b_ok := idx[crlt.VIDB]
+		if !a_ok || !b_ok {
+			continue
+		}
+		mat.Coefficients[a][b], mat.Coefficients[b][a] = crlt.Coefficient, crlt.Coefficient
+		mat.PValues[a][b], mat.PValues[b][a] = crlt.PValue, crlt.PValue
+		mat.Lags[a][b], mat.Lags[b][a] = crlt.Lag, -crlt.Lag
+	}
+	return
+}
+
+/* JSON HAS NO NaN */
+func nanToNil(mat [][]float64) (out [][]interface{}) {
+	out = make([][]interface{}, len(mat))
+	for i, row := range mat {
+		out[i] = make([]interface{}, len(row))
+		for j, v := range row {
+			if !math.IsNaN(v) {
+				out[i][j] = v
+			}
+		}
+	}
+	return
+}
+
+func HandleStartCorrelation(c *fiber.Ctx) (err error) {
+
+	proc, err := paramProcess(c)
+	if err != nil {
+		return
+	}
+
+	cinp := CorrelationInput{}
+	if err = utils.ParseRequestBody(c, &cinp); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err = cinp.Validate(proc); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	label := fmt.Sprintf("process %d : correlation", proc.ID)
+	job, err := StartJob(JOB_TYPE_CORRELATE, label, LocalsUserID(c), func(job *Job) (ref string, err error) {
+		if err = cinp.Correlate(job, proc); err != nil {
+			return
+		}
+		ref = fmt.Sprintf("processes/%d/correlations", proc.ID)
+		return
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"job": job})
+}
+
+func HandleGetCorrelationMatrix(c *fiber.Ctx) (err error) {
+
+	proc, err := paramProcess(c)
+	if err != nil {
+		return
+	}
+
+	method := c.Query("method", CORR_METHOD_PEARSON)
+	switch method {
+	case CORR_METHOD_PEARSON, CORR_METHOD_SPEARMAN, CORR_METHOD_XCORR:
+	default:
+		return c.Status(fiber.StatusBadRequest).SendString("invalid correlation method: " + method)
+	}
+
+	mat, err := GetCorrelationMatrix(proc, method)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"method":       mat.Method,
+		"vids":         mat.VIDs,
+		"names":        mat.Names,
+		"coefficients": nanToNil(mat.Coefficients),
+		"p_values":     nanToNil(mat.PValues),
+		"lags":         mat.Lags,
+	})
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"jaQC-Go-API/utils"
+)
+
+/* PARSES :id AND RETURNS THE DATASET */
+func paramDataset(c *fiber.Ctx) (ds Dataset, err error) {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		err = fiber.NewError(fiber.StatusBadRequest, "invalid dataset id")
+		return
+	}
+	if ds, err = GetDatasetByID(int64(id)); err != nil {
+		err = fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+	return
+}
+
+func HandleGetDatasetList(c *fiber.Ctx) (err error) {
+	dsets, err := GetDatasetList()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"datasets": dsets})
+}
+
+func HandleGetGizmoDatasetList(c *fiber.Ctx) (err error) {
+	giz, err := paramGizmo(c)
+	if err != nil {
+		return
+	}
+
+	dsets, err := GetDatasetListByGizmo(giz.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"datasets": dsets})
+}
+
+func HandleGetDataset(c *fiber.Ctx) (err error) {
+	ds, err := paramDataset(c)
+	if err != nil {
+		return
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"dataset": ds})
+}
+
+func HandleCreateDataset(c *fiber.Ctx) (err error) {
+
+	giz, err := paramGizmo(c)
+	if err != nil {
+		return
+	}
+
+	dinp := DatasetInput{}
+	if err = utils.ParseRequestBody(c, &dinp); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if strings.TrimSpace(dinp.Name) == "" {
+		return c.Status(fiber.StatusBadRequest).SendString("dataset name is required")
+	}
+
+	ds := Dataset{
+		GID:         giz.ID,
+		Name:        strings.TrimSpace(dinp.Name),
+		Description: dinp.Description,
+	}
+	if err = ds.Create(LocalsUserID(c)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"dataset": ds})
+}
+
+func HandleGetDatasetStats(c *fiber.Ctx) (err error) {
+	ds, err := paramDataset(c)
+	if err != nil {
+		return
+	}
+
+	stats, err := ds.Stats()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"stats": stats})
+}
+
+func HandleDeleteDataset(c *fiber.Ctx) (err error) {
+	ds, err := paramDataset(c)
+	if err != nil {
+		return
+	}
+
+	if err = ds.Delete(LocalsUserID(c)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
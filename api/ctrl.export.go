@@ -0,0 +1,455 @@
+package api
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+
+	"jaQC-Go-API/utils"
+)
+
+/* CHECKS THE OPTIONS; RETURNS THE COLUMNS TO WRITE AND THE REQUESTED UNIT PER VARIATE */
+func (eq *ExportQuery) Validate(all []string) (cols []string, loc *time.Location, units map[int64]string, err error) {
+
+	eq.Format = strings.ToLower(strings.TrimSpace(eq.Format))
+	switch eq.Format {
+	case "":
+		eq.Format = EXPORT_FMT_CSV
+	case EXPORT_FMT_CSV, EXPORT_FMT_NDJSON:
+	default:
+		err = fmt.Errorf("invalid export format: %s", eq.Format)
+		return
+	}
+
+	if eq.Delimiter == "" {
+		eq.Delimiter = ","
+	}
+	if utf8.RuneCountInString(eq.Delimiter) != 1 {
+		err = fmt.Errorf("export delimiter must be a single character")
+		return
+	}
+
+	if eq.Columns == "" {
+		cols = all
+	} else {
+		known := make(map[string]bool)
+		for _, col := range all {
+			known[col] = true
+		}
+		for _, col := range strings.Split(eq.Columns, ",") {
+			col = strings.TrimSpace(col)
+			if !known[col] {
+				err = fmt.Errorf("invalid export column: %s", col)
+				return
+			}
+			cols = append(cols, col)
+		}
+	}
+
+	if err = utils.ValidateTimeFormat(eq.TimeFormat); err != nil {
+		return
+	}
+	if loc, err = utils.LoadTimeZone(eq.TimeZone); err != nil {
+		return
+	}
+
+	units = make(map[int64]string)
+	if eq.Units != "" {
+		for _, pair := range strings.Split(eq.Units, ",") {
+			vid, unit, ok := strings.Cut(pair, ":")
+			id, id_err := strconv.ParseInt(strings.TrimSpace(vid), 10, 64)
+			if !ok || id_err != nil || strings.TrimSpace(unit) == "" {
+				err = fmt.Errorf("invalid export units: %s", pair)
+				return
+			}
+			units[id] = utils.NormalizeUnit(unit)
+		}
+	}
+	return
+}
+
+/* NUMERIC TIME FORMATS STAY NUMBERS IN NDJSON */
+func (eq *ExportQuery) Time(ms int64, loc *time.Location) interface{} {
+	str := utils.FormatTimestamp(ms, eq.TimeFormat, loc)
+	if utils.IsNumericTimeFormat(eq.TimeFormat) {
+		return json.Number(str)
+	}
+	return str
+}
+
+func (eq *ExportQuery) ContentType() string {
+	if eq.Format == EXPORT_FMT_NDJSON {
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+/* SETS THE HEADERS FOR A DOWNLOAD NAMED name.<format> */
+func (eq *ExportQuery) SetHeaders(c *fiber.Ctx, name string) {
+	c.Set(fiber.HeaderContentType, eq.ContentType())
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.%s"`, name, eq.Format))
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+}
+
+/* HOW TO REPORT ONE VARIATE'S VALUES: IN Unit, VIA Conv; Scale CONVERTS RATES AND SPREADS */
+type exportVariate struct {
+	Variate
+	Unit  string
+	Conv  func(float64) float64
+	Scale float64
+	cals  []Calibration
+}
+
+func newExportVariate(vrt Variate, units map[int64]string) (ev *exportVariate, err error) {
+	ev = &exportVariate{Variate: vrt, Unit: vrt.Unit}
+	if unit, ok := units[vrt.ID]; ok {
+		ev.Unit = unit
+	}
+	if ev.Conv, err = utils.UnitConverter(vrt.Unit, ev.Unit); err != nil {
+		return nil, fmt.Errorf("variate %d: %s", vrt.ID, err.Error())
+	}
+	ev.Scale = ev.Conv(1) - ev.Conv(0)
+	return
+}
+
+/* WRITES ROWS AS CSV OR NDJSON, FLUSHING A CHUNK EVERY EXPORT_FLUSH_ROWS */
+type exportWriter struct {
+	eq   *ExportQuery
+	cols []string
+	w    *bufio.Writer
+	csv  *csv.Writer
+	rec  []string
+	rows int64
+}
+
+func newExportWriter(w *bufio.Writer, eq *ExportQuery, cols []string) (ew *exportWriter) {
+	ew = &exportWriter{eq: eq, cols: cols, w: w, rec: make([]string, len(cols))}
+	if eq.Format == EXPORT_FMT_CSV {
+		ew.csv = csv.NewWriter(w)
+		ew.csv.Comma, _ = utf8.DecodeRuneInString(eq.Delimiter)
+	}
+	return
+}
+
+func (ew *exportWriter) Header() (err error) {
+	if ew.csv == nil {
+		return
+	}
+	if ew.eq.BOM {
+		if _, err = ew.w.WriteString("\ufeff"); err != nil {
+			return
+		}
+	}
+	return ew.csv.Write(ew.cols)
+}
+
+/* val RETURNS THE VALUE FOR A COLUMN; nil IS WRITTEN AS BLANK / null */
+func (ew *exportWriter) Row(val func(col string) interface{}) (err error) {
+
+	if ew.csv != nil {
+		for i, col := range ew.cols {
+			ew.rec[i] = exportCSVValue(val(col))
+		}
+		err = ew.csv.Write(ew.rec)
+	} else {
+		ew.w.WriteByte('{')
+		for i, col := range ew.cols {
+			if i > 0 {
+				ew.w.WriteByte(',')
+			}
+			ew.w.WriteString(strconv.Quote(col))
+			ew.w.WriteByte(':')
+			ew.w.WriteString(exportJSONValue(val(col)))
+		}
+		_, err = ew.w.WriteString("}\n")
+	}
+	if err != nil {
+		return
+	}
+
+	ew.rows++
+	if ew.rows%EXPORT_FLUSH_ROWS == 0 {
+		return ew.Flush()
+	}
+	return
+}
+
+/* SENDS WHAT IS BUFFERED AS A CHUNK; FAILS ONCE THE CLIENT HAS GONE */
+func (ew *exportWriter) Flush() (err error) {
+	if ew.csv != nil {
+		ew.csv.Flush()
+		if err = ew.csv.Error(); err != nil {
+			return
+		}
+	}
+	return ew.w.Flush()
+}
+
+func exportCSVValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case json.Number:
+		return string(v)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		/* SAMPLES ARE float32; MORE DIGITS ARE CONVERSION NOISE */
+		return strconv.FormatFloat(v, 'g', -1, 32)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(v)
+}
+
+func exportJSONValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case float32:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return "null"
+		}
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "null"
+		}
+		return strconv.FormatFloat(v, 'g', -1, 32)
+	}
+	js, err := json.Marshal(v)
+	if err != nil {
+		return "null"
+	}
+	return string(js)
+}
+
+/* HANDLERS ******************************************************************************/
+
+/* STREAMS CALIBRATED SAMPLES, ONE ROW PER SAMPLE, VARIATE BY VARIATE */
+func HandleExportProcessSamples(c *fiber.Ctx) (err error) {
+
+	proc, err := paramProcess(c)
+	if err != nil {
+		return
+	}
+
+	eq := ExportQuery{}
+	seq := SampleExportQuery{}
+	if err = c.QueryParser(&eq); err == nil {
+		err = c.QueryParser(&seq)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	cols, loc, units, err := eq.Validate(SAMPLE_EXPORT_COLS)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	exclude, err := utils.ParseQualityMask(seq.Exclude)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	vrts, err := GetVariateListByProcess(proc.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	if vrts, err = SelectVariates(vrts, seq.VIDs, proc.ID); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	evs := []*exportVariate{}
+	for _, vrt := range vrts {
+		ev, ev_err := newExportVariate(vrt, units)
+		if ev_err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(ev_err.Error())
+		}
+		if ev.cals, err = GetCalibrationListByChannel(proc.GID, vrt.Channel); err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+		}
+		evs = append(evs, ev)
+	}
+
+	start, end := seq.Start, seq.End
+	if start == 0 && end == 0 {
+		start, end = proc.Start, proc.End
+	}
+
+	ds, err := GetDatasetByID(proc.DID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	ddb, err := ds.Open()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	/* THE STATUS IS SENT BEFORE THE FIRST ROW; LATER ERRORS CAN ONLY CUT THE STREAM SHORT */
+	eq.SetHeaders(c, fmt.Sprintf("process_%d_samples", proc.ID))
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+
+		ew := newExportWriter(w, &eq, cols)
+		if err := ew.Header(); err != nil {
+			return
+		}
+
+		for _, ev := range evs {
+			err := ddb.StreamSamples(ev.Channel, start, end, exclude, func(x int64, raw float32, q uint8) error {
+				value := float64(raw)
+				if cal := CalibrationAt(ev.cals, x); cal != nil {
+					value = cal.Apply(value)
+				}
+				value = ev.Conv(value)
+
+				return ew.Row(func(col string) interface{} {
+					switch col {
+					case "time":
+						return eq.Time(x, loc)
+					case "vid":
+						return ev.ID
+					case "variate":
+						return ev.Name
+					case "channel":
+						return ev.Channel
+					case "value":
+						return value
+					case "unit":
+						return ev.Unit
+					case "raw":
+						return raw
+					case "quality":
+						return utils.QualityName(q)
+					}
+					return nil
+				})
+			})
+			if err != nil {
+				utils.LogErr(err)
+				return
+			}
+		}
+
+		if err := ew.Flush(); err != nil {
+			utils.LogErr(err)
+		}
+	})
+	return
+}
+
+/* STREAMS AGGREGATES MATCHING THE GET /api/aggregates FILTERS, IN id ORDER */
+func HandleExportAggregates(c *fiber.Ctx) (err error) {
+
+	eq := ExportQuery{}
+	aq := AggregateQuery{}
+	if err = c.QueryParser(&eq); err == nil {
+		err = c.QueryParser(&aq)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	cols, loc, units, err := eq.Validate(AGG_EXPORT_COLS)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	/* A UNIT FOR A VARIATE THAT CANNOT TAKE IT IS A BAD REQUEST, NOT A BROKEN STREAM */
+	evs := make(map[int64]*exportVariate)
+	for vid := range units {
+		vrt, vrt_err := GetVariateByID(vid)
+		if vrt_err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(vrt_err.Error())
+		}
+		if evs[vid], err = newExportVariate(vrt, units); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+	}
+
+	eq.SetHeaders(c, "aggregates")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+
+		ew := newExportWriter(w, &eq, cols)
+		if err := ew.Header(); err != nil {
+			return
+		}
+
+		err := aq.StreamAggregates(func(agg *Aggregate) (err error) {
+
+			ev, ok := evs[agg.VID]
+			if !ok {
+				vrt, _ := GetVariateByID(agg.VID) // A MISSING VARIATE EXPORTS UNCONVERTED
+				if ev, err = newExportVariate(vrt, units); err != nil {
+					return
+				}
+				evs[agg.VID] = ev
+			}
+
+			return ew.Row(func(col string) interface{} {
+				switch col {
+				case "id":
+					return agg.ID
+				case "pid":
+					return agg.PID
+				case "vid":
+					return agg.VID
+				case "variate":
+					return ev.Name
+				case "code":
+					return agg.Code
+				case "start":
+					return eq.Time(agg.Start, loc)
+				case "end":
+					return eq.Time(agg.End, loc)
+				case "size":
+					return agg.Size
+				case "min":
+					return ev.Conv(float64(agg.Min))
+				case "max":
+					return ev.Conv(float64(agg.Max))
+				case "mean":
+					return ev.Conv(float64(agg.Mean))
+				case "slope":
+					return ev.Scale * float64(agg.Slope)
+				case "devi":
+					return math.Abs(ev.Scale) * float64(agg.Devi)
+				case "score":
+					return agg.Score
+				case "score_version":
+					return agg.ScoreVersion
+				case "verdict":
+					return agg.Verdict
+				case "valid":
+					return agg.Valid
+				case "rule":
+					return agg.Rule
+				case "unit":
+					return ev.Unit
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			utils.LogErr(err)
+			return
+		}
+
+		if err := ew.Flush(); err != nil {
+			utils.LogErr(err)
+		}
+	})
+	return
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"jaQC-Go-API/utils"
+)
+
+func (ginp *GizmoInput) Validate() (err error) {
+	ginp.Serial = strings.TrimSpace(ginp.Serial)
+	if ginp.Serial == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "gizmo serial is required")
+	}
+
+	switch ginp.Status {
+	case "":
+		ginp.Status = GIZMO_STATUS_ACTIVE
+	case GIZMO_STATUS_ACTIVE, GIZMO_STATUS_INACTIVE, GIZMO_STATUS_SERVICE, GIZMO_STATUS_RETIRED:
+	default:
+		return fiber.NewError(fiber.StatusBadRequest, "invalid gizmo status: "+ginp.Status)
+	}
+	return
+}
+
+/* COPIES EDITABLE FIELDS ONTO giz */
+func (ginp *GizmoInput) Apply(giz *Gizmo) {
+	giz.Serial = ginp.Serial
+	giz.HWClass = ginp.HWClass
+	giz.HWVersion = ginp.HWVersion
+	giz.FWVersion = ginp.FWVersion
+	giz.Name = ginp.Name
+	giz.Location = ginp.Location
+	giz.Owner = ginp.Owner
+	giz.Status = ginp.Status
+}
+
+/* PARSES :id AND RETURNS THE GIZMO */
+func paramGizmo(c *fiber.Ctx) (giz Gizmo, err error) {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		err = fiber.NewError(fiber.StatusBadRequest, "invalid gizmo id")
+		return
+	}
+	if giz, err = GetGizmoByID(int64(id)); err != nil {
+		err = fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+	return
+}
+
+func HandleGetGizmoList(c *fiber.Ctx) (err error) {
+	gizs, err := GetGizmoList()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"gizmos": gizs})
+}
+
+func HandleGetGizmo(c *fiber.Ctx) (err error) {
+	giz, err := paramGizmo(c)
+	if err != nil {
+		return
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"gizmo": giz})
+}
+
+func HandleCreateGizmo(c *fiber.Ctx) (err error) {
+
+	ginp := GizmoInput{}
+	if err = utils.ParseRequestBody(c, &ginp); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err = ginp.Validate(); err != nil {
+		return
+	}
+
+	if _, err = GetGizmoBySerial(ginp.Serial); err == nil {
+		return c.Status(fiber.StatusConflict).SendString("gizmo with serial " + ginp.Serial + " already exists")
+	}
+
+	giz := Gizmo{}
+	ginp.Apply(&giz)
+	if err = giz.Create(LocalsUserID(c)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"gizmo": giz})
+}
+
+func HandleUpdateGizmo(c *fiber.Ctx) (err error) {
+
+	giz, err := paramGizmo(c)
+	if err != nil {
+		return
+	}
+
+	ginp := GizmoInput{}
+	if err = utils.ParseRequestBody(c, &ginp); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err = ginp.Validate(); err != nil {
+		return
+	}
+
+	if other, ser_err := GetGizmoBySerial(ginp.Serial); ser_err == nil && other.ID != giz.ID {
+		return c.Status(fiber.StatusConflict).SendString("gizmo with serial " + ginp.Serial + " already exists")
+	}
+
+	ginp.Apply(&giz)
+	if err = giz.Update(LocalsUserID(c)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"gizmo": giz})
+}
+
+func HandleDeleteGizmo(c *fiber.Ctx) (err error) {
+
+	giz, err := paramGizmo(c)
+	if err != nil {
+		return
+	}
+
+	if err = giz.Delete(LocalsUserID(c)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
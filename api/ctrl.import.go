@@ -0,0 +1,547 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+
+	"jaQC-Go-API/utils"
+)
+
+const JOB_TYPE_IMPORT string = "import"
+
+/* CHECK FOR CANCEL AND REPORT PROGRESS EVERY N ROWS */
+const IMPORT_PROGRESS_ROWS int64 = 1000
+
+/* A VALIDATED ImportColumn; buf HOLDS SAMPLES UNTIL A BATCH IS WRITTEN */
+type importTarget struct {
+	Column  string
+	Channel string
+	Conv    func(float64) float64
+	Min     float64
+	Max     float64
+	Ranged  bool
+	buf     utils.TSXY
+}
+
+/* A ROW THAT CAN BE SKIPPED; ANY OTHER READ ERROR ENDS THE IMPORT */
+type importRowError struct{ msg string }
+
+func (e importRowError) Error() string { return e.msg }
+
+/* YIELDS ONE ROW AT A TIME: THE TIMESTAMP AND A VALUE PER TARGET, NaN WHERE BLANK */
+type importReader interface {
+	Read() (x int64, ys []float64, err error)
+	Row() int64
+}
+
+/* CHECKS THE SETTINGS AND RESOLVES EVERY COLUMN TO A VARIATE OF A PROCESS RECORDED IN ds */
+func (iinp *ImportInput) Validate(ds Dataset, filename string) (tgts []*importTarget, loc *time.Location, err error) {
+
+	iinp.Format = strings.ToLower(strings.TrimSpace(iinp.Format))
+	if iinp.Format == "" {
+		switch strings.ToLower(filepath.Ext(filename)) {
+		case ".ndjson", ".jsonl":
+			iinp.Format = IMPORT_FMT_NDJSON
+		default:
+			iinp.Format = IMPORT_FMT_CSV
+		}
+	}
+	if iinp.Format != IMPORT_FMT_CSV && iinp.Format != IMPORT_FMT_NDJSON {
+		err = fmt.Errorf("invalid import format: %s", iinp.Format)
+		return
+	}
+
+	if iinp.Delimiter == "" {
+		iinp.Delimiter = ","
+	}
+	if utf8.RuneCountInString(iinp.Delimiter) != 1 {
+		err = fmt.Errorf("import delimiter must be a single character")
+		return
+	}
+
+	iinp.TimeColumn = strings.TrimSpace(iinp.TimeColumn)
+	if iinp.TimeColumn == "" {
+		err = fmt.Errorf("import time_column is required")
+		return
+	}
+	if err = utils.ValidateTimeFormat(iinp.TimeFormat); err != nil {
+		return
+	}
+	if loc, err = utils.LoadTimeZone(iinp.TimeZone); err != nil {
+		return
+	}
+
+	switch iinp.OutOfRange {
+	case "":
+		iinp.OutOfRange = IMPORT_RANGE_SKIP
+	case IMPORT_RANGE_SKIP, IMPORT_RANGE_KEEP, IMPORT_RANGE_FAIL:
+	default:
+		err = fmt.Errorf("invalid import out_of_range: %s", iinp.OutOfRange)
+		return
+	}
+
+	if iinp.MaxErrors < 0 {
+		err = fmt.Errorf("import max_errors is negative")
+		return
+	}
+
+	if len(iinp.Columns) == 0 {
+		err = fmt.Errorf("import needs at least one column")
+		return
+	}
+
+	cols := make(map[string]bool)
+	chans := make(map[string]bool)
+	for _, col := range iinp.Columns {
+
+		col.Column = strings.TrimSpace(col.Column)
+		switch {
+		case col.Column == "":
+			err = fmt.Errorf("import column name is required")
+		case col.Column == iinp.TimeColumn:
+			err = fmt.Errorf("import column %s is the time column", col.Column)
+		case cols[col.Column]:
+			err = fmt.Errorf("import column %s is mapped twice", col.Column)
+		}
+		if err != nil {
+			return
+		}
+		cols[col.Column] = true
+
+		vrt, vrt_err := GetVariateByID(col.VID)
+		if vrt_err != nil {
+			return nil, nil, vrt_err
+		}
+		proc, proc_err := GetProcessByID(vrt.PID)
+		if proc_err != nil {
+			return nil, nil, proc_err
+		}
+		if proc.DID != ds.ID {
+			err = fmt.Errorf("variate %d belongs to process %d, which records dataset %d", vrt.ID, proc.ID, proc.DID)
+			return
+		}
+		if chans[vrt.Channel] {
+			err = fmt.Errorf("import maps channel %s twice", vrt.Channel)
+			return
+		}
+		chans[vrt.Channel] = true
+
+		conv, conv_err := utils.UnitConverter(col.Unit, vrt.Unit)
+		if conv_err != nil {
+			err = fmt.Errorf("import column %s: %s", col.Column, conv_err.Error())
+			return
+		}
+
+		tgts = append(tgts, &importTarget{
+			Column:  col.Column,
+			Channel: vrt.Channel,
+			Conv:    conv,
+			Min:     float64(vrt.ExpectMin),
+			Max:     float64(vrt.ExpectMax),
+			Ranged:  vrt.ExpectMax > vrt.ExpectMin,
+		})
+	}
+	return
+}
+
+/* STREAMS THE FILE AT path INTO THE DATASET; SAMPLES WRITTEN BEFORE A FAILURE OR CANCEL ARE KEPT */
+func (iinp *ImportInput) Import(job *Job, ds Dataset, tgts []*importTarget, loc *time.Location, path string) (res ImportResult, err error) {
+
+	res.DID = ds.ID
+	res.JobID = job.ID
+
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return
+	}
+	cnt := &countingReader{r: f}
+	buf := bufio.NewReaderSize(cnt, 64*1024)
+
+	var rdr importReader
+	switch iinp.Format {
+	case IMPORT_FMT_NDJSON:
+		rdr = newNDJSONImportReader(buf, iinp, tgts, loc)
+	default:
+		if rdr, err = newCSVImportReader(buf, iinp, tgts, loc); err != nil {
+			return
+		}
+	}
+
+	ddb, err := ds.Open()
+	if err != nil {
+		return
+	}
+
+	flush := func(tgt *importTarget) (err error) {
+		if len(tgt.buf.X) == 0 {
+			return
+		}
+		if err = ddb.WriteSamples(tgt.Channel, tgt.buf); err != nil {
+			return
+		}
+		if err = FeedClusterStreams(ds.ID, tgt.Channel, tgt.buf, job.Owner); err != nil {
+			return
+		}
+		res.Samples += int64(len(tgt.buf.X))
+		tgt.buf.X = tgt.buf.X[:0]
+		tgt.buf.Y = tgt.buf.Y[:0]
+		return
+	}
+
+	for {
+		x, ys, rerr := rdr.Read()
+		if rerr == io.EOF {
+			break
+		}
+		res.Rows++
+
+		if res.Rows%IMPORT_PROGRESS_ROWS == 0 {
+			if err = job.Context().Err(); err != nil {
+				return
+			}
+			job.Progress(float32(cnt.n), float32(fi.Size()))
+		}
+
+		if rerr != nil {
+			var rowErr importRowError
+			if !errors.As(rerr, &rowErr) {
+				return res, rerr
+			}
+			res.Skipped++
+			msg := fmt.Sprintf("row %d: %s", rdr.Row(), rowErr.msg)
+			if len(res.Errors) < IMPORT_ERROR_LINES {
+				res.Errors = append(res.Errors, msg)
+			}
+			if iinp.MaxErrors > 0 && res.Skipped >= int64(iinp.MaxErrors) {
+				return res, fmt.Errorf("import stopped after %d bad rows; last %s", res.Skipped, msg)
+			}
+			continue
+		}
+
+		for i, tgt := range tgts {
+			if math.IsNaN(ys[i]) {
+				continue
+			}
+			y := tgt.Conv(ys[i])
+
+			if tgt.Ranged && (y < tgt.Min || y > tgt.Max) {
+				res.OutOfRange++
+				switch iinp.OutOfRange {
+				case IMPORT_RANGE_FAIL:
+					return res, fmt.Errorf("row %d: %s = %g is outside %g .. %g", rdr.Row(), tgt.Column, y, tgt.Min, tgt.Max)
+				case IMPORT_RANGE_SKIP:
+					continue
+				}
+			}
+
+			tgt.buf.X = append(tgt.buf.X, x)
+			tgt.buf.Y = append(tgt.buf.Y, float32(y))
+			if len(tgt.buf.X) >= SAMPLE_BATCH_SIZE {
+				if err = flush(tgt); err != nil {
+					return
+				}
+			}
+		}
+	}
+
+	for _, tgt := range tgts {
+		if err = flush(tgt); err != nil {
+			return
+		}
+	}
+	return
+}
+
+/* SENDS THE RESULT TO EVERY SESSION OF THE USER */
+func sendImportResult(uid int64, res ImportResult) {
+	for _, ussn := range UserSessionsMapCopy() {
+		if ussn.USR.ID == uid {
+			ussn.WSSendMessage("import", res)
+		}
+	}
+}
+
+/* BYTES READ SO FAR; PROGRESS IS BY BYTES SINCE THE ROW COUNT IS NOT KNOWN UP FRONT */
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (n int, err error) {
+	n, err = cr.r.Read(p)
+	cr.n += int64(n)
+	return
+}
+
+/* BLANKS AND THE USUAL "NO READING" MARKERS ARE MISSING VALUES, NOT ERRORS */
+func parseImportValue(str string) (y float64, err error) {
+	str = strings.TrimSpace(str)
+	switch strings.ToLower(str) {
+	case "", "nan", "na", "n/a", "null", "-":
+		return math.NaN(), nil
+	}
+	if y, err = strconv.ParseFloat(str, 64); err != nil || math.IsInf(y, 0) {
+		return 0, importRowError{fmt.Sprintf("invalid value: %s", str)}
+	}
+	return
+}
+
+/* CSV ***********************************************************************************/
+type csvImportReader struct {
+	rdr    *csv.Reader
+	time   int
+	cols   []int
+	format string
+	loc    *time.Location
+	row    int64
+	ys     []float64
+}
+
+func newCSVImportReader(r io.Reader, iinp *ImportInput, tgts []*importTarget, loc *time.Location) (cir *csvImportReader, err error) {
+
+	rdr := csv.NewReader(r)
+	rdr.Comma, _ = utf8.DecodeRuneInString(iinp.Delimiter)
+	rdr.FieldsPerRecord = -1
+	rdr.LazyQuotes = true
+	rdr.ReuseRecord = true
+
+	hdr, err := rdr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading csv header: %s", err.Error())
+	}
+
+	idx := make(map[string]int)
+	for i, h := range hdr {
+		if i == 0 {
+			h = strings.TrimPrefix(h, "\ufeff") // EXCEL WRITES A BOM
+		}
+		idx[strings.TrimSpace(h)] = i
+	}
+
+	cir = &csvImportReader{
+		rdr:    rdr,
+		format: iinp.TimeFormat,
+		loc:    loc,
+		row:    1,
+		ys:     make([]float64, len(tgts)),
+	}
+
+	var ok bool
+	if cir.time, ok = idx[iinp.TimeColumn]; !ok {
+		return nil, fmt.Errorf("time column %s is not in the csv header", iinp.TimeColumn)
+	}
+	for _, tgt := range tgts {
+		i, ok := idx[tgt.Column]
+		if !ok {
+			return nil, fmt.Errorf("column %s is not in the csv header", tgt.Column)
+		}
+		cir.cols = append(cir.cols, i)
+	}
+	return
+}
+
+func (cir *csvImportReader) Row() int64 { return cir.row }
+
+func (cir *csvImportReader) Read() (x int64, ys []float64, err error) {
+
+	rec, err := cir.rdr.Read()
+	if err == io.EOF {
+		return
+	}
+	cir.row++
+
+	var perr *csv.ParseError
+	if errors.As(err, &perr) {
+		return 0, nil, importRowError{perr.Err.Error()}
+	}
+	if err != nil {
+		return
+	}
+
+	if cir.time >= len(rec) {
+		return 0, nil, importRowError{"missing timestamp"}
+	}
+	if x, err = utils.ParseTimestamp(rec[cir.time], cir.format, cir.loc); err != nil {
+		return 0, nil, importRowError{err.Error()}
+	}
+
+	for i, col := range cir.cols {
+		if col >= len(rec) {
+			cir.ys[i] = math.NaN()
+			continue
+		}
+		if cir.ys[i], err = parseImportValue(rec[col]); err != nil {
+			return
+		}
+	}
+	return x, cir.ys, nil
+}
+
+/* NDJSON ********************************************************************************/
+type ndjsonImportReader struct {
+	rdr    *bufio.Reader
+	time   string
+	keys   []string
+	format string
+	loc    *time.Location
+	row    int64
+	ys     []float64
+}
+
+func newNDJSONImportReader(r *bufio.Reader, iinp *ImportInput, tgts []*importTarget, loc *time.Location) (nir *ndjsonImportReader) {
+	nir = &ndjsonImportReader{
+		rdr:    r,
+		time:   iinp.TimeColumn,
+		format: iinp.TimeFormat,
+		loc:    loc,
+		ys:     make([]float64, len(tgts)),
+	}
+	for _, tgt := range tgts {
+		nir.keys = append(nir.keys, tgt.Column)
+	}
+	return
+}
+
+func (nir *ndjsonImportReader) Row() int64 { return nir.row }
+
+func (nir *ndjsonImportReader) Read() (x int64, ys []float64, err error) {
+
+	/* SKIP BLANK LINES; A LAST LINE WITHOUT A NEWLINE STILL COUNTS */
+	var ln []byte
+	for {
+		var rerr error
+		ln, rerr = nir.rdr.ReadBytes('\n')
+		nir.row++
+		if len(bytes.TrimSpace(ln)) > 0 {
+			break
+		}
+		if rerr != nil {
+			return 0, nil, rerr
+		}
+	}
+
+	obj := make(map[string]interface{})
+	dec := json.NewDecoder(bytes.NewReader(ln))
+	dec.UseNumber()
+	if err = dec.Decode(&obj); err != nil {
+		return 0, nil, importRowError{"invalid json: " + err.Error()}
+	}
+
+	switch ts := obj[nir.time].(type) {
+	case json.Number:
+		num, num_err := ts.Float64()
+		if num_err == nil {
+			x, err = utils.NumericTimestampMilli(num, nir.format)
+		} else {
+			err = num_err
+		}
+	case string:
+		x, err = utils.ParseTimestamp(ts, nir.format, nir.loc)
+	default:
+		err = fmt.Errorf("missing timestamp")
+	}
+	if err != nil {
+		return 0, nil, importRowError{err.Error()}
+	}
+
+	for i, key := range nir.keys {
+		switch v := obj[key].(type) {
+		case nil:
+			nir.ys[i] = math.NaN()
+		case json.Number:
+			if nir.ys[i], err = v.Float64(); err != nil {
+				return 0, nil, importRowError{fmt.Sprintf("invalid value: %s", v)}
+			}
+		case string:
+			if nir.ys[i], err = parseImportValue(v); err != nil {
+				return
+			}
+		default:
+			return 0, nil, importRowError{fmt.Sprintf("invalid value for %s", key)}
+		}
+	}
+	return x, nir.ys, nil
+}
+
+/* HANDLERS ******************************************************************************/
+func HandleImportDataset(c *fiber.Ctx) (err error) {
+
+	ds, err := paramDataset(c)
+	if err != nil {
+		return
+	}
+
+	iinp := ImportInput{}
+	if err = utils.UnmarshalFormDataObject(c, "import", &iinp); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("import file is required")
+	}
+
+	tgts, loc, err := iinp.Validate(ds, fh.Filename)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	/* THE REQUEST BODY IS GONE ONCE WE RETURN; THE JOB READS ITS OWN COPY */
+	path := fmt.Sprintf("%s/ds%d_%d%s", IMPORT_FILE_DIR, ds.ID, time.Now().UTC().UnixNano(), filepath.Ext(fh.Filename))
+	if err = c.SaveFile(fh, path); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(utils.LogErr(err).Error())
+	}
+
+	label := fmt.Sprintf("dataset %d : import %s", ds.ID, filepath.Base(fh.Filename))
+	job, err := StartJob(JOB_TYPE_IMPORT, label, LocalsUserID(c), func(job *Job) (ref string, err error) {
+		defer os.Remove(path)
+
+		res, err := iinp.Import(job, ds, tgts, loc, path)
+
+		/* AN IMPORT IS THE WHOLE OF ITS DATA; CLOSE THE CLUSTERS IT LEFT OPEN */
+		if fl_err := FlushClusterStreams(func(cs *ClusterStream) bool { return cs.Proc.DID == ds.ID }); fl_err != nil {
+			utils.LogErr(fl_err)
+		}
+
+		if _, st_err := ds.Stats(); st_err != nil {
+			utils.LogErr(st_err)
+		}
+		log.Info(fmt.Sprintf("IMPORT -> DATASET %d : %d ROWS : %d SAMPLES : %d SKIPPED : %d OUT OF RANGE",
+			ds.ID, res.Rows, res.Samples, res.Skipped, res.OutOfRange,
+		))
+		sendImportResult(job.Owner, res)
+
+		if err != nil {
+			return
+		}
+		ref = fmt.Sprintf("datasets/%d/stats", ds.ID)
+		return
+	})
+	if err != nil {
+		os.Remove(path)
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"job": job})
+}
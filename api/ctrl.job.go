@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+
+	"jaQC-Go-API/utils"
+)
+
+/* CANCEL FUNCTIONS FOR JOBS RUNNING IN THIS PROCESS */
+type JobCancelMap map[int64]context.CancelFunc
+var JobCancels = make(JobCancelMap)
+var JobCancelsRWMutex = sync.RWMutex{}
+
+func JobCancelsMapWrite(id int64, cancel context.CancelFunc) {
+	JobCancelsRWMutex.Lock()
+	JobCancels[id] = cancel
+	JobCancelsRWMutex.Unlock()
+}
+func JobCancelsMapRead(id int64) (cancel context.CancelFunc, ok bool) {
+	JobCancelsRWMutex.Lock()
+	cancel, ok = JobCancels[id]
+	JobCancelsRWMutex.Unlock()
+	return
+}
+func JobCancelsMapRemove(id int64) {
+	JobCancelsRWMutex.Lock()
+	delete(JobCancels, id)
+	JobCancelsRWMutex.Unlock()
+}
+
+/* CREATES THE JOB RECORD AND RUNS fn IN THE BACKGROUND */
+func StartJob(typ, label string, owner int64, fn JobFunc) (job *Job, err error) {
+
+	job = &Job{
+		Type:  typ,
+		Label: label,
+		Owner: owner,
+		State: JOB_STATE_QUEUED,
+		mut:   &sync.Mutex{},
+	}
+	job.CreatedBy = owner
+	job.UpdatedBy = owner
+	if err = job.Write(); err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job.ctx = ctx
+	JobCancelsMapWrite(job.ID, cancel)
+
+	go job.run(fn, cancel)
+	return
+}
+
+func (job *Job) run(fn JobFunc, cancel context.CancelFunc) {
+	defer cancel()
+	defer JobCancelsMapRemove(job.ID)
+
+	job.mut.Lock()
+	job.State = JOB_STATE_RUNNING
+	job.StartedAt = time.Now().UTC().UnixMilli()
+	job.mut.Unlock()
+	job.update()
+
+	ref, panicked, err := job.call(fn)
+
+	job.mut.Lock()
+	job.EndedAt = time.Now().UTC().UnixMilli()
+	switch {
+	case panicked:
+		job.State = JOB_STATE_FAILED
+		job.Error = err.Error()
+	case job.ctx.Err() != nil:
+		job.State = JOB_STATE_CANCELLED
+		job.Error = job.ctx.Err().Error()
+	case err != nil:
+		job.State = JOB_STATE_FAILED
+		job.Error = err.Error()
+	default:
+		job.State = JOB_STATE_COMPLETE
+		job.Percent = 100
+		job.ResultRef = ref
+	}
+	job.mut.Unlock()
+	job.update()
+
+	log.Info(fmt.Sprintf("JOB %d ( %s ) : %s", job.ID, job.Type, job.State))
+}
+
+/* RUNS fn, TURNING A PANIC INTO AN ERROR SO ONE BAD JOB CANNOT TAKE THE API DOWN */
+func (job *Job) call(fn JobFunc) (ref string, panicked bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err, panicked = fmt.Errorf("job panicked: %v", r), true
+			utils.LogErr(fmt.Errorf("JOB %d ( %s ) : %s\n%s", job.ID, job.Type, err.Error(), debug.Stack()))
+		}
+	}()
+	ref, err = fn(job)
+	return
+}
+
+/* CALLED BY THE JOB'S WORK; SAVES AND EMITS ONLY WHEN THE PERCENTAGE CHANGES */
+func (job *Job) Progress(curr, end float32) {
+	percent := 0
+	if end > 0 {
+		percent = int((curr / end) * float32(100))
+	}
+
+	job.mut.Lock()
+	changed := percent != job.Percent
+	job.Percent = percent
+	job.mut.Unlock()
+
+	if changed {
+		job.update()
+	}
+}
+
+/* PERSISTS THE JOB AND SENDS A PROGRESS MESSAGE TO EVERY SESSION OF THE OWNER */
+func (job *Job) update() {
+
+	job.mut.Lock()
+	job.UpdatedAt = time.Now().UTC().UnixMilli()
+	rec := *job
+	job.mut.Unlock()
+
+	if err := rec.Write(); err != nil {
+		utils.LogErr(err)
+	}
+
+	msg := ProgressMessage{
+		Source:  rec.Type,
+		Label:   rec.Label,
+		Percent: rec.Percent,
+		JobID:   rec.ID,
+		State:   rec.State,
+	}
+	for _, ussn := range UserSessionsMapCopy() {
+		if ussn.USR.ID == rec.Owner {
+			ussn.WSSendMessage("progress", msg)
+		}
+	}
+}
+
+func CancelJob(id int64) (err error) {
+	cancel, ok := JobCancelsMapRead(id)
+	if !ok {
+		return fmt.Errorf("job with id %d is not running", id)
+	}
+	cancel()
+	return
+}
+
+/* OWNERS SEE THEIR OWN JOBS; ADMINS SEE ALL */
+func authJob(c *fiber.Ctx) (job Job, err error) {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		err = fiber.NewError(fiber.StatusBadRequest, "invalid job id")
+		return
+	}
+
+	if job, err = GetJobByID(int64(id)); err != nil {
+		err = fiber.NewError(fiber.StatusNotFound, err.Error())
+		return
+	}
+
+	role, _ := c.Locals("role").(string)
+	if job.Owner != LocalsUserID(c) && role != ROLE_SUPER && role != ROLE_ADMIN {
+		err = fiber.NewError(fiber.StatusUnauthorized, "you do not own this job")
+	}
+	return
+}
+
+func HandleGetJobList(c *fiber.Ctx) (err error) {
+	jobs, err := GetJobListByOwner(LocalsUserID(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"jobs": jobs})
+}
+
+func HandleGetJob(c *fiber.Ctx) (err error) {
+	job, err := authJob(c)
+	if err != nil {
+		return
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"job": job})
+}
+
+func HandleCancelJob(c *fiber.Ctx) (err error) {
+	job, err := authJob(c)
+	if err != nil {
+		return
+	}
+
+	if err = CancelJob(job.ID); err != nil {
+		return c.Status(fiber.StatusConflict).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"message": "cancelling job"})
+}
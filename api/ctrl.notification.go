@@ -0,0 +1,112 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"jaQC-Go-API/utils"
+)
+
+/* PERSISTS THE NOTIFICATION AND PUSHES IT TO EVERY CONNECTED RECIPIENT */
+func (ninp *NotificationInput) SendNotification(from int64) (note Notification, err error) {
+
+	note.Title = strings.TrimSpace(ninp.Title)
+	note.Body = ninp.Body
+	note.Level = ninp.Level
+	note.TargetRole = ninp.TargetRole
+	note.TargetOrg = ninp.TargetOrg
+	note.TargetUser = ninp.TargetUser
+	note.CreatedBy = from
+	note.UpdatedBy = from
+
+	if note.Level == "" {
+		note.Level = NOTE_LEVEL_INFO
+	}
+
+	uids, err := note.Write()
+	if err != nil {
+		return
+	}
+
+	recipients := make(map[int64]bool)
+	for _, uid := range uids {
+		recipients[uid] = true
+	}
+
+	msg := note.Response()
+	for _, ussn := range UserSessionsMapCopy() {
+		if recipients[ussn.USR.ID] {
+			ussn.WSSendMessage("notification", msg)
+		}
+	}
+	return
+}
+
+func (note *Notification) Response() NotificationResponse {
+	return NotificationResponse{
+		ID:        note.ID,
+		Title:     note.Title,
+		Body:      note.Body,
+		Level:     note.Level,
+		CreatedAt: note.CreatedAt,
+		CreatedBy: note.CreatedBy,
+	}
+}
+
+func HandleSendNotification(c *fiber.Ctx) (err error) {
+
+	ninp := NotificationInput{}
+	if err = utils.ParseRequestBody(c, &ninp); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	if strings.TrimSpace(ninp.Title) == "" {
+		return c.Status(fiber.StatusBadRequest).SendString("notification title is required")
+	}
+
+	switch ninp.Level {
+	case "", NOTE_LEVEL_INFO, NOTE_LEVEL_WARN, NOTE_LEVEL_ALERT:
+	default:
+		return c.Status(fiber.StatusBadRequest).SendString("invalid notification level: " + ninp.Level)
+	}
+
+	note, err := ninp.SendNotification(LocalsUserID(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"notification": note})
+}
+
+func HandleGetNotificationList(c *fiber.Ctx) (err error) {
+
+	notes, err := GetNotificationListByUser(LocalsUserID(c), c.QueryBool("unread"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"notifications": notes})
+}
+
+func HandleReadNotification(c *fiber.Ctx) (err error) {
+	return markNotification(c, "read_at")
+}
+
+func HandleDismissNotification(c *fiber.Ctx) (err error) {
+	return markNotification(c, "dismissed_at")
+}
+
+func markNotification(c *fiber.Ctx, col string) (err error) {
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid notification id")
+	}
+
+	if err = MarkNotification(int64(id), LocalsUserID(c), col); err != nil {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"jaQC-Go-API/utils"
+)
+
+/* CHECKS THE GIZMO AND DATASET EXIST AND BELONG TOGETHER */
+func (pinp *ProcessInput) Validate() (err error) {
+
+	pinp.Name = strings.TrimSpace(pinp.Name)
+	if pinp.Name == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "process name is required")
+	}
+
+	if _, err = GetGizmoByID(pinp.GID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	ds, err := GetDatasetByID(pinp.DID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if ds.GID != pinp.GID {
+		return fiber.NewError(fiber.StatusBadRequest, "dataset does not belong to gizmo")
+	}
+
+	if pinp.End != 0 && pinp.End < pinp.Start {
+		return fiber.NewError(fiber.StatusBadRequest, "process end is before start")
+	}
+	return
+}
+
+func (pinp *ProcessInput) Apply(proc *Process) {
+	proc.GID = pinp.GID
+	proc.DID = pinp.DID
+	proc.Name = pinp.Name
+	proc.Start = pinp.Start
+	proc.End = pinp.End
+	proc.Recipe = pinp.Recipe
+	proc.Notes = pinp.Notes
+}
+
+/* CLIPS [ start, end ) TO THE PROCESS WINDOW; end == 0 MEANS OPEN ENDED; ok IS FALSE IF NOTHING IS LEFT */
+func (proc *Process) Window(start, end int64) (from, to int64, ok bool) {
+	from, to = start, end
+	if from < proc.Start {
+		from = proc.Start
+	}
+	if proc.End != 0 && (to == 0 || to > proc.End) {
+		to = proc.End
+	}
+	ok = to == 0 || from < to
+	return
+}
+
+/* PARSES :id AND RETURNS THE PROCESS */
+func paramProcess(c *fiber.Ctx) (proc Process, err error) {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		err = fiber.NewError(fiber.StatusBadRequest, "invalid process id")
+		return
+	}
+	if proc, err = GetProcessByID(int64(id)); err != nil {
+		err = fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+	return
+}
+
+func HandleGetProcessList(c *fiber.Ctx) (err error) {
+	procs, err := GetProcessList(int64(c.QueryInt("gid")))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"processes": procs})
+}
+
+func HandleGetProcess(c *fiber.Ctx) (err error) {
+	proc, err := paramProcess(c)
+	if err != nil {
+		return
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"process": proc})
+}
+
+func HandleCreateProcess(c *fiber.Ctx) (err error) {
+
+	pinp := ProcessInput{}
+	if err = utils.ParseRequestBody(c, &pinp); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err = pinp.Validate(); err != nil {
+		return
+	}
+
+	proc := Process{}
+	pinp.Apply(&proc)
+	if err = proc.Create(LocalsUserID(c)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"process": proc})
+}
+
+func HandleUpdateProcess(c *fiber.Ctx) (err error) {
+
+	proc, err := paramProcess(c)
+	if err != nil {
+		return
+	}
+
+	pinp := ProcessInput{}
+	if err = utils.ParseRequestBody(c, &pinp); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err = pinp.Validate(); err != nil {
+		return
+	}
+
+	pinp.Apply(&proc)
+	if err = proc.Update(LocalsUserID(c)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	/* LIVE CLUSTER STREAMS HOLD THE OLD WINDOW; AN ENDED PROCESS CLOSES ITS LAST CLUSTERS */
+	if err = FlushClusterStreams(func(cs *ClusterStream) bool { return cs.PID == proc.ID }); err != nil {
+		utils.LogErr(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"process": proc})
+}
+
+func HandleGetProcessVariateList(c *fiber.Ctx) (err error) {
+	proc, err := paramProcess(c)
+	if err != nil {
+		return
+	}
+
+	vrts, err := GetVariateListByProcess(proc.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"variates": vrts})
+}
+
+func HandleGetProcessAggregateList(c *fiber.Ctx) (err error) {
+	proc, err := paramProcess(c)
+	if err != nil {
+		return
+	}
+
+	aggs, err := GetAggregateListByProcess(proc.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"aggregates": aggs})
+}
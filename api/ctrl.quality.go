@@ -0,0 +1,276 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"jaQC-Go-API/utils"
+)
+
+const JOB_TYPE_OUTLIERS string = "outliers"
+
+/* LONGEST SERIES GET /outliers SCORES; LONGER WINDOWS GO THROUGH THE FLAGGING JOB */
+const OUTLIER_MAX_POINTS int = 1 << 20
+
+var errOutlierPoints = errors.New("outlier window holds too many samples")
+
+/* THE QUALITY FLAGS THE VARIATE LEAVES OUT; Exclude IS VALIDATED WHEN IT IS SET */
+func (vrt *Variate) QualityMask() utils.QualityMask {
+	mask, _ := utils.ParseQualityMask(vrt.Exclude)
+	return mask
+}
+
+func (oinp *OutlierInput) Config() (cfg utils.OutlierConfig, err error) {
+	if oinp.End != 0 && oinp.End <= oinp.Start {
+		err = fmt.Errorf("outlier end must be after start")
+		return
+	}
+	cfg = utils.OutlierConfig{
+		Method:       oinp.Method,
+		Window:       oinp.Window,
+		Threshold:    oinp.Threshold,
+		BadThreshold: oinp.BadThreshold,
+	}
+	if err = cfg.Validate(); err != nil {
+		return
+	}
+	cfg.Defaults()
+	return
+}
+
+/* SAMPLES THE DETECTORS NEVER JUDGE: SYNTHETIC ONES AND THOSE A PERSON HAS ALREADY EXCLUDED */
+const OUTLIER_SKIP = utils.QualityMask(1<<utils.QUALITY_INTERPOLATED | 1<<utils.QUALITY_EXCLUDED)
+
+/*
+SCORES THE VARIATE'S CALIBRATED SAMPLES OVER [ start, end ), CLIPPED TO THE PROCESS WINDOW
+MORE THAN limit SAMPLES IS AN errOutlierPoints; 0 IS NO LIMIT
+*/
+func (vrt *Variate) FindOutliers(cfg utils.OutlierConfig, start, end int64, limit int) (outs []utils.Outlier, err error) {
+
+	proc, err := GetProcessByID(vrt.PID)
+	if err != nil {
+		return
+	}
+	start, end, ok := proc.Window(start, end)
+	if !ok {
+		return
+	}
+
+	ts, err := vrt.GetTSXYExcluding(start, end, OUTLIER_SKIP)
+	if err != nil {
+		return
+	}
+	if limit > 0 && len(ts.X) > limit {
+		err = fmt.Errorf("%w; %d samples, limit %d", errOutlierPoints, len(ts.X), limit)
+		return
+	}
+	return utils.FindOutliers(ts, cfg), nil
+}
+
+/*
+REPLACES THE suspect AND bad FLAGS OF THE VARIATE'S CHANNEL OVER [ start, end ) WITH THOSE OF A FRESH SCAN
+interpolated AND excluded SAMPLES KEEP THEIR FLAGS
+*/
+func (vrt *Variate) FlagOutliers(ctx context.Context, cfg utils.OutlierConfig, start, end int64) (outs []utils.Outlier, err error) {
+
+	proc, err := GetProcessByID(vrt.PID)
+	if err != nil {
+		return
+	}
+	start, end, ok := proc.Window(start, end)
+	if !ok {
+		return
+	}
+
+	if outs, err = vrt.FindOutliers(cfg, start, end, 0); err != nil {
+		return
+	}
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	ds, err := GetDatasetByID(proc.DID)
+	if err != nil {
+		return
+	}
+	ddb, err := ds.Open()
+	if err != nil {
+		return
+	}
+
+	detected := utils.QualityMask(1<<utils.QUALITY_SUSPECT | 1<<utils.QUALITY_BAD)
+	if _, err = ddb.SetSampleQualityRange(vrt.Channel, start, end, utils.QUALITY_GOOD, detected); err != nil {
+		return
+	}
+
+	for _, q := range detected.Flags() {
+		xs := []int64{}
+		for _, out := range outs {
+			if out.Quality == q {
+				xs = append(xs, out.X)
+			}
+		}
+		if err = ddb.SetSampleQuality(vrt.Channel, xs, q); err != nil {
+			return
+		}
+	}
+	return
+}
+
+/* HANDLERS ******************************************************************************/
+func HandleGetVariateOutliers(c *fiber.Ctx) (err error) {
+
+	vrt, err := paramVariate(c)
+	if err != nil {
+		return
+	}
+
+	oinp := OutlierInput{}
+	if err = c.QueryParser(&oinp); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	cfg, err := oinp.Config()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	/* A WINDOW TOO LONG TO SCORE HERE CAN STILL BE FLAGGED BY POST /api/variates/:id/outliers */
+	outs, err := vrt.FindOutliers(cfg, oinp.Start, oinp.End, OUTLIER_MAX_POINTS)
+	if errors.Is(err, errOutlierPoints) {
+		return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	if outs == nil {
+		outs = []utils.Outlier{}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"outliers": outs})
+}
+
+func HandleStartOutlierFlagging(c *fiber.Ctx) (err error) {
+
+	vrt, err := paramVariate(c)
+	if err != nil {
+		return
+	}
+
+	oinp := OutlierInput{}
+	if err = utils.ParseRequestBody(c, &oinp); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	cfg, err := oinp.Config()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	proc, err := GetProcessByID(vrt.PID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	label := fmt.Sprintf("variate %d : outliers", vrt.ID)
+	job, err := StartJob(JOB_TYPE_OUTLIERS, label, LocalsUserID(c), func(job *Job) (ref string, err error) {
+		if _, err = vrt.FlagOutliers(job.Context(), cfg, oinp.Start, oinp.End); err != nil {
+			return
+		}
+		ref = fmt.Sprintf("datasets/%d/quality?channel=%s", proc.DID, vrt.Channel)
+		return
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"job": job})
+}
+
+/* AGGREGATES ARE NOT REBUILT; POST /api/variates/:id/clusters PICKS UP THE NEW SETTING */
+func HandleUpdateVariateExclude(c *fiber.Ctx) (err error) {
+
+	vrt, err := paramVariate(c)
+	if err != nil {
+		return
+	}
+
+	einp := ExcludeInput{}
+	if err = utils.ParseRequestBody(c, &einp); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if _, err = utils.ParseQualityMask(einp.Exclude); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	vrt.Exclude = strings.ToLower(strings.ReplaceAll(einp.Exclude, " ", ""))
+	if err = vrt.Update(LocalsUserID(c)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"variate": vrt})
+}
+
+func HandleGetDatasetQuality(c *fiber.Ctx) (err error) {
+
+	ds, err := paramDataset(c)
+	if err != nil {
+		return
+	}
+
+	qq := QualityQuery{}
+	if err = c.QueryParser(&qq); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if qq.Channel == "" {
+		return c.Status(fiber.StatusBadRequest).SendString("quality channel is required")
+	}
+
+	ddb, err := ds.Open()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	counts, err := ddb.GetQualityCounts(qq.Channel, qq.Start, qq.End)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"quality": counts})
+}
+
+/* MANUAL FLAGGING, e.g. "excluded" OVER A KNOWN BAD STRETCH, OR "good" TO CLEAR IT */
+func HandleUpdateDatasetQuality(c *fiber.Ctx) (err error) {
+
+	ds, err := paramDataset(c)
+	if err != nil {
+		return
+	}
+
+	qinp := QualityInput{}
+	if err = utils.ParseRequestBody(c, &qinp); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if qinp.Channel = strings.TrimSpace(qinp.Channel); qinp.Channel == "" {
+		return c.Status(fiber.StatusBadRequest).SendString("quality channel is required")
+	}
+	if qinp.End != 0 && qinp.End <= qinp.Start {
+		return c.Status(fiber.StatusBadRequest).SendString("quality end must be after start")
+	}
+	q, err := utils.ParseQuality(qinp.Quality)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	ddb, err := ds.Open()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	count, err := ddb.SetSampleQualityRange(qinp.Channel, qinp.Start, qinp.End, q, 0)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"updated": count})
+}
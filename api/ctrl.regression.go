@@ -0,0 +1,121 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+
+	"jaQC-Go-API/utils"
+)
+
+const REGRESSION_DEGREE int = 1
+
+/* wls WEIGHTS BY QUALITY FLAG; bad AND excluded SAMPLES ONLY REACH THE FIT IF THE VARIATE KEEPS THEM */
+var REGRESSION_QUALITY_WEIGHTS = map[uint8]float64{
+	utils.QUALITY_GOOD:         1,
+	utils.QUALITY_INTERPOLATED: 0.5,
+	utils.QUALITY_SUSPECT:      0.25,
+	utils.QUALITY_BAD:          0,
+	utils.QUALITY_EXCLUDED:     0,
+}
+
+func (rq *RegressionQuery) Validate() (err error) {
+	switch rq.Method {
+	case "":
+		rq.Method = utils.REGRESS_POLY
+	case utils.REGRESS_POLY, utils.REGRESS_WLS, utils.REGRESS_HUBER:
+	case utils.REGRESS_THEIL_SEN:
+		if rq.Degree > 1 {
+			return fmt.Errorf("theil_sen regression is linear only")
+		}
+	default:
+		return fmt.Errorf("invalid regression method: %s", rq.Method)
+	}
+	if rq.Degree == 0 {
+		rq.Degree = REGRESSION_DEGREE
+	}
+	switch {
+	case rq.Degree < 0 || rq.Degree > utils.REGRESS_MAX_DEGREE:
+		err = fmt.Errorf("regression degree must be 1 to %d", utils.REGRESS_MAX_DEGREE)
+	case rq.K < 0:
+		err = fmt.Errorf("huber k is negative")
+	case rq.End != 0 && rq.End <= rq.Start:
+		err = fmt.Errorf("regression end must be after start")
+	}
+	return
+}
+
+/*
+FITS THE VARIATE'S CALIBRATED SAMPLES OVER [ start, end ), CLIPPED TO THE PROCESS WINDOW
+x IS IN SECONDS FROM x0, THE FIRST SAMPLE, SO Coefs[1] IS A RATE PER SECOND LIKE Aggregate.Slope
+*/
+func (vrt *Variate) Regression(rq RegressionQuery) (fit utils.Fit, x0 int64, err error) {
+
+	proc, err := GetProcessByID(vrt.PID)
+	if err != nil {
+		return
+	}
+	start, end, ok := proc.Window(rq.Start, rq.End)
+	if !ok {
+		err = fmt.Errorf("regression window is outside process %d", proc.ID)
+		return
+	}
+
+	ts, quals, err := vrt.GetTSXYQuality(start, end, vrt.QualityMask())
+	if err != nil {
+		return
+	}
+	if len(ts.X) == 0 {
+		err = fmt.Errorf("regression window holds no samples")
+		return
+	}
+
+	x0 = ts.X[0]
+	xs := make([]float64, len(ts.X))
+	ys := make([]float64, len(ts.Y))
+	for i := range ts.X {
+		xs[i] = float64(ts.X[i]-x0) / 1000
+		ys[i] = float64(ts.Y[i])
+	}
+
+	switch rq.Method {
+	case utils.REGRESS_WLS:
+		ws := make([]float64, len(quals))
+		for i, q := range quals {
+			ws[i] = REGRESSION_QUALITY_WEIGHTS[q]
+		}
+		fit, err = utils.WeightedPolyFit(xs, ys, ws, rq.Degree)
+	case utils.REGRESS_THEIL_SEN:
+		fit, err = utils.TheilSen(xs, ys)
+	case utils.REGRESS_HUBER:
+		fit, err = utils.Huber(xs, ys, rq.Degree, rq.K)
+	default:
+		fit, err = utils.PolyFit(xs, ys, rq.Degree)
+	}
+	return
+}
+
+/* HANDLERS ******************************************************************************/
+func HandleGetVariateRegression(c *fiber.Ctx) (err error) {
+
+	vrt, err := paramVariate(c)
+	if err != nil {
+		return
+	}
+
+	rq := RegressionQuery{}
+	if err = c.QueryParser(&rq); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err = rq.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	/* DEGENERATE INPUT IS THE CALLER'S WINDOW, NOT A SERVER FAULT */
+	fit, x0, err := vrt.Regression(rq)
+	if err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"regression": fit, "x0": x0})
+}
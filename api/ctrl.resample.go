@@ -0,0 +1,101 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"jaQC-Go-API/utils"
+)
+
+/* LARGEST GRID GET /api/processes/:id/resample WILL RETURN */
+const RESAMPLE_MAX_POINTS = int64(100000)
+
+/* THE GRID FOR THE QUERY, CLIPPED TO THE PROCESS WINDOW; AN OPEN PROCESS RUNS TO NOW */
+func (rq *ResampleQuery) Config(proc Process) (cfg utils.ResampleConfig, err error) {
+
+	if rq.Step <= 0 {
+		err = fmt.Errorf("resample step must be positive")
+		return
+	}
+	start, end, ok := proc.Window(rq.Start, rq.End)
+	if end == 0 {
+		end = time.Now().UTC().UnixMilli()
+	}
+	if !ok || end <= start {
+		err = fmt.Errorf("resample window is outside process %d", proc.ID)
+		return
+	}
+	n := (end - start + rq.Step - 1) / rq.Step
+	if n > RESAMPLE_MAX_POINTS {
+		err = fmt.Errorf("resample window is too large for step %d ms; %d points, limit %d", rq.Step, n, RESAMPLE_MAX_POINTS)
+		return
+	}
+
+	cfg = utils.ResampleConfig{
+		Start:  start,
+		Step:   rq.Step,
+		N:      int(n),
+		Interp: rq.Interp,
+		Agg:    rq.Agg,
+		MaxGap: rq.MaxGap,
+	}
+	err = cfg.Validate()
+	return
+}
+
+/* NaN HAS NO JSON FORM */
+func nullable(vs []float64) (out []*float64) {
+	out = make([]*float64, len(vs))
+	for i := range vs {
+		if !math.IsNaN(vs[i]) {
+			out[i] = &vs[i]
+		}
+	}
+	return
+}
+
+/* HANDLERS ******************************************************************************/
+func HandleGetProcessResample(c *fiber.Ctx) (err error) {
+
+	proc, err := paramProcess(c)
+	if err != nil {
+		return
+	}
+
+	rq := ResampleQuery{}
+	if err = c.QueryParser(&rq); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	cfg, err := rq.Config(proc)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	vrts, err := GetVariateListByProcess(proc.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	if vrts, err = SelectVariates(vrts, rq.VIDs, proc.ID); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	start, end := cfg.Span()
+	rvs := []ResampledVariate{}
+	for _, vrt := range vrts {
+		ts, ts_err := vrt.GetTSXY(start, end)
+		if ts_err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString(ts_err.Error())
+		}
+		rvs = append(rvs, ResampledVariate{
+			VID:  vrt.ID,
+			Name: vrt.Name,
+			Unit: vrt.Unit,
+			Y:    nullable(utils.Resample(ts, cfg)),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"x": cfg.Grid(), "variates": rvs})
+}
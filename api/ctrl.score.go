@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+
+	"jaQC-Go-API/utils"
+)
+
+const JOB_TYPE_SCORE string = "score"
+
+func (agg *Aggregate) Stats() utils.ClusterStats {
+	return utils.ClusterStats{
+		Start: agg.Start,
+		End:   agg.End,
+		Size:  agg.Size,
+		Min:   agg.Min,
+		Max:   agg.Max,
+		Mean:  agg.Mean,
+		Slope: agg.Slope,
+		Devi:  agg.Devi,
+	}
+}
+
+/* SETS Score, Verdict AND ScoreVersion ON agg */
+func (rs *ScoreRuleSet) Score(agg *Aggregate) {
+	score, verdict := rs.Evaluate(agg.Stats())
+	agg.Score = float32(score)
+	agg.Verdict = verdict
+	agg.ScoreVersion = rs.Version
+}
+
+/*
+SCORES THE VARIATE'S AGGREGATES OVER [ start, end ) WITH ITS LATEST RULE SET
+AGGREGATES IT ALREADY SCORED THE SAME ARE NOT WRITTEN; RETURNS THE NUMBER THAT FAIL
+*/
+func (vrt *Variate) Rescore(ctx context.Context, owner, start, end int64, progress func(curr, end float32)) (failed int64, err error) {
+
+	rs, err := GetLatestScoreRuleSet(vrt.ID)
+	if err != nil {
+		return
+	}
+	if rs == nil {
+		err = fmt.Errorf("variate %d has no score rule set", vrt.ID)
+		return
+	}
+
+	aggs, err := GetAggregateListByVariateWindow(vrt.ID, start, end)
+	if err != nil {
+		return
+	}
+
+	for i := range aggs {
+		agg := &aggs[i]
+
+		if i%SCORE_PROGRESS_AGGREGATES == 0 {
+			if err = ctx.Err(); err != nil {
+				return
+			}
+			if progress != nil {
+				progress(float32(i), float32(len(aggs)))
+			}
+		}
+
+		was := *agg
+		rs.Score(agg)
+		if agg.Verdict == utils.SCORE_FAIL {
+			failed++
+		}
+		if agg.Score == was.Score && agg.Verdict == was.Verdict && agg.ScoreVersion == was.ScoreVersion {
+			continue
+		}
+		if err = agg.UpdateScore(owner); err != nil {
+			return
+		}
+	}
+	return
+}
+
+/* HANDLERS ******************************************************************************/
+func HandleGetVariateScoreRules(c *fiber.Ctx) (err error) {
+
+	vrt, err := paramVariate(c)
+	if err != nil {
+		return
+	}
+
+	sets, err := GetScoreRuleSetListByVariate(vrt.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"score_rule_sets": sets})
+}
+
+/* WRITES THE NEXT VERSION; EXISTING AGGREGATES KEEP THEIR SCORES UNTIL A SCORE JOB RUNS */
+func HandleCreateVariateScoreRules(c *fiber.Ctx) (err error) {
+
+	vrt, err := paramVariate(c)
+	if err != nil {
+		return
+	}
+
+	rinp := ScoreRuleSetInput{}
+	if err = utils.ParseRequestBody(c, &rinp); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err = rinp.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	rinp.Defaults()
+
+	rs := ScoreRuleSet{
+		VID:        vrt.ID,
+		Note:       rinp.Note,
+		ScoreRules: rinp.ScoreRules,
+	}
+	if err = rs.Create(LocalsUserID(c)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	/* THE NEXT SAMPLES START A STREAM SCORING WITH THE NEW VERSION */
+	if err = FlushClusterStreams(func(cs *ClusterStream) bool { return cs.ID == vrt.ID }); err != nil {
+		utils.LogErr(err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"score_rule_set": rs})
+}
+
+func HandleStartScoring(c *fiber.Ctx) (err error) {
+
+	vrt, err := paramVariate(c)
+	if err != nil {
+		return
+	}
+
+	sinp := ScoreInput{}
+	if err = utils.ParseRequestBody(c, &sinp); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if sinp.End != 0 && sinp.End <= sinp.Start {
+		return c.Status(fiber.StatusBadRequest).SendString("score end must be after start")
+	}
+	rs, err := GetLatestScoreRuleSet(vrt.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	if rs == nil {
+		return c.Status(fiber.StatusBadRequest).SendString("variate has no score rule set")
+	}
+
+	label := fmt.Sprintf("variate %d : score v%d", vrt.ID, rs.Version)
+	job, err := StartJob(JOB_TYPE_SCORE, label, LocalsUserID(c), func(job *Job) (ref string, err error) {
+		if _, err = vrt.Rescore(job.Context(), job.Owner, sinp.Start, sinp.End, job.Progress); err != nil {
+			return
+		}
+		ref = fmt.Sprintf("aggregates?vid=%d&verdict=%s", vrt.ID, utils.SCORE_FAIL)
+		return
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"job": job})
+}
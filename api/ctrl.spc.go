@@ -0,0 +1,352 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"jaQC-Go-API/utils"
+)
+
+const JOB_TYPE_SPC string = "spc"
+
+/* THE VARIATE'S SPC SETTINGS WITH ZERO FIELDS DEFAULTED; AN EMPTY Chart MEANS SPC IS OFF */
+func (vrt *Variate) SPCConfig() (cfg utils.SPCConfig, err error) {
+	cfg = vrt.SPC
+	if err = cfg.Validate(); err != nil {
+		err = fmt.Errorf("variate %d: %s", vrt.ID, err.Error())
+		return
+	}
+	cfg.Defaults()
+	return
+}
+
+func (sq *SPCQuery) Apply(cfg *utils.SPCConfig) {
+	if sq.Chart != "" {
+		cfg.Chart = sq.Chart
+	}
+	if sq.Source != "" {
+		cfg.Source = sq.Source
+	}
+	if sq.Subgroup != 0 {
+		cfg.Subgroup = sq.Subgroup
+	}
+	if sq.Rules != "" {
+		cfg.Rules = sq.Rules
+	}
+	if sq.BaselineEnd != 0 {
+		cfg.BaselineStart = sq.BaselineStart
+		cfg.BaselineEnd = sq.BaselineEnd
+	}
+	if sq.Lambda != 0 {
+		cfg.Lambda = sq.Lambda
+	}
+	if sq.L != 0 {
+		cfg.L = sq.L
+	}
+}
+
+/* EACH AGGREGATE IS ONE SUBGROUP OF ITS CLUSTER'S SAMPLES */
+func (agg *Aggregate) Subgroup() utils.Subgroup {
+	return utils.Subgroup{
+		X:     agg.Start,
+		End:   agg.End,
+		N:     int(agg.Size),
+		Mean:  float64(agg.Mean),
+		Range: float64(agg.Max - agg.Min),
+		Devi:  float64(agg.Devi),
+	}
+}
+
+/* SUBGROUPS OF THE VARIATE OVER [ start, end ) FROM THE CONFIGURED SOURCE */
+func (vrt *Variate) SPCSubgroups(cfg utils.SPCConfig, start, end int64) (sgs []utils.Subgroup, err error) {
+
+	if cfg.Source == utils.SPC_SOURCE_SAMPLES {
+		ts, ts_err := vrt.GetTSXY(start, end)
+		if ts_err != nil {
+			return nil, ts_err
+		}
+		return utils.Subgroups(ts, cfg.Subgroup), nil
+	}
+
+	aggs, err := GetAggregateListByVariateWindow(vrt.ID, start, end)
+	if err != nil {
+		return
+	}
+	for i := range aggs {
+		sgs = append(sgs, aggs[i].Subgroup())
+	}
+	return
+}
+
+/*
+PLOTS [ start, end ), CLIPPED TO THE PROCESS WINDOW
+LIMITS COME FROM THE BASELINE WHEN ONE IS SET ( IT MAY LIE OUTSIDE THE PROCESS ), ELSE FROM THE PLOTTED SUBGROUPS
+*/
+func (vrt *Variate) SPCChart(cfg utils.SPCConfig, start, end int64) (chart utils.SPCChart, err error) {
+
+	if !cfg.Enabled() {
+		err = fmt.Errorf("variate %d has no spc chart configured", vrt.ID)
+		return
+	}
+
+	proc, err := GetProcessByID(vrt.PID)
+	if err != nil {
+		return
+	}
+	start, end, ok := proc.Window(start, end)
+	if !ok {
+		err = fmt.Errorf("spc window is outside process %d", proc.ID)
+		return
+	}
+
+	sgs, err := vrt.SPCSubgroups(cfg, start, end)
+	if err != nil {
+		return
+	}
+	base := sgs
+	if cfg.BaselineEnd != 0 {
+		if base, err = vrt.SPCSubgroups(cfg, cfg.BaselineStart, cfg.BaselineEnd); err != nil {
+			return
+		}
+	}
+
+	return utils.BuildSPCChart(cfg, base, sgs)
+}
+
+/*
+SETS Valid AND Rule ON THE VARIATE'S AGGREGATES OVER [ start, end ) FROM ITS SPC CHART
+AN AGGREGATE IS INVALID IF ANY FLAGGED POINT OVERLAPS IT; RETURNS THE NUMBER FLAGGED
+*/
+func (vrt *Variate) ApplySPC(ctx context.Context, owner, start, end int64, progress func(curr, end float32)) (flagged int64, err error) {
+
+	cfg, err := vrt.SPCConfig()
+	if err != nil {
+		return
+	}
+	chart, err := vrt.SPCChart(cfg, start, end)
+	if err != nil {
+		return
+	}
+
+	aggs, err := GetAggregateListByVariateWindow(vrt.ID, start, end)
+	if err != nil {
+		return
+	}
+
+	/* POINTS AND AGGREGATES ARE BOTH IN TIME ORDER AND NEITHER OVERLAP THEMSELVES */
+	pts := chart.Points
+	j := 0
+	for i := range aggs {
+		agg := &aggs[i]
+
+		if i%SPC_PROGRESS_AGGREGATES == 0 {
+			if err = ctx.Err(); err != nil {
+				return
+			}
+			if progress != nil {
+				progress(float32(i), float32(len(aggs)))
+			}
+		}
+
+		for j < len(pts) && pts[j].End < agg.Start {
+			j++
+		}
+		rules := []string{}
+		for k := j; k < len(pts) && pts[k].X <= agg.End; k++ {
+			rules = mergeRules(rules, pts[k].Rules)
+		}
+
+		valid, rule := len(rules) == 0, strings.Join(rules, ",")
+		if !valid {
+			flagged++
+		}
+		if agg.Valid == valid && agg.Rule == rule {
+			continue
+		}
+		agg.Valid, agg.Rule = valid, rule
+		if err = agg.UpdateSPC(owner); err != nil {
+			return
+		}
+	}
+	return
+}
+
+/* ADDS THE RULES OF add NOT ALREADY IN rules, KEEPING THEM SORTED */
+func mergeRules(rules, add []string) []string {
+	for _, r := range add {
+		seen := false
+		for _, have := range rules {
+			seen = seen || have == r
+		}
+		if !seen {
+			rules = append(rules, r)
+		}
+	}
+	sort.Strings(rules)
+	return rules
+}
+
+/* LIVE SPC FOR A CLUSTER STREAM; NEEDS A BASELINE, AS THE STREAM NEVER SEES ITS WHOLE WINDOW */
+func (cs *ClusterStream) watchSPC() (err error) {
+
+	cfg, err := cs.SPCConfig()
+	if err != nil || !cfg.Enabled() || cfg.BaselineEnd == 0 {
+		return
+	}
+
+	base, err := cs.SPCSubgroups(cfg, cfg.BaselineStart, cfg.BaselineEnd)
+	if err != nil {
+		return
+	}
+	lim, err := utils.SPCEstimate(cfg, base)
+	if err != nil {
+		return
+	}
+
+	cs.spc = utils.NewSPCMonitor(cfg, lim)
+	return
+}
+
+/* CALLED FROM Push, UNDER cs.mut, BEFORE THE SAMPLE REACHES THE Clusterer */
+func (cs *ClusterStream) pushSPC(x int64, y float32) {
+	if cs.spc == nil || cs.spc.Config.Source != utils.SPC_SOURCE_SAMPLES {
+		return
+	}
+	cs.spcBuf.X = append(cs.spcBuf.X, x)
+	cs.spcBuf.Y = append(cs.spcBuf.Y, y)
+	if len(cs.spcBuf.X) < cs.spc.Config.Subgroup {
+		return
+	}
+	for _, sg := range utils.Subgroups(cs.spcBuf, cs.spc.Config.Subgroup) {
+		if pt := cs.spc.Next(sg); !pt.InControl() {
+			cs.spcFlags = append(cs.spcFlags, pt)
+		}
+	}
+	cs.spcBuf = utils.TSXY{}
+}
+
+/* SETS Valid AND Rule ON A CLOSING AGGREGATE */
+func (cs *ClusterStream) judgeSPC(agg *Aggregate) {
+	if cs.spc == nil {
+		return
+	}
+
+	rules := []string{}
+	if cs.spc.Config.Source == utils.SPC_SOURCE_AGGREGATES {
+		rules = mergeRules(rules, cs.spc.Next(agg.Subgroup()).Rules)
+	} else {
+		keep := cs.spcFlags[:0]
+		for _, pt := range cs.spcFlags {
+			if pt.X <= agg.End && pt.End >= agg.Start {
+				rules = mergeRules(rules, pt.Rules)
+			}
+			if pt.End > agg.End {
+				keep = append(keep, pt)
+			}
+		}
+		cs.spcFlags = keep
+	}
+
+	agg.Valid = len(rules) == 0
+	agg.Rule = strings.Join(rules, ",")
+}
+
+/* HANDLERS ******************************************************************************/
+func HandleGetVariateSPC(c *fiber.Ctx) (err error) {
+
+	vrt, err := paramVariate(c)
+	if err != nil {
+		return
+	}
+
+	sq := SPCQuery{}
+	if err = c.QueryParser(&sq); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if sq.End != 0 && sq.End <= sq.Start {
+		return c.Status(fiber.StatusBadRequest).SendString("spc end must be after start")
+	}
+
+	cfg := vrt.SPC
+	sq.Apply(&cfg)
+	if err = cfg.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	cfg.Defaults()
+
+	chart, err := vrt.SPCChart(cfg, sq.Start, sq.End)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"spc": chart, "rule_text": utils.SPC_RULE_TEXT})
+}
+
+func HandleUpdateVariateSPC(c *fiber.Ctx) (err error) {
+
+	vrt, err := paramVariate(c)
+	if err != nil {
+		return
+	}
+
+	cfg := utils.SPCConfig{}
+	if err = utils.ParseRequestBody(c, &cfg); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	vrt.SPC = cfg
+	if _, err = vrt.SPCConfig(); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err = vrt.Update(LocalsUserID(c)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	/* THE NEXT SAMPLES START A STREAM WITH THE NEW LIMITS */
+	if err = FlushClusterStreams(func(cs *ClusterStream) bool { return cs.ID == vrt.ID }); err != nil {
+		utils.LogErr(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"variate": vrt})
+}
+
+func HandleStartSPC(c *fiber.Ctx) (err error) {
+
+	vrt, err := paramVariate(c)
+	if err != nil {
+		return
+	}
+
+	sinp := SPCInput{}
+	if err = utils.ParseRequestBody(c, &sinp); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if sinp.End != 0 && sinp.End <= sinp.Start {
+		return c.Status(fiber.StatusBadRequest).SendString("spc end must be after start")
+	}
+	cfg, err := vrt.SPCConfig()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if !cfg.Enabled() {
+		return c.Status(fiber.StatusBadRequest).SendString("variate has no spc chart configured")
+	}
+
+	label := fmt.Sprintf("variate %d : spc", vrt.ID)
+	job, err := StartJob(JOB_TYPE_SPC, label, LocalsUserID(c), func(job *Job) (ref string, err error) {
+		if _, err = vrt.ApplySPC(job.Context(), job.Owner, sinp.Start, sinp.End, job.Progress); err != nil {
+			return
+		}
+		ref = fmt.Sprintf("aggregates?vid=%d&valid=false", vrt.ID)
+		return
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"job": job})
+}
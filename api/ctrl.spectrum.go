@@ -0,0 +1,112 @@
+package api
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gofiber/fiber/v2"
+
+	"jaQC-Go-API/utils"
+)
+
+/* LARGEST EVENLY SPACED SERIES A SPECTRUM IS TAKEN OVER */
+const SPECTRUM_MAX_POINTS = int64(1 << 21)
+
+/* GAPS LONGER THAN THIS MANY STEPS ARE NOT INTERPOLATED; SEGMENTS SPANNING THEM ARE SKIPPED */
+const SPECTRUM_GAP_STEPS = int64(3)
+
+/*
+WELCH PSD OF THE VARIATE'S CALIBRATED SAMPLES OVER [ start, end ), CLIPPED TO THE PROCESS WINDOW
+THE SAMPLES ARE FIRST PUT ON AN EVEN GRID; TIMESTAMPS ARE IN ms, SO THE RATE IS ROUNDED TO A WHOLE ms STEP
+*/
+func (vrt *Variate) Spectrum(sq SpectrumQuery) (sp utils.Spectrum, err error) {
+
+	proc, err := GetProcessByID(vrt.PID)
+	if err != nil {
+		return
+	}
+	start, end, ok := proc.Window(sq.Start, sq.End)
+	if !ok {
+		err = fmt.Errorf("spectrum window is outside process %d", proc.ID)
+		return
+	}
+
+	ts, err := vrt.GetTSXY(start, end)
+	if err != nil {
+		return
+	}
+	if len(ts.X) < 2 {
+		err = fmt.Errorf("spectrum window holds fewer than 2 samples")
+		return
+	}
+
+	rate := sq.Rate
+	if rate == 0 {
+		rate = float64(vrt.SampleRate)
+	}
+	if rate == 0 {
+		dts := make([]float64, len(ts.X)-1)
+		for i := range dts {
+			dts[i] = float64(ts.X[i+1] - ts.X[i])
+		}
+		if med := utils.Median(dts); med > 0 {
+			rate = 1000 / med
+		}
+	}
+	if !(rate > 0) || math.IsInf(rate, 0) {
+		err = fmt.Errorf("spectrum rate must be positive")
+		return
+	}
+
+	step := max(1, int64(math.Round(1000/rate)))
+	n := (ts.X[len(ts.X)-1]-ts.X[0])/step + 1
+	if n > SPECTRUM_MAX_POINTS {
+		err = fmt.Errorf("spectrum window is too large for %g Hz; %d points, limit %d", rate, n, SPECTRUM_MAX_POINTS)
+		return
+	}
+	grid := utils.ResampleConfig{
+		Start:  ts.X[0],
+		Step:   step,
+		N:      int(n),
+		Interp: utils.RESAMPLE_LINEAR,
+		Agg:    utils.RESAMPLE_MEAN,
+		MaxGap: SPECTRUM_GAP_STEPS * step,
+	}
+
+	return utils.Welch(utils.Resample(ts, grid), utils.PSDConfig{
+		Rate:    1000 / float64(step),
+		Segment: sq.Segment,
+		Overlap: sq.Overlap,
+		Window:  sq.Window,
+		Detrend: sq.Detrend,
+		Peaks:   sq.Peaks,
+	})
+}
+
+/* HANDLERS ******************************************************************************/
+func HandleGetVariateSpectrum(c *fiber.Ctx) (err error) {
+
+	vrt, err := paramVariate(c)
+	if err != nil {
+		return
+	}
+
+	sq := SpectrumQuery{}
+	if err = c.QueryParser(&sq); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if sq.End != 0 && sq.End <= sq.Start {
+		return c.Status(fiber.StatusBadRequest).SendString("spectrum end must be after start")
+	}
+	if sq.Rate < 0 {
+		return c.Status(fiber.StatusBadRequest).SendString("spectrum rate is negative")
+	}
+
+	/* A BAD OPTION OR TOO SHORT A WINDOW IS THE CALLER'S TO FIX */
+	sp, err := vrt.Spectrum(sq)
+	if err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"spectrum": sp})
+}
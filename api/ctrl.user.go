@@ -52,6 +52,14 @@ func RoleCheckViewer(c *fiber.Ctx) (err error) {
 	return c.Status(fiber.StatusUnauthorized).SendString(AUTH_MSG_VIEWER)
 }
 
+/* USER ID SET BY JWT.Authenticate; JWT NUMBERS ARRIVE AS float64 */
+func LocalsUserID(c *fiber.Ctx) (uid int64) {
+	if sub, ok := c.Locals("sub").(float64); ok {
+		uid = int64(sub)
+	}
+	return
+}
+
 /* SAFE RESPONSE DATA */
 func (user *User) FilterUserRecord() UserResponse {
 	return UserResponse{
@@ -59,6 +67,7 @@ func (user *User) FilterUserRecord() UserResponse {
 		Name:      user.Name,
 		Email:     user.Email,
 		Role:      user.Role,
+		Org:       user.Org,
 		CreatedAt: user.CreatedAt,
 		UpdatedAt: user.UpdatedAt,
 	}
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+/* EVENTS KEPT PER SESSION FOR Last-Event-ID RESUME */
+const SSE_BUFFER_SIZE = 256
+
+/* EVENTS QUEUED PER SUBSCRIBER BEFORE IT IS DROPPED; THE CLIENT RECONNECTS AND RESUMES */
+const SSE_SUB_QUEUE = 64
+
+type SSEEvent struct {
+	ID   int64
+	Type string
+	Data string
+}
+
+/* WRITES THE EVENT IN text/event-stream FORMAT */
+func (evt SSEEvent) Write(w *bufio.Writer) (err error) {
+	if evt.ID > 0 {
+		fmt.Fprintf(w, "id: %d\n", evt.ID)
+	}
+	fmt.Fprintf(w, "event: %s\n", evt.Type)
+	for _, line := range strings.Split(evt.Data, "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+	return w.Flush()
+}
+
+type SSEStream struct {
+	*sync.RWMutex
+	LastID int64
+	Events []SSEEvent
+	Subs   map[chan SSEEvent]struct{}
+}
+
+/* ADDS EVENT TO THE RING BUFFER AND FANS IT OUT TO SUBSCRIBERS */
+func (strm *SSEStream) Publish(typ, data string) {
+	strm.Lock()
+	defer strm.Unlock()
+
+	strm.LastID++
+	evt := SSEEvent{ID: strm.LastID, Type: typ, Data: data}
+
+	strm.Events = append(strm.Events, evt)
+	if len(strm.Events) > SSE_BUFFER_SIZE {
+		strm.Events = strm.Events[len(strm.Events)-SSE_BUFFER_SIZE:]
+	}
+
+	for sub := range strm.Subs {
+		select {
+		case sub <- evt:
+		default:
+			/* SLOW CONSUMER; DROP IT */
+			delete(strm.Subs, sub)
+			close(sub)
+		}
+	}
+}
+
+/* RETURNS A SUBSCRIPTION AND, WHEN resume, ANY BUFFERED EVENTS AFTER lastID */
+func (strm *SSEStream) Subscribe(lastID int64, resume bool) (sub chan SSEEvent, missed []SSEEvent) {
+	strm.Lock()
+	defer strm.Unlock()
+
+	for _, evt := range strm.Events {
+		if resume && evt.ID > lastID {
+			missed = append(missed, evt)
+		}
+	}
+
+	sub = make(chan SSEEvent, SSE_SUB_QUEUE)
+	strm.Subs[sub] = struct{}{}
+	return
+}
+
+func (strm *SSEStream) Unsubscribe(sub chan SSEEvent) {
+	strm.Lock()
+	if _, ok := strm.Subs[sub]; ok {
+		delete(strm.Subs, sub)
+		close(sub)
+	}
+	strm.Unlock()
+}
+
+func (strm *SSEStream) Close() {
+	strm.Lock()
+	for sub := range strm.Subs {
+		delete(strm.Subs, sub)
+		close(sub)
+	}
+	strm.Unlock()
+}
+
+type SSEStreamMap map[string]*SSEStream
+var SSEStreamsMap = make(SSEStreamMap)
+var SSEStreamsMapRWMutex = sync.RWMutex{}
+
+/* RETURNS THE STREAM FOR GIVEN SESSION, CREATING IT IF NEEDED */
+func SSEStreamsMapGet(sid string) (strm *SSEStream) {
+	SSEStreamsMapRWMutex.Lock()
+	strm, ok := SSEStreamsMap[sid]
+	if !ok {
+		strm = &SSEStream{
+			RWMutex: &sync.RWMutex{},
+			Subs:    make(map[chan SSEEvent]struct{}),
+		}
+		SSEStreamsMap[sid] = strm
+	}
+	SSEStreamsMapRWMutex.Unlock()
+	return
+}
+func SSEStreamsMapRemove(sid string) {
+	SSEStreamsMapRWMutex.Lock()
+	strm, ok := SSEStreamsMap[sid]
+	delete(SSEStreamsMap, sid)
+	SSEStreamsMapRWMutex.Unlock()
+
+	if ok {
+		strm.Close()
+	}
+}
+
+/* CALLED BY WSSendMessage */
+func SSEPublish(sid, typ, data string) {
+	SSEStreamsMapGet(sid).Publish(typ, data)
+}
+
+/* SSE HANDLER; REQUIRES HandleUserSessionAuth */
+func HandleSSEConnect(c *fiber.Ctx) (err error) {
+
+	ussn, ok := c.Locals("ussn").(UserSession)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).SendString("user session not found; please log in")
+	}
+	sid := ussn.SID.String()
+
+	/* EventSource SENDS THE HEADER ON RECONNECT; THE QUERY ALLOWS A MANUAL RESUME */
+	/* A FIRST CONNECTION SENDS NEITHER AND GETS ONLY NEW EVENTS */
+	last_id := c.Get("Last-Event-ID", c.Query("last_event_id"))
+	lastID, id_err := strconv.ParseInt(last_id, 10, 64)
+
+	strm := SSEStreamsMapGet(sid)
+	sub, missed := strm.Subscribe(lastID, last_id != "" && id_err == nil)
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer strm.Unsubscribe(sub)
+
+		/* TELL EventSource HOW LONG TO WAIT BEFORE RECONNECTING */
+		fmt.Fprintf(w, "retry: %d\n\n", WS_WRITE_WAIT.Milliseconds())
+
+		for _, evt := range missed {
+			if err := evt.Write(w); err != nil {
+				return
+			}
+		}
+
+		live := time.NewTicker(WS_PING_DUR)
+		defer live.Stop()
+
+		for {
+			select {
+
+			case evt, open := <- sub:
+				if !open {
+					log.Info("HandleSSEConnect() -> STREAM CLOSED : ", sid)
+					return
+				}
+				if err := evt.Write(w); err != nil {
+					return
+				}
+
+			case now := <- live.C:
+				/* A FAILED FLUSH MEANS THE CLIENT IS GONE */
+				js := fmt.Sprintf(`{"type":"live","data":"%s"}`, now.UTC().Format(time.RFC3339Nano))
+				if err := (SSEEvent{Type: "live", Data: js}).Write(w); err != nil {
+					return
+				}
+			}
+		}
+	})
+
+	return
+}
@@ -18,6 +18,8 @@ import (
 )
 
 const WS_PING_DUR = time.Second * 30
+const WS_PONG_WAIT = WS_PING_DUR * 2
+const WS_WRITE_WAIT = time.Second * 10
 const WS_MAX_ERR = int64(10)
 const WS_MIN_ERR_SEC = 3
 
@@ -77,6 +79,8 @@ func UserSessionsMapRemove(usid string) {
 	UserSessionsMapRWMutex.Lock()
 	delete(UserSessionsMap, usid)
 	UserSessionsMapRWMutex.Unlock()
+
+	SSEStreamsMapRemove(usid)
 }
 
 /* AUTHENTICATE USER INPUT AND RETURN JWTs */
@@ -245,17 +249,61 @@ func (ussn *UserSession) RefreshAccessToken() (err error) {
 	return UserSessionsMapWrite(*ussn)
 }
 
+/* SESSION MIDDLEWARE; REQUIRES JWT.Authenticate AND A VALID SESSION ID ( ?sid= ) */
+func HandleUserSessionAuth(c *fiber.Ctx) (err error) {
+
+	ussn, err := GetAuthUserSession(c.Query("sid"))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).SendString(err.Error())
+	}
+
+	/* THE TOKEN MUST BELONG TO THE SESSION'S USER */
+	if ussn.USR.ID != LocalsUserID(c) {
+		return c.Status(fiber.StatusUnauthorized).SendString("token does not match user session")
+	}
+
+	c.Locals("ussn", ussn)
+	return c.Next()
+}
+
+/* UPGRADE MIDDLEWARE; REQUIRES HandleUserSessionAuth */
+func HandleWSUpgrade(c *fiber.Ctx) (err error) {
+
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+	return c.Next()
+}
+
+/* WEBSOCKET HANDLER; RUNS UNTIL THE CONNECTION CLOSES */
+func HandleWSConnect(ws *websocket.Conn) {
+	ussn, ok := ws.Locals("ussn").(UserSession)
+	if !ok {
+		log.Error("HandleWSConnect() -> NO USER SESSION")
+		return
+	}
+	ussn.WSConnect(ws)
+}
+
 func (ussn *UserSession) WSConnect(ws *websocket.Conn) {
 
-	ussn.RWMChan = &sync.RWMutex{}
+	if ussn.RWMChan == nil {
+		ussn.RWMChan = &sync.RWMutex{}
+	}
 	ussn.CloseWSListen = make(chan struct{})
 	ussn.CloseWSSend = make(chan struct{})
-	ussn.WSClosedByClient = make(chan struct{})
-	ussn.WSReceiveErrorLimit = make(chan struct{})
-	ussn.WSSendErrorLimit = make(chan struct{})
+	ussn.WSClosedByClient = make(chan struct{}, 1)
+	ussn.WSReceiveErrorLimit = make(chan struct{}, 1)
+	ussn.WSSendErrorLimit = make(chan struct{}, 1)
 	ussn.DataOut = make(chan string)
 	ussn.Connected = true
 
+	/* ANY PONG FROM THE CLIENT EXTENDS THE READ DEADLINE; A DEAD PEER TIMES OUT */
+	ws.SetReadDeadline(time.Now().Add(WS_PONG_WAIT))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(WS_PONG_WAIT))
+	})
+
 	if err := UserSessionsMapWrite(*ussn); err != nil {
 		utils.LogErr(err)
 	}
@@ -264,56 +312,51 @@ func (ussn *UserSession) WSConnect(ws *websocket.Conn) {
 
 	go ussn.WSRunMessageSender(ws)
 
-	nextPing := time.Now().UTC().Add(WS_PING_DUR).UnixMilli()
-	// conn := true
-	for ussn.Connected  {
+	ping := time.NewTicker(WS_PING_DUR)
+	for ussn.Connected {
 		select {
 
 		case <- ussn.WSClosedByClient:
 			log.Info("WSConnect() -> ussn.WSClosedByClient -> CLOSING...")
-			ussn.Connected  = false
+			ussn.Connected = false
 
 		case <- ussn.WSReceiveErrorLimit:
 			log.Info("WSConnect() -> ussn.WSReceiveErrorLimit -> CLOSING...")
-			ussn.Connected  = false
+			ussn.Connected = false
 		
 		case <- ussn.WSSendErrorLimit:
 			log.Info("WSConnect() -> ussn.WSSendErrorLimit -> CLOSING...")
-			ussn.Connected  = false
-
-		default:
-			if time.Now().UTC().UnixMilli() > nextPing {
-
-				nextPing = time.Now().UTC().Add(WS_PING_DUR).UnixMilli()
-				
-				if err := ussn.WSSendMessage("live", time.Now().UTC()); err != nil {
-					log.Info("WSConnect() -> ERROR SENDING PING : ", err.Error())
-				}
+			ussn.Connected = false
+
+		case <- ping.C:
+			/* PROTOCOL LEVEL PING; WriteControl IS SAFE ALONGSIDE THE SENDER */
+			if err := ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(WS_WRITE_WAIT)); err != nil {
+				log.Info("WSConnect() -> ERROR SENDING PING -> CLOSING... ", err.Error())
+				ussn.Connected = false
+				continue
+			}
 
+			live := *ussn
+			if err := live.WSSendMessage("live", time.Now().UTC()); err != nil {
+				log.Info("WSConnect() -> ERROR SENDING LIVE : ", err.Error())
 			}
 		}
 	}
+	ping.Stop()
 
-	if ussn.CloseWSListen != nil {
-		ussn.CloseWSListen <- struct{}{}
-		close(ussn.CloseWSListen)
-		ussn.CloseWSListen = nil
-	}
-
-	if ussn.CloseWSSend != nil {
-		ussn.CloseWSSend <- struct{}{}
-		close(ussn.CloseWSSend)
-		ussn.CloseWSSend = nil
-	}
-
-	if ussn.DataOut != nil {
-		close(ussn.DataOut)
-		ussn.DataOut = nil
-	}
-
+	/* MARK THE SESSION DISCONNECTED BEFORE STOPPING THE SENDER SO NO WRITER BLOCKS ON DataOut */
+	ussn.RWMChan.Lock()
+	ussn.DataOut = nil
 	if err := UserSessionsMapWrite(*ussn); err != nil {
 		utils.LogErr(err)
 	}
+	ussn.RWMChan.Unlock()
+
+	close(ussn.CloseWSSend)
+	close(ussn.CloseWSListen)
+
+	/* UNBLOCK ANY PENDING ReadMessage */
+	ws.Close()
 
 	log.Info("WSConnect() -> CLOSED.")
 }
@@ -340,54 +383,43 @@ func MaxWSError(start, count int64) (outStart, outCount int64, limit bool) {
 
 func (ussn *UserSession) WSListenForMessages(ws *websocket.Conn) {
 
-	start := time.Now().UTC().Unix()
-	count := int64(0)
-	limit := false
+	closed := ussn.WSClosedByClient
+	stop := ussn.CloseWSListen
+
+	signal := func(ch chan struct{}) {
+		select {
+		case ch <- struct{}{}:
+		case <- stop:
+		}
+	}
 
 	listen := true
 	for listen {
 
-		select {
-
-		case <- ussn.CloseWSListen:
-			// log.Info("WSListenForMessages() -> CLOSING.")
+		/* BLOCKS UNTIL A MESSAGE ARRIVES OR THE READ DEADLINE PASSES */
+		_, msg, err := ws.ReadMessage()
+		if err != nil {
+			/* READ ERRORS ARE PERMANENT; CLOSED BY CLIENT, TIMED OUT OR CLOSED BY WSConnect */
+			log.Info("error reading websocket message: ", err.Error())
+			signal(closed)
 			listen = false
-
-		default:
-			_, msg, err := ws.ReadMessage()
-			if err != nil {
-				if strings.Contains(err.Error(), "close") {
-					log.Info("error reading websocket message: ", err.Error())
-					msg = []byte("close")
-				} else {
-					log.Info("error reading websocket message: ", err.Error())
-					if start, count, limit = MaxWSError(start, count); limit {
-						log.Error("CLOSING WS CONNECTION; MAX RECEIVE ERRORS")
-						ussn.WSReceiveErrorLimit <- struct{}{}
-					}
-				}
-			}
-
-			if string(msg) == "close" {
-				ussn.WSClosedByClient <- struct{}{}
-			}
+			continue
 		}
 
+		if string(msg) == "close" {
+			signal(closed)
+			listen = false
+		}
 	}
-
-	if ussn.WSClosedByClient != nil {
-		close(ussn.WSClosedByClient)
-		ussn.WSClosedByClient = nil
-	}
-
-	if ussn.WSReceiveErrorLimit != nil {
-		close(ussn.WSReceiveErrorLimit)
-		ussn.WSReceiveErrorLimit = nil
-	}// log.Info("WSListenForMessages() -> STOPPED.")
+	// log.Info("WSListenForMessages() -> STOPPED.")
 }
 
 func (ussn *UserSession) WSRunMessageSender(ws *websocket.Conn) {
 
+	data_out := ussn.DataOut
+	limit_out := ussn.WSSendErrorLimit
+	stop := ussn.CloseWSSend
+
 	start := time.Now().UTC().Unix()
 	count := int64(0)
 	limit := false
@@ -397,25 +429,25 @@ func (ussn *UserSession) WSRunMessageSender(ws *websocket.Conn) {
 
 		select {
 
-		case <- ussn.CloseWSSend:
+		case <- stop:
 			// log.Info("WSRunMessageSender() -> CLOSING.")
 			send = false
 
-		case data := <- ussn.DataOut:
+		case data := <- data_out:
+			ws.SetWriteDeadline(time.Now().Add(WS_WRITE_WAIT))
 			if err := ws.WriteJSON(data); err != nil {
 				log.Error("error sending websocket message: ", err.Error())
 				if start, count, limit = MaxWSError(start, count); limit {
 					log.Error("CLOSING WS CONNECTION; MAX SEND ERRORS")
-					ussn.WSSendErrorLimit <- struct{}{}
+					select {
+					case limit_out <- struct{}{}:
+					default:
+					}
 				}
 			}
 		}
 	}
-
-	if ussn.WSSendErrorLimit != nil {
-		close(ussn.WSSendErrorLimit)
-		ussn.WSSendErrorLimit = nil
-	}// log.Info("WSRunMessageSender() -> STOPPED.")
+	// log.Info("WSRunMessageSender() -> STOPPED.")
 }
 
 
@@ -423,17 +455,19 @@ type ProgressMessage struct {
 	Source string `json:"source"`
 	Label string `json:"label"`
 	Percent int `json:"percent"`
+	JobID int64 `json:"job_id,omitempty"`
+	State string `json:"state,omitempty"`
 }
 func (ussn *UserSession) WSSendProgressStartMessage(source, label string) (err error) {
-	return ussn.WSSendMessage("progress",  ProgressMessage{ source, label, 0 })
+	return ussn.WSSendMessage("progress",  ProgressMessage{ Source: source, Label: label, Percent: 0 })
 }
 func (ussn *UserSession) WSSendProgressCompleteMessage(source, label string) (err error) {
-	return ussn.WSSendMessage("progress",  ProgressMessage{ source, label, 100 })
+	return ussn.WSSendMessage("progress",  ProgressMessage{ Source: source, Label: label, Percent: 100 })
 }
 func (ussn *UserSession) WSSendProgressMessage(source, label string, curr, end float32 ) (err error) {
 	percent := int((float32(curr)/float32(end))*float32(100)) 
 	// log.Info(fmt.Sprintf("WSSendProgressMessage( ) -> %s : %d : ", label, percent), source)
-	return ussn.WSSendMessage("progress",  ProgressMessage{ source, label, percent })
+	return ussn.WSSendMessage("progress",  ProgressMessage{ Source: source, Label: label, Percent: percent })
 }
 
 
@@ -471,14 +505,17 @@ func (ussn *UserSession) WSSendMessage(typ string, data interface{}) (err error)
 		return
 	}
 
-	if ussn.Connected {
-		if ussn.RWMChan == nil {
-			ussn.RWMChan = &sync.RWMutex{}
-		}
+	/* SSE CLIENTS GET THE SAME MESSAGE; "live" IS GENERATED PER STREAM */
+	if typ != "live" {
+		SSEPublish(ussn.SID.String(), typ, string(js))
+	}
+
+	if ussn.Connected && ussn.RWMChan != nil {
 	
+		/* RE-READ UNDER LOCK; WSConnect CLEARS DataOut UNDER THE SAME LOCK WHEN IT CLOSES */
 		ussn.RWMChan.Lock()
-		if ussn.DataOut != nil {
-			ussn.DataOut <- string(string(js))
+		if cur, cur_err := UserSessionsMapRead(ussn.SID.String()); cur_err == nil && cur.Connected && cur.DataOut != nil {
+			cur.DataOut <- string(js)
 		}
 		ussn.RWMChan.Unlock()
 	}
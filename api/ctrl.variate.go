@@ -0,0 +1,184 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"jaQC-Go-API/utils"
+)
+
+/* DEFAULT AND LARGEST max_points FOR GET /api/variates/:id/series */
+const SERIES_MAX_POINTS int = 2000
+const SERIES_MAX_POINTS_LIMIT int = 100000
+
+func (vinp *VariateInput) Validate() (err error) {
+	vinp.Channel = strings.TrimSpace(vinp.Channel)
+	if vinp.Channel == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "variate channel is required")
+	}
+	if vinp.ExpectMax < vinp.ExpectMin {
+		return fiber.NewError(fiber.StatusBadRequest, "variate expect_max is below expect_min")
+	}
+	if vinp.SampleRate < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "variate sample_rate is negative")
+	}
+	if _, err = utils.ParseQualityMask(vinp.Exclude); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err = vinp.Filters.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err = vinp.Spec.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if vinp.Name == "" {
+		vinp.Name = vinp.Channel
+	}
+	return
+}
+
+/* KEEPS THE VARIATES NAMED IN vids ( COMMA SEPARATED IDS ), IN THAT ORDER; EMPTY KEEPS THEM ALL */
+func SelectVariates(vrts []Variate, vids string, pid int64) (sel []Variate, err error) {
+
+	if vids == "" {
+		return vrts, nil
+	}
+
+	byID := make(map[int64]Variate)
+	for _, vrt := range vrts {
+		byID[vrt.ID] = vrt
+	}
+	for _, str := range strings.Split(vids, ",") {
+		id, id_err := strconv.ParseInt(strings.TrimSpace(str), 10, 64)
+		vrt, ok := byID[id]
+		if id_err != nil || !ok {
+			return nil, fmt.Errorf("variate %s is not part of process %d", str, pid)
+		}
+		sel = append(sel, vrt)
+	}
+	return
+}
+
+/* PARSES :id AND RETURNS THE VARIATE */
+func paramVariate(c *fiber.Ctx) (vrt Variate, err error) {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		err = fiber.NewError(fiber.StatusBadRequest, "invalid variate id")
+		return
+	}
+	if vrt, err = GetVariateByID(int64(id)); err != nil {
+		err = fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+	return
+}
+
+func HandleCreateVariate(c *fiber.Ctx) (err error) {
+
+	proc, err := paramProcess(c)
+	if err != nil {
+		return
+	}
+
+	vinp := VariateInput{}
+	if err = utils.ParseRequestBody(c, &vinp); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err = vinp.Validate(); err != nil {
+		return
+	}
+
+	vrt := Variate{
+		PID:        proc.ID,
+		Channel:    vinp.Channel,
+		Name:       vinp.Name,
+		Unit:       vinp.Unit,
+		ExpectMin:  vinp.ExpectMin,
+		ExpectMax:  vinp.ExpectMax,
+		SampleRate: vinp.SampleRate,
+		Cluster:    vinp.Cluster,
+		SPC:        vinp.SPC,
+		Exclude:    vinp.Exclude,
+		Filters:    vinp.Filters,
+		Spec:       vinp.Spec,
+	}
+	if vinp.Cluster != (utils.ClusterConfig{}) {
+		if _, err = vrt.ClusterConfig(); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+	}
+	if _, err = vrt.SPCConfig(); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err = vrt.Create(LocalsUserID(c)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"variate": vrt})
+}
+
+func HandleGetVariate(c *fiber.Ctx) (err error) {
+	vrt, err := paramVariate(c)
+	if err != nil {
+		return
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"variate": vrt})
+}
+
+/* CALIBRATED SAMPLES, DOWNSAMPLED FOR CHARTING; THE WINDOW DEFAULTS TO THE PROCESS'S OWN */
+func HandleGetVariateSeries(c *fiber.Ctx) (err error) {
+
+	vrt, err := paramVariate(c)
+	if err != nil {
+		return
+	}
+
+	sq := SeriesQuery{}
+	if err = c.QueryParser(&sq); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err = utils.ValidateDownsampleMethod(sq.Method); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	switch {
+	case sq.MaxPoints == 0:
+		sq.MaxPoints = SERIES_MAX_POINTS
+	case sq.MaxPoints < 3 || sq.MaxPoints > SERIES_MAX_POINTS_LIMIT:
+		return c.Status(fiber.StatusBadRequest).SendString(fmt.Sprintf("max_points must be 3 to %d", SERIES_MAX_POINTS_LIMIT))
+	}
+
+	proc, err := GetProcessByID(vrt.PID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	start, end, ok := proc.Window(sq.Start, sq.End)
+	ts := utils.TSXY{}
+	if ok {
+		if ts, err = vrt.GetTSXY(start, end); err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+		}
+	}
+	if sq.Filtered {
+		ts = vrt.Filters.Apply(ts)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"series":  ts.TSDSampled(0, sq.Method, sq.MaxPoints),
+		"samples": len(ts.X),
+	})
+}
+
+func HandleGetVariateAggregateList(c *fiber.Ctx) (err error) {
+	vrt, err := paramVariate(c)
+	if err != nil {
+		return
+	}
+
+	aggs, err := GetAggregateListByVariate(vrt.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"aggregates": aggs})
+}
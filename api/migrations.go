@@ -0,0 +1,489 @@
+package api
+
+import (
+	"gorm.io/gorm"
+
+	"jaQC-Go-API/utils"
+)
+
+/* 
+MAIN DATABASE MIGRATIONS, OLDEST FIRST; APPEND ONLY
+NEVER EDIT A MIGRATION THAT HAS SHIPPED; ADD A NEW ONE
+*/
+var MAIN_DB_MIGRATIONS = []utils.Migration{
+	{
+		Version: 1,
+		Name:    "baseline",
+		Up:      m0001Up,
+		Down:    m0001Down,
+	},
+	{
+		Version: 2,
+		Name:    "variate cluster config",
+		Up:      m0002Up,
+		Down:    m0002Down,
+	},
+	{
+		Version: 3,
+		Name:    "spc",
+		Up:      m0003Up,
+		Down:    m0003Down,
+	},
+	{
+		Version: 4,
+		Name:    "variate quality exclude",
+		Up:      m0004Up,
+		Down:    m0004Down,
+	},
+	{
+		Version: 5,
+		Name:    "capability",
+		Up:      m0005Up,
+		Down:    m0005Down,
+	},
+	{
+		Version: 6,
+		Name:    "variate filters",
+		Up:      m0006Up,
+		Down:    m0006Down,
+	},
+	{
+		Version: 7,
+		Name:    "score rule sets",
+		Up:      m0007Up,
+		Down:    m0007Down,
+	},
+}
+
+/* DATASET DATABASE MIGRATIONS; RUN WHENEVER A DATASET DATABASE IS OPENED */
+var DATASET_DB_MIGRATIONS = []utils.Migration{
+	{
+		Version: 1,
+		Name:    "samples",
+		Up:      d0001Up,
+		Down:    d0001Down,
+	},
+	{
+		Version: 2,
+		Name:    "sample quality",
+		Up:      d0002Up,
+		Down:    d0002Down,
+	},
+}
+
+/* 0001 BASELINE ***********************************************************************
+SNAPSHOT OF THE SCHEMA WHEN MIGRATIONS WERE INTRODUCED
+AutoMigrate HERE ADOPTS DATABASES CREATED BEFORE schema_migrations EXISTED
+*/
+type m0001Meta struct {
+	ID        int64 `gorm:"autoIncrement"`
+	CreatedAt int64 `gorm:"autoCreateTime:milli"`
+	CreatedBy int64
+	UpdatedAt int64 `gorm:"autoUpdateTime:milli"`
+	UpdatedBy int64
+	DeletedAt int64
+}
+
+type m0001User struct {
+	Meta m0001Meta `gorm:"embedded"`
+	Password  string `gorm:"type:varchar(100);not null"`
+	Name      string `gorm:"type:varchar(100);not null"`
+	Email     string `gorm:"type:varchar(100);uniqueIndex;not null"`
+	Role      string
+	Org       string `gorm:"type:varchar(100);index"`
+}
+func (m0001User) TableName() string { return "users" }
+
+type m0001Gizmo struct {
+	Meta m0001Meta `gorm:"embedded"`
+	Serial    string `gorm:"type:varchar(100);uniqueIndex;not null"`
+	HWClass   string `gorm:"column:hw_class;type:varchar(100)"`
+	HWVersion string `gorm:"column:hw_version;type:varchar(100)"`
+	FWVersion string `gorm:"column:fw_version;type:varchar(100)"`
+	Name      string `gorm:"type:varchar(100)"`
+	Location  string `gorm:"type:varchar(100)"`
+	Owner     int64  `gorm:"index"`
+	Status    string
+}
+func (m0001Gizmo) TableName() string { return "gizmos" }
+
+type m0001Calibration struct {
+	Meta m0001Meta `gorm:"embedded"`
+	GID           int64  `gorm:"column:gid; not null; index"`
+	Channel       string `gorm:"type:varchar(100);not null; index"`
+	Unit          string `gorm:"type:varchar(50)"`
+	Method        string `gorm:"not null"`
+	Coefficients  string
+	TableX        string `gorm:"column:table_x"`
+	TableY        string `gorm:"column:table_y"`
+	EffectiveFrom int64  `gorm:"not null; index"`
+	Certificate   string `gorm:"type:varchar(100)"`
+	PerformedBy   string `gorm:"type:varchar(100)"`
+	Supersedes    int64
+	SupersededBy  int64
+}
+func (m0001Calibration) TableName() string { return "calibrations" }
+
+type m0001Dataset struct {
+	Meta m0001Meta `gorm:"embedded"`
+	GID         int64  `gorm:"column:gid; not null; index"`
+	Name        string `gorm:"type:varchar(100);not null"`
+	Description string
+	File        string `gorm:"type:varchar(100);uniqueIndex"`
+	Rows        int64
+	Bytes       int64
+	FirstX      int64 `gorm:"column:first_x"`
+	LastX       int64 `gorm:"column:last_x"`
+}
+func (m0001Dataset) TableName() string { return "datasets" }
+
+type m0001Process struct {
+	Meta m0001Meta `gorm:"embedded"`
+	GID       int64  `gorm:"column:gid; not null; index"`
+	DID       int64  `gorm:"column:did; not null; index"`
+	Name      string `gorm:"type:varchar(100);not null"`
+	Start     int64
+	End       int64
+	Recipe    string
+	Notes     string
+
+	Gizmo   *m0001Gizmo   `gorm:"foreignKey:GID; constraint:OnDelete:RESTRICT"`
+	Dataset *m0001Dataset `gorm:"foreignKey:DID; constraint:OnDelete:RESTRICT"`
+}
+func (m0001Process) TableName() string { return "processes" }
+
+type m0001Variate struct {
+	Meta m0001Meta `gorm:"embedded"`
+	PID        int64  `gorm:"column:pid; not null; index"`
+	Channel    string `gorm:"type:varchar(100);not null"`
+	Name       string `gorm:"type:varchar(100);not null"`
+	Unit       string `gorm:"type:varchar(50)"`
+	ExpectMin  float32
+	ExpectMax  float32
+	SampleRate float32
+
+	Process *m0001Process `gorm:"foreignKey:PID; constraint:OnDelete:CASCADE"`
+}
+func (m0001Variate) TableName() string { return "variates" }
+
+type m0001Aggregate struct {
+	Meta m0001Meta `gorm:"embedded"`
+	PID       int64 `gorm:"column:pid; not null"`
+	VID       int64 `gorm:"column:vid; not null"`
+	Code      int64
+	ADate     int64 `gorm:"a_date"`
+	Start     int64
+	End       int64
+	Size      int64
+	Min       float32
+	Max       float32
+	Mean      float32
+	Slope     float32
+	Devi      float32
+	Score     float32
+	Valid     bool
+
+	Process *m0001Process `gorm:"foreignKey:PID; constraint:OnDelete:CASCADE"`
+	Variate *m0001Variate `gorm:"foreignKey:VID; constraint:OnDelete:CASCADE"`
+}
+func (m0001Aggregate) TableName() string { return "aggregates" }
+
+type m0001Correlate struct {
+	Meta m0001Meta `gorm:"embedded"`
+	PID         int64  `gorm:"column:pid; not null; index"`
+	VIDA        int64  `gorm:"column:vid_a; not null"`
+	VIDB        int64  `gorm:"column:vid_b; not null"`
+	Method      string `gorm:"not null"`
+	Lag         int64
+	Coefficient float64
+	PValue      float64 `gorm:"column:p_value"`
+	N           int64
+	Start       int64
+	End         int64
+	Step        int64
+
+	Process  *m0001Process `gorm:"foreignKey:PID; constraint:OnDelete:CASCADE"`
+	VariateA *m0001Variate `gorm:"foreignKey:VIDA; constraint:OnDelete:CASCADE"`
+	VariateB *m0001Variate `gorm:"foreignKey:VIDB; constraint:OnDelete:CASCADE"`
+}
+func (m0001Correlate) TableName() string { return "correlates" }
+
+type m0001Job struct {
+	Meta m0001Meta `gorm:"embedded"`
+	Type      string `gorm:"not null"`
+	Label     string
+	Owner     int64  `gorm:"index; not null"`
+	State     string `gorm:"index; not null"`
+	Percent   int
+	Error     string
+	ResultRef string `gorm:"column:result_ref"`
+	StartedAt int64
+	EndedAt   int64
+}
+func (m0001Job) TableName() string { return "jobs" }
+
+type m0001Notification struct {
+	Meta m0001Meta `gorm:"embedded"`
+	Title      string `gorm:"type:varchar(200);not null"`
+	Body       string
+	Level      string
+	TargetRole string `gorm:"column:target_role"`
+	TargetOrg  string `gorm:"column:target_org"`
+	TargetUser int64  `gorm:"column:target_user"`
+}
+func (m0001Notification) TableName() string { return "notifications" }
+
+type m0001NotificationRecipient struct {
+	Meta m0001Meta `gorm:"embedded"`
+	NID         int64 `gorm:"column:nid; not null; index"`
+	UID         int64 `gorm:"column:uid; not null; index"`
+	ReadAt      int64
+	DismissedAt int64
+}
+func (m0001NotificationRecipient) TableName() string { return "notification_recipients" }
+
+/* PARENTS BEFORE CHILDREN */
+var m0001Tables = []interface{}{
+	m0001User{},
+	m0001Gizmo{},
+	m0001Calibration{},
+	m0001Dataset{},
+	m0001Process{},
+	m0001Variate{},
+	m0001Aggregate{},
+	m0001Correlate{},
+	m0001Job{},
+	m0001Notification{},
+	m0001NotificationRecipient{},
+}
+
+func m0001Up(tx *gorm.DB) error {
+	return tx.Migrator().AutoMigrate(m0001Tables...)
+}
+
+func m0001Down(tx *gorm.DB) (err error) {
+	for i := len(m0001Tables) - 1; i >= 0; i-- {
+		if err = tx.Migrator().DropTable(m0001Tables[i]); err != nil {
+			return
+		}
+	}
+	return
+}
+/* END 0001 BASELINE *******************************************************************/
+
+/* 0002 VARIATE CLUSTER CONFIG *********************************************************/
+type m0002Variate struct {
+	Cluster string `gorm:"column:cluster"` // JSON utils.ClusterConfig
+}
+func (m0002Variate) TableName() string { return "variates" }
+
+func m0002Up(tx *gorm.DB) error {
+	return tx.Migrator().AddColumn(&m0002Variate{}, "Cluster")
+}
+
+func m0002Down(tx *gorm.DB) error {
+	return tx.Migrator().DropColumn(&m0002Variate{}, "Cluster")
+}
+/* END 0002 VARIATE CLUSTER CONFIG *****************************************************/
+
+/* 0003 SPC ****************************************************************************/
+type m0003Variate struct {
+	SPC string `gorm:"column:spc"` // JSON utils.SPCConfig
+}
+func (m0003Variate) TableName() string { return "variates" }
+
+type m0003Aggregate struct {
+	Rule string `gorm:"column:rule; type:varchar(100)"`
+}
+func (m0003Aggregate) TableName() string { return "aggregates" }
+
+func m0003Up(tx *gorm.DB) (err error) {
+	if err = tx.Migrator().AddColumn(&m0003Variate{}, "SPC"); err != nil {
+		return
+	}
+	return tx.Migrator().AddColumn(&m0003Aggregate{}, "Rule")
+}
+
+func m0003Down(tx *gorm.DB) (err error) {
+	if err = tx.Migrator().DropColumn(&m0003Aggregate{}, "Rule"); err != nil {
+		return
+	}
+	return tx.Migrator().DropColumn(&m0003Variate{}, "SPC")
+}
+/* END 0003 SPC ************************************************************************/
+
+/* 0004 VARIATE QUALITY EXCLUDE ********************************************************/
+type m0004Variate struct {
+	Exclude string `gorm:"column:exclude; type:varchar(100)"`
+}
+func (m0004Variate) TableName() string { return "variates" }
+
+func m0004Up(tx *gorm.DB) error {
+	return tx.Migrator().AddColumn(&m0004Variate{}, "Exclude")
+}
+
+func m0004Down(tx *gorm.DB) error {
+	return tx.Migrator().DropColumn(&m0004Variate{}, "Exclude")
+}
+/* END 0004 VARIATE QUALITY EXCLUDE ****************************************************/
+
+/* 0005 CAPABILITY *********************************************************************/
+type m0005Variate struct {
+	LSL    *float64 `gorm:"column:lsl"`
+	USL    *float64 `gorm:"column:usl"`
+	Target *float64 `gorm:"column:target"`
+}
+func (m0005Variate) TableName() string { return "variates" }
+
+type m0005Capability struct {
+	Meta m0001Meta `gorm:"embedded"`
+	PID        int64 `gorm:"column:pid; not null; index"`
+	VID        int64 `gorm:"column:vid; not null; index"`
+	Start      int64
+	End        int64
+	Subgroup   int
+	Confidence float64
+
+	LSL    *float64 `gorm:"column:lsl"`
+	USL    *float64 `gorm:"column:usl"`
+	Target *float64 `gorm:"column:target"`
+
+	N            int64
+	Mean         float64
+	SigmaWithin  float64
+	SigmaOverall float64
+	Cp           *float64 `gorm:"column:cp"`
+	CpLo         *float64 `gorm:"column:cp_lo"`
+	CpHi         *float64 `gorm:"column:cp_hi"`
+	Cpk          *float64 `gorm:"column:cpk"`
+	CpkLo        *float64 `gorm:"column:cpk_lo"`
+	CpkHi        *float64 `gorm:"column:cpk_hi"`
+	Pp           *float64 `gorm:"column:pp"`
+	PpLo         *float64 `gorm:"column:pp_lo"`
+	PpHi         *float64 `gorm:"column:pp_hi"`
+	Ppk          *float64 `gorm:"column:ppk"`
+	PpkLo        *float64 `gorm:"column:ppk_lo"`
+	PpkHi        *float64 `gorm:"column:ppk_hi"`
+	Cpm          *float64 `gorm:"column:cpm"`
+	CpmLo        *float64 `gorm:"column:cpm_lo"`
+	CpmHi        *float64 `gorm:"column:cpm_hi"`
+	AD           float64  `gorm:"column:ad"`
+	ADPValue     float64  `gorm:"column:ad_p_value"`
+	Normal       bool
+
+	Process *m0001Process `gorm:"foreignKey:PID; constraint:OnDelete:CASCADE"`
+	Variate *m0001Variate `gorm:"foreignKey:VID; constraint:OnDelete:CASCADE"`
+}
+func (m0005Capability) TableName() string { return "capabilities" }
+
+func m0005Up(tx *gorm.DB) (err error) {
+	for _, col := range []string{"LSL", "USL", "Target"} {
+		if err = tx.Migrator().AddColumn(&m0005Variate{}, col); err != nil {
+			return
+		}
+	}
+	return tx.Migrator().AutoMigrate(m0005Capability{})
+}
+
+func m0005Down(tx *gorm.DB) (err error) {
+	if err = tx.Migrator().DropTable(m0005Capability{}); err != nil {
+		return
+	}
+	for _, col := range []string{"Target", "USL", "LSL"} {
+		if err = tx.Migrator().DropColumn(&m0005Variate{}, col); err != nil {
+			return
+		}
+	}
+	return
+}
+/* END 0005 CAPABILITY *****************************************************************/
+
+/* 0006 VARIATE FILTERS ****************************************************************/
+type m0006Variate struct {
+	Filters string `gorm:"column:filters"` // JSON utils.FilterChain
+}
+func (m0006Variate) TableName() string { return "variates" }
+
+func m0006Up(tx *gorm.DB) error {
+	return tx.Migrator().AddColumn(&m0006Variate{}, "Filters")
+}
+
+func m0006Down(tx *gorm.DB) error {
+	return tx.Migrator().DropColumn(&m0006Variate{}, "Filters")
+}
+/* END 0006 VARIATE FILTERS ************************************************************/
+
+/* 0007 SCORE RULE SETS ****************************************************************/
+type m0007Aggregate struct {
+	ScoreVersion int    `gorm:"column:score_version"`
+	Verdict      string `gorm:"type:varchar(10)"`
+}
+func (m0007Aggregate) TableName() string { return "aggregates" }
+
+type m0007ScoreRuleSet struct {
+	Meta m0001Meta `gorm:"embedded"`
+	VID     int64  `gorm:"column:vid; not null; uniqueIndex:idx_score_rule_sets_vid_version"`
+	Version int    `gorm:"not null; uniqueIndex:idx_score_rule_sets_vid_version"`
+	Note    string `gorm:"type:varchar(255)"`
+	Rules   string // JSON []utils.ScoreRule
+	Pass    float64
+	Warn    float64
+
+	Variate *m0001Variate `gorm:"foreignKey:VID; constraint:OnDelete:CASCADE"`
+}
+func (m0007ScoreRuleSet) TableName() string { return "score_rule_sets" }
+
+func m0007Up(tx *gorm.DB) (err error) {
+	for _, col := range []string{"ScoreVersion", "Verdict"} {
+		if err = tx.Migrator().AddColumn(&m0007Aggregate{}, col); err != nil {
+			return
+		}
+	}
+	return tx.Migrator().AutoMigrate(m0007ScoreRuleSet{})
+}
+
+func m0007Down(tx *gorm.DB) (err error) {
+	if err = tx.Migrator().DropTable(m0007ScoreRuleSet{}); err != nil {
+		return
+	}
+	for _, col := range []string{"Verdict", "ScoreVersion"} {
+		if err = tx.Migrator().DropColumn(&m0007Aggregate{}, col); err != nil {
+			return
+		}
+	}
+	return
+}
+/* END 0007 SCORE RULE SETS ************************************************************/
+
+/* DATASET 0001 SAMPLES ****************************************************************/
+type d0001Sample struct {
+	ID      int64   `gorm:"autoIncrement"`
+	Channel string  `gorm:"type:varchar(100);not null; index:idx_samples_channel_x,priority:1"`
+	X       int64   `gorm:"not null; index:idx_samples_channel_x,priority:2"`
+	Y       float32
+}
+func (d0001Sample) TableName() string { return "samples" }
+
+func d0001Up(tx *gorm.DB) error {
+	return tx.Migrator().AutoMigrate(d0001Sample{})
+}
+
+func d0001Down(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(d0001Sample{})
+}
+/* END DATASET 0001 SAMPLES ************************************************************/
+
+/* DATASET 0002 SAMPLE QUALITY *********************************************************/
+type d0002Sample struct {
+	Quality uint8 `gorm:"column:quality; not null; default:0"` // utils.QUALITY_*
+}
+func (d0002Sample) TableName() string { return "samples" }
+
+func d0002Up(tx *gorm.DB) error {
+	return tx.Migrator().AddColumn(&d0002Sample{}, "Quality")
+}
+
+func d0002Down(tx *gorm.DB) error {
+	return tx.Migrator().DropColumn(&d0002Sample{}, "Quality")
+}
+/* END DATASET 0002 SAMPLE QUALITY *****************************************************/
@@ -23,6 +23,50 @@ type Aggregate struct {
 	Devi  float32 `json:"devi"`
 	Score float32 `json:"score"`
 
-	Valid bool `json:"valid"`
+	ScoreVersion int    `gorm:"column:score_version" json:"score_version"` // ScoreRuleSet.Version; 0 IS THE CLUSTER LIMITS SCORE
+	Verdict      string `gorm:"type:varchar(10)" json:"verdict"`          // pass | warn | fail; EMPTY WITHOUT A RULE SET
+
+	Valid bool   `json:"valid"`
+	Rule  string `gorm:"type:varchar(100)" json:"rule"` // SPC RULES THAT FIRED, COMMA SEPARATED; EMPTY WHILE Valid
+
+	Process *Process `gorm:"foreignKey:PID; constraint:OnDelete:CASCADE" json:"-"`
+	Variate *Variate `gorm:"foreignKey:VID; constraint:OnDelete:CASCADE" json:"-"`
+}
+func (Aggregate) TableName() string { return "aggregates" }
+/* FILTERS FOR GET /api/aggregates; ZERO VALUES ARE IGNORED */
+type AggregateQuery struct {
+	PID      int64    `query:"pid"`
+	VID      int64    `query:"vid"`
+	Code     *int64   `query:"code"`
+	Valid    *bool    `query:"valid"`
+	ScoreMin *float32 `query:"score_min"`
+	ScoreMax *float32 `query:"score_max"`
+	Verdict  string   `query:"verdict"`
+	Start    int64    `query:"start"` // Time:milli; AGGREGATES ENDING AT OR AFTER start
+	End      int64    `query:"end"`   // Time:milli; AGGREGATES STARTING BEFORE end
+	Sort     string   `query:"sort"`  // COLUMN, PREFIX WITH "-" FOR DESCENDING
+	Cursor   string   `query:"cursor"`
+	Limit    int      `query:"limit"`
+	Summary  bool     `query:"summary"`
+	Bins     int      `query:"bins"` // SUMMARY HISTOGRAM BINS
+}
+
+/* KEYSET POSITION; THE LAST ROW OF THE PREVIOUS PAGE */
+type AggregateCursor struct {
+	Value float64 `json:"v"`
+	ID    int64   `json:"id"`
+}
+
+type AggregateSummary struct {
+	VID        int64     `json:"vid"`
+	Count      int64     `json:"count"`
+	ValidCount int64     `json:"valid_count"`
+	ScoreMin   float32   `json:"score_min"`
+	ScoreMax   float32   `json:"score_max"`
+	ScoreMean  float32   `json:"score_mean"`
+	ScoreP50   float32   `json:"score_p50"`
+	Histogram  []int64   `json:"histogram"`  // COUNTS PER BIN
+	BinEdges   []float32 `json:"bin_edges"` // len(Histogram) + 1
+
+	Capability *Capability `json:"capability"` // THE VARIATE'S LATEST, null IF NEVER COMPUTED
 }
-func (Aggregate) TableName() string { return "aggregates" }
\ No newline at end of file
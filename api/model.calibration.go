@@ -0,0 +1,47 @@
+package api
+
+import (
+	"jaQC-Go-API/utils"
+)
+
+const CAL_METHOD_POLY string = "polynomial"
+const CAL_METHOD_TABLE string = "table"
+
+/* CONVERTS RAW READINGS ON ONE GIZMO CHANNEL TO ENGINEERING UNITS FROM EffectiveFrom ONWARD */
+type Calibration struct {
+	utils.Meta    `gorm:"embedded"`
+	GID           int64     `gorm:"column:gid; not null; index" json:"gid"` // GIZMO ID
+	Channel       string    `gorm:"type:varchar(100);not null; index" json:"channel"`
+	Unit          string    `gorm:"type:varchar(50)" json:"unit"`
+	Method        string    `gorm:"not null" json:"method"`
+	Coefficients  []float64 `gorm:"serializer:json" json:"coefficients"` // c0 + c1*x + c2*x^2 ...
+	TableX        []float64 `gorm:"column:table_x; serializer:json" json:"table_x"`
+	TableY        []float64 `gorm:"column:table_y; serializer:json" json:"table_y"`
+	EffectiveFrom int64     `gorm:"not null; index" json:"effective_from"` // Time:milli
+	Certificate   string    `gorm:"type:varchar(100)" json:"certificate"`
+	PerformedBy   string    `gorm:"type:varchar(100)" json:"performed_by"`
+	Supersedes    int64     `json:"supersedes"`    // CalibrationID THIS CORRECTS
+	SupersededBy  int64     `json:"superseded_by"` // CalibrationID THAT CORRECTS THIS
+}
+func (Calibration) TableName() string { return "calibrations" }
+
+/* CONVERTS ONE RAW READING */
+func (cal *Calibration) Apply(raw float64) float64 {
+	if cal.Method == CAL_METHOD_TABLE {
+		return utils.InterpolateTable(cal.TableX, cal.TableY, raw)
+	}
+	return utils.Polynomial(cal.Coefficients, raw)
+}
+
+/* TRANSPORT OBJECT */
+type CalibrationInput struct {
+	Channel       string    `json:"channel" validate:"required"`
+	Unit          string    `json:"unit"`
+	Method        string    `json:"method" validate:"required"`
+	Coefficients  []float64 `json:"coefficients"`
+	TableX        []float64 `json:"table_x"`
+	TableY        []float64 `json:"table_y"`
+	EffectiveFrom int64     `json:"effective_from" validate:"required"`
+	Certificate   string    `json:"certificate"`
+	PerformedBy   string    `json:"performed_by"`
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"jaQC-Go-API/utils"
+)
+
+/* PROCESS CAPABILITY OF A VARIATE OVER [ Start, End ), WITH THE SPEC IT WAS JUDGED AGAINST */
+type Capability struct {
+	utils.Meta `gorm:"embedded"`
+	PID        int64   `gorm:"column:pid; not null; index" json:"pid"` // PROCESS ID
+	VID        int64   `gorm:"column:vid; not null; index" json:"vid"` // VARIATE ID
+	Start      int64   `json:"start"`
+	End        int64   `json:"end"`
+	Subgroup   int     `json:"subgroup"`
+	Confidence float64 `json:"confidence"`
+
+	utils.Spec       `gorm:"embedded"`
+	utils.Capability `gorm:"embedded"`
+
+	Process *Process `gorm:"foreignKey:PID; constraint:OnDelete:CASCADE" json:"-"`
+	Variate *Variate `gorm:"foreignKey:VID; constraint:OnDelete:CASCADE" json:"-"`
+}
+func (Capability) TableName() string { return "capabilities" }
+
+/* TRANSPORT OBJECT; THE WINDOW DEFAULTS TO THE PROCESS'S OWN */
+type CapabilityInput struct {
+	Start      int64   `json:"start"`
+	End        int64   `json:"end"`
+	Subgroup   int     `json:"subgroup"`   // 0 IS utils.CAPABILITY_SUBGROUP; 1 USES MOVING RANGES
+	Confidence float64 `json:"confidence"` // 0 IS utils.CAPABILITY_CONFIDENCE
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"sync"
+
+	"jaQC-Go-API/utils"
+)
+
+/* DEFAULTS FOR ZERO ClusterConfig FIELDS */
+const CLUSTER_MIN_SIZE int = 10
+const CLUSTER_MAX_SIZE int = 100000        // BOUNDS THE SAMPLES HELD FOR AN OPEN CLUSTER
+const CLUSTER_DEVI_FRACTION float32 = 0.02 // OF THE VARIATE'S EXPECTED RANGE
+const CLUSTER_GAP_PERIODS float32 = 10     // SAMPLE PERIODS OF SILENCE THAT CLOSE A CLUSTER
+
+/* SEGMENTS ONE VARIATE AS ITS SAMPLES ARRIVE; EACH CLOSED CLUSTER BECOMES AN Aggregate */
+type ClusterStream struct {
+	Variate
+	Proc  Process
+	Owner int64 // UserID RECORDED ON THE AGGREGATES
+	Count int64 // AGGREGATES WRITTEN
+
+	cfg utils.ClusterConfig
+	clr *utils.Clusterer
+	err error // FIRST WRITE ERROR
+	mut *sync.Mutex
+	flt utils.Filter // THE VARIATE'S FILTER CHAIN; SAMPLES REACH THE Clusterer THROUGH IT
+	rs  *ScoreRuleSet // THE VARIATE'S LATEST; nil SCORES AGAINST cfg
+
+	spc      *utils.SPCMonitor // nil UNLESS THE VARIATE HAS AN SPC BASELINE
+	spcBuf   utils.TSXY        // OPEN SUBGROUP; samples SOURCE ONLY
+	spcFlags []utils.SPCPoint  // FLAGGED SUBGROUPS NOT YET MATCHED TO AN AGGREGATE
+}
+
+/* OPEN LIVE STREAMS, BY VARIATE ID */
+type ClusterStreamMap map[int64]*ClusterStream
+
+var ClusterStreams = make(ClusterStreamMap)
+var ClusterStreamsRWMutex = sync.RWMutex{}
+
+/* GET /api/variates/:id/changepoints; ZERO VALUES TAKE THE utils DEFAULTS */
+type ChangePointQuery struct {
+	Method    string  `query:"method"` // pelt ( DEFAULT ) | cusum
+	Start     int64   `query:"start"`
+	End       int64   `query:"end"`
+	MinSize   int     `query:"min_size"`
+	Threshold float64 `query:"threshold"`
+	Drift     float64 `query:"drift"`
+	Penalty   float64 `query:"penalty"`
+}
+
+/* TRANSPORT OBJECT; RECLUSTER [ start, end ), DEFAULTING TO THE PROCESS WINDOW */
+type ClusterInput struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
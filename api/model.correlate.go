@@ -0,0 +1,50 @@
+package api
+
+import (
+	"jaQC-Go-API/utils"
+)
+
+const CORR_METHOD_PEARSON string = "pearson"
+const CORR_METHOD_SPEARMAN string = "spearman"
+const CORR_METHOD_XCORR string = "xcorr" // PEARSON AT THE LAG WITH THE LARGEST |r|
+
+/* CORRELATION BETWEEN TWO VARIATES OF A PROCESS OVER [ Start, End ) */
+type Correlate struct {
+	utils.Meta  `gorm:"embedded"`
+	PID         int64   `gorm:"column:pid; not null; index" json:"pid"`     // PROCESS ID
+	VIDA        int64   `gorm:"column:vid_a; not null" json:"vid_a"`        // VARIATE ID
+	VIDB        int64   `gorm:"column:vid_b; not null" json:"vid_b"`        // VARIATE ID
+	Method      string  `gorm:"not null" json:"method"`
+	Lag         int64   `json:"lag"` // Time:milli; POSITIVE -> B FOLLOWS A
+	Coefficient float64 `json:"coefficient"`
+	PValue      float64 `gorm:"column:p_value" json:"p_value"`
+	N           int64   `json:"n"` // PAIRS USED
+	Start       int64   `json:"start"`
+	End         int64   `json:"end"`
+	Step        int64   `json:"step"` // Time:milli; ALIGNMENT GRID
+
+	Process  *Process `gorm:"foreignKey:PID; constraint:OnDelete:CASCADE" json:"-"`
+	VariateA *Variate `gorm:"foreignKey:VIDA; constraint:OnDelete:CASCADE" json:"-"`
+	VariateB *Variate `gorm:"foreignKey:VIDB; constraint:OnDelete:CASCADE" json:"-"`
+}
+func (Correlate) TableName() string { return "correlates" }
+
+/* TRANSPORT OBJECT */
+type CorrelationInput struct {
+	Start  int64  `json:"start"`   // Time:milli; DEFAULTS TO PROCESS START
+	End    int64  `json:"end"`     // Time:milli; DEFAULTS TO PROCESS END OR NOW
+	Step   int64  `json:"step"`    // Time:milli; DEFAULTS TO CORR_DEFAULT_STEP
+	MaxLag int64  `json:"max_lag"` // Time:milli; 0 -> NO CROSS CORRELATION
+	Interp string `json:"interp"`  // FILLS EMPTY STEPS; SEE utils.ResampleConfig
+	Agg    string `json:"agg"`     // COMBINES SAMPLES SHARING A STEP
+	MaxGap int64  `json:"max_gap"` // Time:milli; DEFAULTS TO CORR_GAP_STEPS STEPS
+}
+
+type CorrelationMatrix struct {
+	Method       string      `json:"method"`
+	VIDs         []int64     `json:"vids"`
+	Names        []string    `json:"names"`
+	Coefficients [][]float64 `json:"coefficients"`
+	PValues      [][]float64 `json:"p_values"`
+	Lags         [][]int64   `json:"lags"`
+}
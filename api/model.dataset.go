@@ -0,0 +1,59 @@
+package api
+
+import (
+	"sync"
+
+	"jaQC-Go-API/utils"
+)
+
+/* RAW SAMPLES LIVE IN THEIR OWN SQLITE FILE UNDER DATASET_DB_DIR */
+type Dataset struct {
+	utils.Meta  `gorm:"embedded"`
+	GID         int64  `gorm:"column:gid; not null; index" json:"gid"` // GIZMO ID
+	Name        string `gorm:"type:varchar(100);not null" json:"name"`
+	Description string `json:"description"`
+	File        string `gorm:"type:varchar(100);uniqueIndex" json:"file"` // NAME OF THE DB FILE IN DATASET_DB_DIR
+	Rows        int64  `json:"rows"`
+	Bytes       int64  `json:"bytes"`
+	FirstX      int64  `gorm:"column:first_x" json:"first_x"` // Time:milli
+	LastX       int64  `gorm:"column:last_x" json:"last_x"`   // Time:milli
+}
+func (Dataset) TableName() string { return "datasets" }
+
+/* ONE ROW PER RAW READING; Y IS IN RAW ( UNCALIBRATED ) UNITS */
+type Sample struct {
+	ID      int64   `gorm:"autoIncrement" json:"id"`
+	Channel string  `gorm:"type:varchar(100);not null; index:idx_samples_channel_x,priority:1" json:"channel"`
+	X       int64   `gorm:"not null; index:idx_samples_channel_x,priority:2" json:"x"` // Time:milli
+	Y       float32 `json:"y"`
+	Quality uint8   `gorm:"not null; default:0" json:"quality"` // utils.QUALITY_*
+}
+func (Sample) TableName() string { return "samples" }
+
+type DatasetDatabase struct{ utils.SQLiteClient }
+
+/* OPEN DATASET DATABASES, BY DATASET ID */
+type DatasetDatabaseMap map[int64]*DatasetDatabase
+var DatasetDatabases = make(DatasetDatabaseMap)
+var DatasetDatabasesRWMutex = sync.RWMutex{}
+
+/* TRANSPORT OBJECT */
+type DatasetInput struct {
+	Name        string `json:"name" validate:"required"`
+	Description string `json:"description"`
+}
+
+type DatasetChannelStats struct {
+	Channel string `json:"channel"`
+	Rows    int64  `json:"rows"`
+	FirstX  int64  `json:"first_x"`
+	LastX   int64  `json:"last_x"`
+}
+
+type DatasetStats struct {
+	Rows     int64                 `json:"rows"`
+	Bytes    int64                 `json:"bytes"`
+	FirstX   int64                 `json:"first_x"`
+	LastX    int64                 `json:"last_x"`
+	Channels []DatasetChannelStats `json:"channels"`
+}
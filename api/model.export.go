@@ -0,0 +1,33 @@
+package api
+
+const EXPORT_FMT_CSV string = "csv"
+const EXPORT_FMT_NDJSON string = "ndjson"
+
+/* FLUSH A CHUNK TO THE CLIENT EVERY N ROWS */
+const EXPORT_FLUSH_ROWS int64 = 1000
+
+/* COLUMNS AN EXPORT MAY SELECT, IN THEIR DEFAULT ORDER */
+var SAMPLE_EXPORT_COLS = []string{"time", "vid", "variate", "channel", "value", "unit", "raw", "quality"}
+var AGG_EXPORT_COLS = []string{
+	"id", "pid", "vid", "variate", "code", "start", "end", "size",
+	"min", "max", "mean", "slope", "devi", "score", "score_version", "verdict", "valid", "rule", "unit",
+}
+
+/* OUTPUT OPTIONS SHARED BY EVERY EXPORT */
+type ExportQuery struct {
+	Format     string `query:"format"`      // csv | ndjson
+	Columns    string `query:"columns"`     // COMMA SEPARATED; DEFAULT IS ALL
+	TimeZone   string `query:"tz"`          // IANA NAME; DEFAULT UTC
+	TimeFormat string `query:"time_format"` // SEE utils.FormatTimestamp; "excel" GIVES SERIAL DAYS
+	Units      string `query:"units"`       // "vid:unit,vid:unit"; CONVERTS FROM THE VARIATE'S UNIT
+	Delimiter  string `query:"delimiter"`   // CSV ONLY; DEFAULT ","
+	BOM        bool   `query:"bom"`         // CSV ONLY; LETS EXCEL DETECT UTF-8
+}
+
+/* WHICH SAMPLES OF A PROCESS TO EXPORT; THE WINDOW DEFAULTS TO THE PROCESS'S OWN */
+type SampleExportQuery struct {
+	VIDs    string `query:"vids"` // COMMA SEPARATED; DEFAULT IS EVERY VARIATE OF THE PROCESS
+	Start   int64  `query:"start"`
+	End     int64  `query:"end"`
+	Exclude string `query:"exclude"` // QUALITY FLAG NAMES, COMMA SEPARATED; DEFAULT KEEPS EVERY SAMPLE
+}
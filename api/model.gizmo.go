@@ -0,0 +1,36 @@
+package api
+
+import (
+	"jaQC-Go-API/utils"
+)
+
+const GIZMO_STATUS_ACTIVE string = "active"
+const GIZMO_STATUS_INACTIVE string = "inactive"
+const GIZMO_STATUS_SERVICE string = "service"
+const GIZMO_STATUS_RETIRED string = "retired"
+
+/* A DEVICE; DATASETS, CALIBRATIONS AND OTA UPDATES HANG OFF IT */
+type Gizmo struct {
+	utils.Meta `gorm:"embedded"`
+	Serial     string `gorm:"type:varchar(100);uniqueIndex;not null" json:"serial"`
+	HWClass    string `gorm:"column:hw_class;type:varchar(100)" json:"hw_class"`
+	HWVersion  string `gorm:"column:hw_version;type:varchar(100)" json:"hw_version"`
+	FWVersion  string `gorm:"column:fw_version;type:varchar(100)" json:"fw_version"`
+	Name       string `gorm:"type:varchar(100)" json:"name"`
+	Location   string `gorm:"type:varchar(100)" json:"location"`
+	Owner      int64  `gorm:"index" json:"owner"` // UserID
+	Status     string `json:"status"`
+}
+func (Gizmo) TableName() string { return "gizmos" }
+
+/* TRANSPORT OBJECT */
+type GizmoInput struct {
+	Serial    string `json:"serial" validate:"required"`
+	HWClass   string `json:"hw_class"`
+	HWVersion string `json:"hw_version"`
+	FWVersion string `json:"fw_version"`
+	Name      string `json:"name"`
+	Location  string `json:"location"`
+	Owner     int64  `json:"owner"`
+	Status    string `json:"status"`
+}
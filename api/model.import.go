@@ -0,0 +1,41 @@
+package api
+
+const IMPORT_FMT_CSV string = "csv"
+const IMPORT_FMT_NDJSON string = "ndjson"
+
+const IMPORT_RANGE_SKIP string = "skip" // DROP THE VALUE ( DEFAULT )
+const IMPORT_RANGE_KEEP string = "keep" // STORE IT ANYWAY, BUT COUNT IT
+const IMPORT_RANGE_FAIL string = "fail" // STOP THE IMPORT
+
+/* HOW MANY ROW ERRORS ARE KEPT ON THE RESULT */
+const IMPORT_ERROR_LINES int = 20
+
+/* MAPS ONE FILE COLUMN ( CSV HEADER OR NDJSON KEY ) TO A VARIATE */
+type ImportColumn struct {
+	Column string `json:"column"`
+	VID    int64  `json:"vid"`
+	Unit   string `json:"unit"` // UNIT OF THE FILE'S VALUES; CONVERTED TO THE VARIATE'S UNIT
+}
+
+/* TRANSPORT OBJECT; SENT AS THE "import" FORM FIELD ALONG WITH THE "file" */
+type ImportInput struct {
+	Format     string         `json:"format"`       // csv | ndjson; DEFAULTS FROM THE FILE EXTENSION
+	Delimiter  string         `json:"delimiter"`    // CSV ONLY; DEFAULT ","
+	TimeColumn string         `json:"time_column"`  // validate:"required"
+	TimeFormat string         `json:"time_format"`  // SEE utils.ParseTimestamp; DEFAULT auto
+	TimeZone   string         `json:"time_zone"`    // IANA NAME; APPLIED WHEN A TIMESTAMP HAS NO OFFSET
+	Columns    []ImportColumn `json:"columns"`      // validate:"required"
+	OutOfRange string         `json:"out_of_range"` // skip | keep | fail; CHECKED AGAINST Variate.ExpectMin / ExpectMax
+	MaxErrors  int            `json:"max_errors"`   // FAIL AFTER THIS MANY BAD ROWS; 0 NEVER FAILS
+}
+
+/* SENT AS AN "import" MESSAGE WHEN THE JOB ENDS */
+type ImportResult struct {
+	DID        int64    `json:"did"`
+	JobID      int64    `json:"job_id"`
+	Rows       int64    `json:"rows"`
+	Samples    int64    `json:"samples"`
+	Skipped    int64    `json:"skipped"`      // ROWS THAT COULD NOT BE PARSED
+	OutOfRange int64    `json:"out_of_range"` // VALUES OUTSIDE THE VARIATE'S EXPECTED RANGE
+	Errors     []string `json:"errors"`       // FIRST IMPORT_ERROR_LINES ROW ERRORS
+}
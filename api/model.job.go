@@ -0,0 +1,38 @@
+package api
+
+import (
+	"context"
+	"sync"
+
+	"jaQC-Go-API/utils"
+)
+
+const JOB_STATE_QUEUED string = "queued"
+const JOB_STATE_RUNNING string = "running"
+const JOB_STATE_COMPLETE string = "complete"
+const JOB_STATE_FAILED string = "failed"
+const JOB_STATE_CANCELLED string = "cancelled"
+
+type Job struct {
+	utils.Meta `gorm:"embedded"`
+	Type      string `gorm:"not null" json:"type"`
+	Label     string `json:"label"`
+	Owner     int64  `gorm:"index; not null" json:"owner"` // UserID
+	State     string `gorm:"index; not null" json:"state"`
+	Percent   int    `json:"percent"`
+	Error     string `json:"error"`
+	ResultRef string `gorm:"column:result_ref" json:"result_ref"` // e.g. "aggregates?pid=12"
+
+	StartedAt int64 `json:"started_at"` // Time:milli
+	EndedAt   int64 `json:"ended_at"`   // Time:milli
+
+	ctx context.Context `gorm:"-"`
+	mut *sync.Mutex    `gorm:"-"`
+}
+func (Job) TableName() string { return "jobs" }
+
+/* DONE WHEN THE JOB IS CANCELLED; CHECK IT BETWEEN UNITS OF WORK */
+func (job *Job) Context() context.Context { return job.ctx }
+
+/* THE WORK TO RUN; THE RETURNED REFERENCE IS STORED AS Job.ResultRef */
+type JobFunc func(job *Job) (resultRef string, err error)
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"jaQC-Go-API/utils"
+)
+
+const NOTE_LEVEL_INFO string = "info"
+const NOTE_LEVEL_WARN string = "warn"
+const NOTE_LEVEL_ALERT string = "alert"
+
+type Notification struct {
+	utils.Meta `gorm:"embedded"`
+	Title      string `gorm:"type:varchar(200);not null" json:"title"`
+	Body       string `json:"body"`
+	Level      string `json:"level"`
+	TargetRole string `gorm:"column:target_role" json:"target_role"` // BLANK -> ALL ROLES
+	TargetOrg  string `gorm:"column:target_org" json:"target_org"`   // BLANK -> ALL ORGS
+	TargetUser int64  `gorm:"column:target_user" json:"target_user"` // 0 -> ALL USERS
+}
+func (Notification) TableName() string { return "notifications" }
+
+/* ONE ROW PER USER PER NOTIFICATION; CREATED WHEN THE NOTIFICATION IS SENT */
+type NotificationRecipient struct {
+	utils.Meta  `gorm:"embedded"`
+	NID         int64 `gorm:"column:nid; not null; index" json:"nid"` // NOTIFICATION ID
+	UID         int64 `gorm:"column:uid; not null; index" json:"uid"` // USER ID
+	ReadAt      int64 `json:"read_at"`      // Time:milli
+	DismissedAt int64 `json:"dismissed_at"` // Time:milli
+}
+func (NotificationRecipient) TableName() string { return "notification_recipients" }
+
+/* TRANSPORT OBJECT */
+type NotificationInput struct {
+	Title      string `json:"title" validate:"required"`
+	Body       string `json:"body"`
+	Level      string `json:"level"`
+	TargetRole string `json:"target_role"`
+	TargetOrg  string `json:"target_org"`
+	TargetUser int64  `json:"target_user"`
+}
+
+type NotificationResponse struct {
+	ID        int64  `json:"id"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	Level     string `json:"level"`
+	CreatedAt int64  `json:"created_at"`
+	CreatedBy int64  `json:"created_by"`
+	ReadAt    int64  `json:"read_at"`
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"jaQC-Go-API/utils"
+)
+
+/* A RUN OR BATCH ON A GIZMO; ITS SAMPLES LIVE IN THE DATASET DID */
+type Process struct {
+	utils.Meta `gorm:"embedded"`
+	GID        int64             `gorm:"column:gid; not null; index" json:"gid"` // GIZMO ID
+	DID        int64             `gorm:"column:did; not null; index" json:"did"` // DATASET ID
+	Name       string            `gorm:"type:varchar(100);not null" json:"name"`
+	Start      int64             `json:"start"` // Time:milli
+	End        int64             `json:"end"`   // Time:milli; 0 WHILE RUNNING
+	Recipe     map[string]string `gorm:"serializer:json" json:"recipe"`
+	Notes      string            `json:"notes"`
+
+	Gizmo   *Gizmo   `gorm:"foreignKey:GID; constraint:OnDelete:RESTRICT" json:"-"`
+	Dataset *Dataset `gorm:"foreignKey:DID; constraint:OnDelete:RESTRICT" json:"-"`
+}
+func (Process) TableName() string { return "processes" }
+
+/* TRANSPORT OBJECT */
+type ProcessInput struct {
+	GID    int64             `json:"gid" validate:"required"`
+	DID    int64             `json:"did" validate:"required"`
+	Name   string            `json:"name" validate:"required"`
+	Start  int64             `json:"start"`
+	End    int64             `json:"end"`
+	Recipe map[string]string `json:"recipe"`
+	Notes  string            `json:"notes"`
+}
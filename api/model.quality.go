@@ -0,0 +1,32 @@
+package api
+
+/* GET ( QUERY ) AND POST ( BODY ) /api/variates/:id/outliers; ZERO VALUES TAKE THE utils DEFAULTS */
+type OutlierInput struct {
+	Method       string  `query:"method" json:"method"` // hampel | mad | iqr
+	Window       int     `query:"window" json:"window"` // hampel SAMPLES ON EACH SIDE
+	Threshold    float64 `query:"threshold" json:"threshold"`
+	BadThreshold float64 `query:"bad_threshold" json:"bad_threshold"`
+	Start        int64   `query:"start" json:"start"`
+	End          int64   `query:"end" json:"end"`
+}
+
+/* PUT /api/datasets/:id/quality; FLAGS EVERY SAMPLE OF Channel OVER [ Start, End ) */
+type QualityInput struct {
+	Channel string `json:"channel" validate:"required"`
+	Start   int64  `json:"start"`
+	End     int64  `json:"end"`                         // 0 MEANS OPEN ENDED
+	Quality string `json:"quality" validate:"required"` // SEE utils.QUALITY_NAMES
+}
+
+/* GET /api/datasets/:id/quality */
+type QualityQuery struct {
+	Channel string `query:"channel"`
+	Start   int64  `query:"start"`
+	End     int64  `query:"end"`
+}
+
+/* SAMPLES PER QUALITY FLAG */
+type QualityCount struct {
+	Quality string `json:"quality"`
+	Count   int64  `json:"count"`
+}
@@ -0,0 +1,10 @@
+package api
+
+/* GET /api/variates/:id/regression; THE WINDOW DEFAULTS TO THE PROCESS'S OWN */
+type RegressionQuery struct {
+	Start  int64   `query:"start"`
+	End    int64   `query:"end"`
+	Method string  `query:"method"` // poly ( DEFAULT ) | wls | theil_sen | huber
+	Degree int     `query:"degree"` // poly, wls AND huber; 0 IS REGRESSION_DEGREE
+	K      float64 `query:"k"`      // huber TUNING CONSTANT IN ROBUST SIGMAS; 0 IS utils.HUBER_K
+}
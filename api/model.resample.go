@@ -0,0 +1,20 @@
+package api
+
+/* GET /api/processes/:id/resample; THE WINDOW DEFAULTS TO THE PROCESS'S OWN */
+type ResampleQuery struct {
+	VIDs   string `query:"vids"` // COMMA SEPARATED; DEFAULT IS EVERY VARIATE OF THE PROCESS
+	Start  int64  `query:"start"`
+	End    int64  `query:"end"`
+	Step   int64  `query:"step"`    // Time:milli; REQUIRED
+	Interp string `query:"interp"`  // linear | previous | nearest
+	Agg    string `query:"agg"`     // mean | min | max | last
+	MaxGap int64  `query:"max_gap"` // Time:milli; 0 IS UNLIMITED
+}
+
+/* ONE VARIATE ON THE GRID; null WHERE THE VALUE IS MISSING */
+type ResampledVariate struct {
+	VID  int64      `json:"vid"`
+	Name string     `json:"name"`
+	Unit string     `json:"unit"`
+	Y    []*float64 `json:"y"`
+}
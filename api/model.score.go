@@ -0,0 +1,33 @@
+package api
+
+import (
+	"jaQC-Go-API/utils"
+)
+
+/* CHECK FOR CANCEL AND REPORT PROGRESS EVERY N AGGREGATES */
+const SCORE_PROGRESS_AGGREGATES int = 1000
+
+/* ONE VERSION OF A VARIATE'S QC SCORING RULES; A CHANGE WRITES THE NEXT VERSION, OLD ONES ARE KEPT */
+type ScoreRuleSet struct {
+	utils.Meta `gorm:"embedded"`
+	VID        int64  `gorm:"column:vid; not null; uniqueIndex:idx_score_rule_sets_vid_version" json:"vid"` // VARIATE ID
+	Version    int    `gorm:"not null; uniqueIndex:idx_score_rule_sets_vid_version" json:"version"`         // 1, 2, ... PER VARIATE
+	Note       string `gorm:"type:varchar(255)" json:"note"`
+
+	utils.ScoreRules `gorm:"embedded"`
+
+	Variate *Variate `gorm:"foreignKey:VID; constraint:OnDelete:CASCADE" json:"-"`
+}
+func (ScoreRuleSet) TableName() string { return "score_rule_sets" }
+
+/* TRANSPORT OBJECT; BECOMES THE VARIATE'S NEXT RULE SET VERSION */
+type ScoreRuleSetInput struct {
+	utils.ScoreRules
+	Note string `json:"note"`
+}
+
+/* TRANSPORT OBJECT; RE-SCORE [ start, end ), DEFAULTING TO EVERY AGGREGATE OF THE VARIATE */
+type ScoreInput struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
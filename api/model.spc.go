@@ -0,0 +1,24 @@
+package api
+
+/* CHECK FOR CANCEL AND REPORT PROGRESS EVERY N AGGREGATES */
+const SPC_PROGRESS_AGGREGATES int = 1000
+
+/* GET /api/variates/:id/spc; NON ZERO FIELDS OVERRIDE THE VARIATE'S SPC CONFIG */
+type SPCQuery struct {
+	Start         int64   `query:"start"`
+	End           int64   `query:"end"`
+	Chart         string  `query:"chart"`
+	Source        string  `query:"source"`
+	Subgroup      int     `query:"subgroup"`
+	Rules         string  `query:"rules"`
+	BaselineStart int64   `query:"baseline_start"`
+	BaselineEnd   int64   `query:"baseline_end"`
+	Lambda        float64 `query:"lambda"`
+	L             float64 `query:"l"`
+}
+
+/* TRANSPORT OBJECT; RE-EVALUATE [ start, end ), DEFAULTING TO THE PROCESS WINDOW */
+type SPCInput struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
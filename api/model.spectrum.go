@@ -0,0 +1,13 @@
+package api
+
+/* GET /api/variates/:id/spectrum; THE WINDOW DEFAULTS TO THE PROCESS'S OWN, ZERO VALUES TAKE THE utils DEFAULTS */
+type SpectrumQuery struct {
+	Start   int64    `query:"start"`
+	End     int64    `query:"end"`
+	Rate    float64  `query:"rate"`    // Hz; DEFAULTS TO Variate.SampleRate, ELSE THE MEDIAN SAMPLE INTERVAL
+	Segment int      `query:"segment"` // SAMPLES PER WELCH SEGMENT
+	Overlap *float64 `query:"overlap"` // FRACTION OF A SEGMENT; ABSENT IS utils.PSD_OVERLAP, 0 IS NO OVERLAP
+	Window  string   `query:"window"`  // hann | hamming | blackman | rect
+	Detrend string   `query:"detrend"` // linear | constant | none
+	Peaks   int      `query:"peaks"`
+}
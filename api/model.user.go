@@ -10,6 +10,7 @@ type User struct {
 	Name     string `gorm:"type:varchar(100);not null" json:"name"`
 	Email    string `gorm:"type:varchar(100);uniqueIndex;not null" json:"email"`
 	Role     string `json:"role"`
+	Org      string `gorm:"type:varchar(100);index" json:"org"`
 }
 func (User) TableName() string { return "users" }
 
@@ -31,6 +32,7 @@ type UserResponse struct {
 	Name      string `json:"name"`
 	Email     string `json:"email"`
 	Role      string `json:"role"`
+	Org       string `json:"org"`
 	CreatedAt int64  `json:"created_at"`
 	UpdatedAt int64  `json:"updated_at"`
 }
\ No newline at end of file
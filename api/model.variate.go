@@ -0,0 +1,63 @@
+package api
+
+import (
+	"jaQC-Go-API/utils"
+)
+
+/* A MEASURED CHANNEL OF A PROCESS; Channel MATCHES Sample.Channel AND Calibration.Channel */
+type Variate struct {
+	utils.Meta `gorm:"embedded"`
+	PID        int64   `gorm:"column:pid; not null; index" json:"pid"` // PROCESS ID
+	Channel    string  `gorm:"type:varchar(100);not null" json:"channel"`
+	Name       string  `gorm:"type:varchar(100);not null" json:"name"`
+	Unit       string  `gorm:"type:varchar(50)" json:"unit"`
+	ExpectMin  float32 `json:"expect_min"`
+	ExpectMax  float32 `json:"expect_max"`
+	SampleRate float32 `json:"sample_rate"` // Hz
+
+	Cluster utils.ClusterConfig `gorm:"serializer:json" json:"cluster"` // ZERO FIELDS TAKE DEFAULTS; SEE ClusterConfig()
+	SPC     utils.SPCConfig     `gorm:"column:spc; serializer:json" json:"spc"` // DECIDES Aggregate.Valid; SEE SPCConfig()
+	Exclude string              `gorm:"type:varchar(100)" json:"exclude"`        // QUALITY FLAGS LEFT OUT OF AGGREGATES AND STATISTICS, COMMA SEPARATED
+	Filters utils.FilterChain   `gorm:"serializer:json" json:"filters"`          // RUN ON SAMPLES BEFORE CLUSTERING; SEE HandleUpdateVariateFilters
+
+	utils.Spec `gorm:"embedded"` // lsl, usl, target FOR CAPABILITY; SEE HandleUpdateVariateSpec
+
+	Process *Process `gorm:"foreignKey:PID; constraint:OnDelete:CASCADE" json:"-"`
+}
+func (Variate) TableName() string { return "variates" }
+
+/* TRANSPORT OBJECT */
+type VariateInput struct {
+	Channel    string  `json:"channel" validate:"required"`
+	Name       string  `json:"name"`
+	Unit       string  `json:"unit"`
+	ExpectMin  float32 `json:"expect_min"`
+	ExpectMax  float32 `json:"expect_max"`
+	SampleRate float32 `json:"sample_rate"`
+
+	Cluster utils.ClusterConfig `json:"cluster"`
+	SPC     utils.SPCConfig     `json:"spc"`
+	Exclude string              `json:"exclude"`
+	Filters utils.FilterChain   `json:"filters"`
+
+	utils.Spec
+}
+
+/* GET /api/variates/:id/series */
+type SeriesQuery struct {
+	Start     int64  `query:"start"`
+	End       int64  `query:"end"`
+	MaxPoints int    `query:"max_points"` // 0 IS SERIES_MAX_POINTS
+	Method    string `query:"method"`     // lttb ( DEFAULT ) | minmax
+	Filtered  bool   `query:"filtered"`   // THROUGH THE VARIATE'S FILTER CHAIN, AS CLUSTERING SEES IT
+}
+
+/* PUT /api/variates/:id/filters */
+type FiltersInput struct {
+	Filters utils.FilterChain `json:"filters"` // EMPTY CLUSTERS THE SAMPLES AS RECORDED
+}
+
+/* PUT /api/variates/:id/exclude */
+type ExcludeInput struct {
+	Exclude string `json:"exclude"` // QUALITY FLAG NAMES, COMMA SEPARATED; EMPTY KEEPS EVERY SAMPLE
+}
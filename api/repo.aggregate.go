@@ -0,0 +1,411 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"jaQC-Go-API/utils"
+)
+
+const AGGREGATE_WRITE_ERR = "error writing aggregate record to main database"
+
+func GetAggregateListByProcess(pid int64) (aggs []Aggregate, err error) {
+	qry := MDB.Raw(`
+		SELECT *
+		FROM `+TBL_AGGS+`
+		WHERE pid = ?
+		AND deleted_at = 0
+		ORDER BY vid, start
+		`,
+		pid,
+	)
+	err = MDB.Scanner(qry, &aggs)
+	return
+}
+func GetAggregateListByVariate(vid int64) (aggs []Aggregate, err error) {
+	qry := MDB.Raw(`
+		SELECT *
+		FROM `+TBL_AGGS+`
+		WHERE vid = ?
+		AND deleted_at = 0
+		ORDER BY start
+		`,
+		vid,
+	)
+	err = MDB.Scanner(qry, &aggs)
+	return
+}
+
+func (agg *Aggregate) Create(uid int64) (err error) {
+	agg.CreatedBy = uid
+	agg.UpdatedBy = uid
+	if res := MDB.Create(agg); res.Error != nil {
+		err = fmt.Errorf("%s: %s", AGGREGATE_WRITE_ERR, res.Error.Error())
+	}
+	return
+}
+
+/* A VARIATE'S AGGREGATES OVERLAPPING [ start, end ); end == 0 MEANS OPEN ENDED */
+func GetAggregateListByVariateWindow(vid, start, end int64) (aggs []Aggregate, err error) {
+	if end == 0 {
+		end = int64(^uint64(0) >> 1)
+	}
+	qry := MDB.Raw(`
+		SELECT *
+		FROM `+TBL_AGGS+`
+		WHERE vid = ?
+		AND "end" >= ? AND start < ?
+		AND deleted_at = 0
+		ORDER BY start
+		`,
+		vid,
+		start,
+		end,
+	)
+	err = MDB.Scanner(qry, &aggs)
+	return
+}
+
+/* WRITES THE SPC VERDICT ONLY */
+func (agg *Aggregate) UpdateSPC(uid int64) (err error) {
+	agg.UpdatedBy = uid
+	res := MDB.Model(agg).Select("Valid", "Rule", "UpdatedAt", "UpdatedBy").Updates(agg)
+	if res.Error != nil {
+		err = fmt.Errorf("%s: %s", AGGREGATE_WRITE_ERR, res.Error.Error())
+	}
+	return
+}
+
+/* WRITES THE SCORE AND THE RULE SET VERSION BEHIND IT ONLY */
+func (agg *Aggregate) UpdateScore(uid int64) (err error) {
+	agg.UpdatedBy = uid
+	res := MDB.Model(agg).Select("Score", "ScoreVersion", "Verdict", "UpdatedAt", "UpdatedBy").Updates(agg)
+	if res.Error != nil {
+		err = fmt.Errorf("%s: %s", AGGREGATE_WRITE_ERR, res.Error.Error())
+	}
+	return
+}
+
+/*
+REMOVES A VARIATE'S AGGREGATES OVERLAPPING [ start, end ); end == 0 MEANS OPEN ENDED
+RETURNS THE WINDOW WIDENED TO COVER WHAT WAS REMOVED, SO RECLUSTERING CAN REBUILD ALL OF IT
+*/
+func DeleteVariateAggregates(vid, start, end int64) (first, last int64, err error) {
+
+	first, last = start, end
+	if end == 0 {
+		end = int64(^uint64(0) >> 1)
+	}
+
+	ext := struct {
+		First int64
+		Last  int64
+	}{}
+	qry := MDB.Raw(`
+		SELECT COALESCE(MIN(start), 0) AS first, COALESCE(MAX("end"), 0) AS last
+		FROM `+TBL_AGGS+`
+		WHERE vid = ?
+		AND "end" >= ? AND start < ?
+		`,
+		vid,
+		start,
+		end,
+	)
+	if err = MDB.Scanner(qry, &ext); err != nil {
+		return
+	}
+	if ext.First != 0 && ext.First < first {
+		first = ext.First
+	}
+	/* THE LAST SAMPLE OF A CLUSTER IS AT "end"; THE WINDOW IS HALF OPEN */
+	if last != 0 && ext.Last >= last {
+		last = ext.Last + 1
+	}
+
+	if res := MDB.Exec(`
+		DELETE FROM `+TBL_AGGS+`
+		WHERE vid = ?
+		AND "end" >= ? AND start < ?
+		`,
+		vid,
+		start,
+		end,
+	); res.Error != nil {
+		err = fmt.Errorf("%s: %s", AGGREGATE_WRITE_ERR, res.Error.Error())
+	}
+	return
+}
+
+const AGG_QUERY_LIMIT = 100
+const AGG_QUERY_MAX_LIMIT = 1000
+const AGG_SUMMARY_BINS = 10
+const AGG_SUMMARY_MAX_BINS = 1000
+
+/* COLUMNS AGGREGATES MAY BE SORTED BY */
+var AGG_SORT_COLS = map[string]bool{
+	"id": true, "start": true, "end": true, "size": true, "score": true,
+	"min": true, "max": true, "mean": true, "slope": true, "devi": true,
+}
+
+/* BUILDS THE WHERE CLAUSE SHARED BY LIST AND SUMMARY QUERIES */
+func (aq *AggregateQuery) where() (sql string, args []interface{}) {
+
+	sql = ` WHERE deleted_at = 0 `
+	add := func(clause string, arg interface{}) {
+		sql += ` AND ` + clause
+		args = append(args, arg)
+	}
+
+	if aq.PID != 0 {
+		add(`pid = ?`, aq.PID)
+	}
+	if aq.VID != 0 {
+		add(`vid = ?`, aq.VID)
+	}
+	if aq.Code != nil {
+		add(`code = ?`, *aq.Code)
+	}
+	if aq.Valid != nil {
+		add(`valid = ?`, *aq.Valid)
+	}
+	if aq.ScoreMin != nil {
+		add(`score >= ?`, *aq.ScoreMin)
+	}
+	if aq.ScoreMax != nil {
+		add(`score <= ?`, *aq.ScoreMax)
+	}
+	if aq.Verdict != "" {
+		add(`verdict = ?`, aq.Verdict)
+	}
+
+	/* OVERLAPPING THE WINDOW; THE LAST SAMPLE OF A CLUSTER IS AT "end" */
+	if aq.Start != 0 {
+		add(`"end" >= ?`, aq.Start)
+	}
+	if aq.End != 0 {
+		add(`start < ?`, aq.End)
+	}
+	return
+}
+
+/* ONE PAGE OF AGGREGATES AND THE CURSOR FOR THE NEXT; next IS BLANK ON THE LAST PAGE */
+func (aq *AggregateQuery) GetAggregateList() (aggs []Aggregate, next string, err error) {
+
+	col, desc := aq.Sort, false
+	if len(col) > 0 && col[0] == '-' {
+		col, desc = col[1:], true
+	}
+	if col == "" {
+		col = "id"
+	}
+	if !AGG_SORT_COLS[col] {
+		err = fmt.Errorf("invalid sort column: %s", col)
+		return
+	}
+
+	if aq.Limit <= 0 {
+		aq.Limit = AGG_QUERY_LIMIT
+	}
+	if aq.Limit > AGG_QUERY_MAX_LIMIT {
+		aq.Limit = AGG_QUERY_MAX_LIMIT
+	}
+
+	where, args := aq.where()
+
+	/* KEYSET PAGING ON ( col, id ) */
+	cmp, dir := ">", "ASC"
+	if desc {
+		cmp, dir = "<", "DESC"
+	}
+	if aq.Cursor != "" {
+		cur := AggregateCursor{}
+		if err = decodeCursor(aq.Cursor, &cur); err != nil {
+			return
+		}
+		where += fmt.Sprintf(` AND ( "%s" %s ? OR ( "%s" = ? AND id %s ? ) )`, col, cmp, col, cmp)
+		args = append(args, cur.Value, cur.Value, cur.ID)
+	}
+
+	qry := MDB.Raw(`
+		SELECT *
+		FROM `+TBL_AGGS+where+
+		fmt.Sprintf(` ORDER BY "%s" %s, id %s LIMIT %d`, col, dir, dir, aq.Limit+1),
+		args...,
+	)
+	if err = MDB.Scanner(qry, &aggs); err != nil {
+		return
+	}
+
+	/* THE EXTRA ROW TELLS US THERE IS ANOTHER PAGE */
+	if len(aggs) > aq.Limit {
+		aggs = aggs[:aq.Limit]
+		last := aggs[len(aggs)-1]
+		next, err = encodeCursor(AggregateCursor{Value: last.sortValue(col), ID: last.ID})
+	}
+	return
+}
+
+func (agg *Aggregate) sortValue(col string) float64 {
+	switch col {
+	case "start":
+		return float64(agg.Start)
+	case "end":
+		return float64(agg.End)
+	case "size":
+		return float64(agg.Size)
+	case "score":
+		return float64(agg.Score)
+	case "min":
+		return float64(agg.Min)
+	case "max":
+		return float64(agg.Max)
+	case "mean":
+		return float64(agg.Mean)
+	case "slope":
+		return float64(agg.Slope)
+	case "devi":
+		return float64(agg.Devi)
+	}
+	return float64(agg.ID)
+}
+
+func encodeCursor(cur AggregateCursor) (str string, err error) {
+	js, err := json.Marshal(cur)
+	if err != nil {
+		return
+	}
+	return utils.BytesToBase64URL(js), nil
+}
+func decodeCursor(str string, cur *AggregateCursor) (err error) {
+	js, err := utils.Base64URLToBytes(str)
+	if err != nil {
+		return fmt.Errorf("invalid cursor")
+	}
+	if err = json.Unmarshal(js, cur); err != nil {
+		return fmt.Errorf("invalid cursor")
+	}
+	return
+}
+
+/* COUNTS AND SCORE DISTRIBUTION PER VARIATE FOR THE FILTERED AGGREGATES */
+func (aq *AggregateQuery) GetAggregateSummary() (sums []AggregateSummary, err error) {
+
+	if aq.Bins <= 0 {
+		aq.Bins = AGG_SUMMARY_BINS
+	}
+
+	where, args := aq.where()
+	rows, err := MDB.Raw(`
+		SELECT vid, score, valid
+		FROM `+TBL_AGGS+where+`
+		ORDER BY vid, score
+		`,
+		args...,
+	).Rows()
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	var vid int64
+	var score float32
+	var valid bool
+	scores := []float32{}
+	cur := AggregateSummary{}
+
+	flush := func() {
+		if cur.Count > 0 {
+			sums = append(sums, cur.distribution(scores, aq.Bins))
+		}
+	}
+
+	for rows.Next() {
+		if err = rows.Scan(&vid, &score, &valid); err != nil {
+			return
+		}
+		if vid != cur.VID || cur.Count == 0 {
+			flush()
+			cur = AggregateSummary{VID: vid}
+			scores = scores[:0]
+		}
+		cur.Count++
+		if valid {
+			cur.ValidCount++
+		}
+		scores = append(scores, score)
+	}
+	flush()
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	vids := make([]int64, len(sums))
+	for i := range sums {
+		vids[i] = sums[i].VID
+	}
+	caps, err := GetLatestCapabilities(vids)
+	if err != nil {
+		return
+	}
+	for i := range sums {
+		if pcap, ok := caps[sums[i].VID]; ok {
+			sums[i].Capability = &pcap
+		}
+	}
+	return
+}
+
+/* scores ARRIVE SORTED ASCENDING */
+func (sum AggregateSummary) distribution(scores []float32, bins int) AggregateSummary {
+
+	sum.ScoreMin = scores[0]
+	sum.ScoreMax = scores[len(scores)-1]
+	sum.ScoreMean = utils.MeanFloat32(scores)
+	sum.ScoreP50 = scores[len(scores)/2]
+
+	span := sum.ScoreMax - sum.ScoreMin
+	sum.Histogram = make([]int64, bins)
+	sum.BinEdges = make([]float32, bins+1)
+	for b := range sum.BinEdges {
+		sum.BinEdges[b] = sum.ScoreMin + span*float32(b)/float32(bins)
+	}
+	for _, s := range scores {
+		b := 0
+		if span > 0 {
+			b = int((s - sum.ScoreMin) / span * float32(bins))
+		}
+		if b >= bins {
+			b = bins - 1
+		}
+		sum.Histogram[b]++
+	}
+	return sum
+}
+
+/* CALLS fn FOR EVERY FILTERED AGGREGATE IN id ORDER WITHOUT HOLDING THEM IN MEMORY */
+func (aq *AggregateQuery) StreamAggregates(fn func(agg *Aggregate) error) (err error) {
+
+	where, args := aq.where()
+	rows, err := MDB.Raw(`
+		SELECT *
+		FROM `+TBL_AGGS+where+`
+		ORDER BY id
+		`,
+		args...,
+	).Rows()
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		agg := Aggregate{}
+		if err = MDB.ScanRows(rows, &agg); err != nil {
+			return
+		}
+		if err = fn(&agg); err != nil {
+			return
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"fmt"
+)
+
+const CAL_WRITE_ERR = "error writing calibration record to main database"
+
+func GetCalibrationByID(id int64) (cal Calibration, err error) {
+
+	qry := MDB.Raw(`
+		SELECT * 
+		FROM `+TBL_CALS+`
+		WHERE id = ?
+		AND deleted_at = 0
+		`,
+		id,
+	)
+
+	if err = MDB.Scanner(qry, &cal); err != nil {
+		return
+	}
+
+	if cal.ID == 0 {
+		err = fmt.Errorf("calibration with id %d does not exist", id)
+		return
+	}
+
+	return
+}
+
+/* ALL CALIBRATIONS FOR GIVEN GIZMO, INCLUDING SUPERSEDED ONES */
+func GetCalibrationListByGizmo(gid int64) (cals []Calibration, err error) {
+	qry := MDB.Raw(`
+		SELECT *
+		FROM `+TBL_CALS+`
+		WHERE gid = ?
+		AND deleted_at = 0
+		ORDER BY channel, effective_from
+		`,
+		gid,
+	)
+	err = MDB.Scanner(qry, &cals)
+	return
+}
+
+/* IN-FORCE CALIBRATIONS FOR ONE CHANNEL, OLDEST FIRST */
+func GetCalibrationListByChannel(gid int64, channel string) (cals []Calibration, err error) {
+	qry := MDB.Raw(`
+		SELECT *
+		FROM `+TBL_CALS+`
+		WHERE gid = ?
+		AND channel = ?
+		AND superseded_by = 0
+		AND deleted_at = 0
+		ORDER BY effective_from
+		`,
+		gid,
+		channel,
+	)
+	err = MDB.Scanner(qry, &cals)
+	return
+}
+
+func (cal *Calibration) Create(uid int64) (err error) {
+	cal.CreatedBy = uid
+	cal.UpdatedBy = uid
+	if res := MDB.Create(cal); res.Error != nil {
+		err = fmt.Errorf("%s: %s", CAL_WRITE_ERR, res.Error.Error())
+	}
+	return
+}
+
+/* CREATES corr AND MARKS cal AS SUPERSEDED IN ONE TRANSACTION */
+func (cal *Calibration) Correct(corr *Calibration, uid int64) (err error) {
+
+	corr.GID = cal.GID
+	corr.Channel = cal.Channel
+	corr.Supersedes = cal.ID
+	corr.CreatedBy = uid
+	corr.UpdatedBy = uid
+
+	tx := MDB.Begin()
+	if res := tx.Create(corr); res.Error != nil {
+		tx.Rollback()
+		return fmt.Errorf("%s: %s", CAL_WRITE_ERR, res.Error.Error())
+	}
+
+	cal.SupersededBy = corr.ID
+	cal.UpdatedBy = uid
+	if res := tx.Save(cal); res.Error != nil {
+		tx.Rollback()
+		return fmt.Errorf("%s: %s", CAL_WRITE_ERR, res.Error.Error())
+	}
+
+	if res := tx.Commit(); res.Error != nil {
+		err = fmt.Errorf("%s: %s", CAL_WRITE_ERR, res.Error.Error())
+	}
+	return
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"fmt"
+)
+
+const CAPABILITY_WRITE_ERR = "error writing capability records to main database"
+
+/* NEWEST FIRST */
+func GetCapabilityListByVariate(vid int64) (caps []Capability, err error) {
+	qry := MDB.Raw(`
+		SELECT *
+		FROM `+TBL_CAPS+`
+		WHERE vid = ?
+		AND deleted_at = 0
+		ORDER BY id DESC
+		`,
+		vid,
+	)
+	err = MDB.Scanner(qry, &caps)
+	return
+}
+
+/* THE MOST RECENT CAPABILITY OF EACH VARIATE IN vids; VARIATES WITHOUT ONE ARE ABSENT */
+func GetLatestCapabilities(vids []int64) (caps map[int64]Capability, err error) {
+
+	caps = make(map[int64]Capability)
+	if len(vids) == 0 {
+		return
+	}
+
+	list := []Capability{}
+	qry := MDB.Raw(`
+		SELECT *
+		FROM `+TBL_CAPS+`
+		WHERE id IN (
+			SELECT MAX(id)
+			FROM `+TBL_CAPS+`
+			WHERE vid IN ?
+			AND deleted_at = 0
+			GROUP BY vid
+		)
+		`,
+		vids,
+	)
+	if err = MDB.Scanner(qry, &list); err != nil {
+		return
+	}
+	for _, pcap := range list {
+		caps[pcap.VID] = pcap
+	}
+	return
+}
+
+func (pcap *Capability) Create(uid int64) (err error) {
+	pcap.CreatedBy = uid
+	pcap.UpdatedBy = uid
+	if res := MDB.Create(pcap); res.Error != nil {
+		err = fmt.Errorf("%s: %s", CAPABILITY_WRITE_ERR, res.Error.Error())
+	}
+	return
+}
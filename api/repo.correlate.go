@@ -0,0 +1,44 @@
+package api
+
+import (
+	"fmt"
+)
+
+const CORRELATE_WRITE_ERR = "error writing correlate records to main database"
+
+func GetCorrelateListByProcess(pid int64, method string) (crlts []Correlate, err error) {
+	qry := MDB.Raw(`
+		SELECT *
+		FROM `+TBL_CRLTS+`
+		WHERE pid = ?
+		AND method = ?
+		AND deleted_at = 0
+		`,
+		pid,
+		method,
+	)
+	err = MDB.Scanner(qry, &crlts)
+	return
+}
+
+/* REPLACES ALL CORRELATES FOR THE PROCESS IN ONE TRANSACTION */
+func ReplaceProcessCorrelates(pid int64, crlts []Correlate) (err error) {
+
+	tx := MDB.Begin()
+	if res := tx.Exec(`DELETE FROM `+TBL_CRLTS+` WHERE pid = ?`, pid); res.Error != nil {
+		tx.Rollback()
+		return fmt.Errorf("%s: %s", CORRELATE_WRITE_ERR, res.Error.Error())
+	}
+
+	if len(crlts) > 0 {
+		if res := tx.Create(&crlts); res.Error != nil {
+			tx.Rollback()
+			return fmt.Errorf("%s: %s", CORRELATE_WRITE_ERR, res.Error.Error())
+		}
+	}
+
+	if res := tx.Commit(); res.Error != nil {
+		err = fmt.Errorf("%s: %s", CORRELATE_WRITE_ERR, res.Error.Error())
+	}
+	return
+}
@@ -0,0 +1,378 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+
+	"jaQC-Go-API/utils"
+)
+
+const DATASET_WRITE_ERR = "error writing dataset record to main database"
+const SAMPLE_WRITE_ERR = "error writing samples to dataset database"
+const SAMPLE_BATCH_SIZE = 1000
+
+func GetDatasetList() (dsets []Dataset, err error) {
+	qry := MDB.Raw(`
+		SELECT *
+		FROM ` + TBL_DATS + `
+		WHERE deleted_at = 0
+		ORDER BY id
+	`)
+	err = MDB.Scanner(qry, &dsets)
+	return
+}
+func GetDatasetListByGizmo(gid int64) (dsets []Dataset, err error) {
+	qry := MDB.Raw(`
+		SELECT *
+		FROM `+TBL_DATS+`
+		WHERE gid = ?
+		AND deleted_at = 0
+		ORDER BY id
+		`,
+		gid,
+	)
+	err = MDB.Scanner(qry, &dsets)
+	return
+}
+func GetDatasetByID(id int64) (ds Dataset, err error) {
+
+	qry := MDB.Raw(`
+		SELECT * 
+		FROM `+TBL_DATS+`
+		WHERE id = ?
+		AND deleted_at = 0
+		`,
+		id,
+	)
+
+	if err = MDB.Scanner(qry, &ds); err != nil {
+		return
+	}
+
+	if ds.ID == 0 {
+		err = fmt.Errorf("dataset with id %d does not exist", id)
+		return
+	}
+
+	return
+}
+
+func (ds *Dataset) Path() string {
+	return fmt.Sprintf("%s/%s", DATASET_DB_DIR, ds.File)
+}
+
+/* WRITES THE RECORD, THEN CREATES THE DATABASE FILE AND ITS SCHEMA */
+func (ds *Dataset) Create(uid int64) (err error) {
+
+	ds.CreatedBy = uid
+	ds.UpdatedBy = uid
+	if res := MDB.Create(ds); res.Error != nil {
+		return fmt.Errorf("%s: %s", DATASET_WRITE_ERR, res.Error.Error())
+	}
+
+	ds.File = fmt.Sprintf("dataset_%06d.db", ds.ID)
+	if res := MDB.Save(ds); res.Error != nil {
+		return fmt.Errorf("%s: %s", DATASET_WRITE_ERR, res.Error.Error())
+	}
+
+	/* OPENING CREATES THE FILE AND MIGRATES ITS SCHEMA */
+	if _, err = ds.Open(); err != nil {
+		ds.Close()
+		MDB.Delete(ds)
+		os.Remove(ds.Path())
+		return
+	}
+
+	log.Info("DATASET CREATED : ", ds.Path())
+	return
+}
+
+/* RETURNS THE OPEN CLIENT FOR THE DATASET, CONNECTING IF NEEDED */
+func (ds *Dataset) Open() (ddb *DatasetDatabase, err error) {
+
+	DatasetDatabasesRWMutex.Lock()
+	defer DatasetDatabasesRWMutex.Unlock()
+
+	if ddb, ok := DatasetDatabases[ds.ID]; ok && ddb.ConnectionOK() {
+		return ddb, nil
+	}
+
+	if ds.File == "" {
+		err = fmt.Errorf("dataset %d has no database file", ds.ID)
+		return
+	}
+
+	ddb = &DatasetDatabase{}
+	if ddb.SQLiteClient, err = utils.ConfigureDBSQLiteClient(DATASET_DB_DIR, ds.File); err != nil {
+		return
+	}
+
+	if err = ddb.Connect(); err != nil {
+		return
+	}
+
+	if _, err = utils.Migrate(ddb.DB, DATASET_DB_MIGRATIONS, utils.MIGRATE_LATEST, false); err != nil {
+		ddb.Disconnect()
+		return
+	}
+
+	DatasetDatabases[ds.ID] = ddb
+	return
+}
+
+func (ds *Dataset) Close() (err error) {
+
+	DatasetDatabasesRWMutex.Lock()
+	defer DatasetDatabasesRWMutex.Unlock()
+
+	ddb, ok := DatasetDatabases[ds.ID]
+	if !ok {
+		return
+	}
+	delete(DatasetDatabases, ds.ID)
+	return ddb.Disconnect()
+}
+
+/* CLOSES EVERY OPEN DATASET DATABASE; CALLED ON SHUTDOWN */
+func CloseDatasetDatabases() {
+	DatasetDatabasesRWMutex.Lock()
+	defer DatasetDatabasesRWMutex.Unlock()
+
+	for id, ddb := range DatasetDatabases {
+		if err := ddb.Disconnect(); err != nil {
+			utils.LogErr(err)
+		}
+		delete(DatasetDatabases, id)
+	}
+}
+
+/* CLOSES AND REMOVES THE DATABASE FILE; THE RECORD IS KEPT, MARKED DELETED */
+func (ds *Dataset) Delete(uid int64) (err error) {
+
+	if err = ds.Close(); err != nil {
+		return
+	}
+
+	if err = os.Remove(ds.Path()); err != nil && !os.IsNotExist(err) {
+		return utils.LogErr(err)
+	}
+
+	ds.DeletedAt = time.Now().UTC().UnixMilli()
+	ds.UpdatedBy = uid
+	if res := MDB.Save(ds); res.Error != nil {
+		err = fmt.Errorf("%s: %s", DATASET_WRITE_ERR, res.Error.Error())
+	}
+	return
+}
+
+/* COMPUTES SIZE AND ROW COUNTS AND STORES THE TOTALS ON THE RECORD */
+func (ds *Dataset) Stats() (stats DatasetStats, err error) {
+
+	ddb, err := ds.Open()
+	if err != nil {
+		return
+	}
+
+	fi, err := os.Stat(ds.Path())
+	if err != nil {
+		return stats, utils.LogErr(err)
+	}
+	stats.Bytes = fi.Size()
+	/* UNTIL A CHECKPOINT, RECENT WRITES LIVE IN THE WRITE-AHEAD LOG */
+	if wal, wal_err := os.Stat(ds.Path() + "-wal"); wal_err == nil {
+		stats.Bytes += wal.Size()
+	}
+
+	qry := ddb.Raw(`
+		SELECT channel, COUNT(*) AS rows, MIN(x) AS first_x, MAX(x) AS last_x
+		FROM ` + TBL_SAMPLES + `
+		GROUP BY channel
+		ORDER BY channel
+	`)
+	if err = ddb.Scanner(qry, &stats.Channels); err != nil {
+		return
+	}
+
+	for i, ch := range stats.Channels {
+		stats.Rows += ch.Rows
+		if i == 0 || ch.FirstX < stats.FirstX {
+			stats.FirstX = ch.FirstX
+		}
+		if ch.LastX > stats.LastX {
+			stats.LastX = ch.LastX
+		}
+	}
+
+	ds.Rows = stats.Rows
+	ds.Bytes = stats.Bytes
+	ds.FirstX = stats.FirstX
+	ds.LastX = stats.LastX
+	if res := MDB.Save(ds); res.Error != nil {
+		err = fmt.Errorf("%s: %s", DATASET_WRITE_ERR, res.Error.Error())
+	}
+	return
+}
+
+/* APPENDS RAW SAMPLES FOR ONE CHANNEL IN BATCHES */
+func (ddb *DatasetDatabase) WriteSamples(channel string, raw utils.TSXY) (err error) {
+
+	smps := make([]Sample, 0, SAMPLE_BATCH_SIZE)
+	for i, x := range raw.X {
+		smps = append(smps, Sample{Channel: channel, X: x, Y: raw.Y[i]})
+		if len(smps) == SAMPLE_BATCH_SIZE || i == len(raw.X)-1 {
+			if res := ddb.Create(&smps); res.Error != nil {
+				return fmt.Errorf("%s: %s", SAMPLE_WRITE_ERR, res.Error.Error())
+			}
+			smps = smps[:0]
+		}
+	}
+	return
+}
+
+/* RAW SAMPLES FOR ONE CHANNEL OVER [ start, end ) AND THEIR QUALITY FLAGS; end == 0 MEANS OPEN ENDED */
+func (ddb *DatasetDatabase) GetSamples(channel string, start, end int64, exclude utils.QualityMask) (raw utils.TSXY, quals []uint8, err error) {
+	err = ddb.StreamSamples(channel, start, end, exclude, func(x int64, y float32, q uint8) error {
+		raw.X = append(raw.X, x)
+		raw.Y = append(raw.Y, y)
+		quals = append(quals, q)
+		return nil
+	})
+	return
+}
+
+/*
+CALLS fn FOR EACH RAW SAMPLE IN x ORDER WITHOUT HOLDING THEM IN MEMORY; AN ERROR FROM fn STOPS THE SCAN
+SAMPLES WITH A QUALITY FLAG IN exclude ARE SKIPPED
+*/
+func (ddb *DatasetDatabase) StreamSamples(channel string, start, end int64, exclude utils.QualityMask, fn func(x int64, y float32, q uint8) error) (err error) {
+
+	if end == 0 {
+		end = int64(^uint64(0) >> 1)
+	}
+
+	rows, err := ddb.Raw(`
+		SELECT x, y, quality
+		FROM `+TBL_SAMPLES+`
+		WHERE channel = ?
+		AND x >= ? AND x < ?
+		ORDER BY x
+		`,
+		channel,
+		start,
+		end,
+	).Rows()
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	var x int64
+	var y float32
+	var q uint8
+	for rows.Next() {
+		if err = rows.Scan(&x, &y, &q); err != nil {
+			return
+		}
+		if exclude.Has(q) {
+			continue
+		}
+		if err = fn(x, y, q); err != nil {
+			return
+		}
+	}
+	return rows.Err()
+}
+
+/* SAMPLES OF channel OVER [ start, end ) PER QUALITY FLAG; end == 0 MEANS OPEN ENDED */
+func (ddb *DatasetDatabase) GetQualityCounts(channel string, start, end int64) (counts []QualityCount, err error) {
+
+	if end == 0 {
+		end = int64(^uint64(0) >> 1)
+	}
+
+	rows := []struct {
+		Quality uint8
+		Count   int64
+	}{}
+	qry := ddb.Raw(`
+		SELECT quality, COUNT(*) AS count
+		FROM `+TBL_SAMPLES+`
+		WHERE channel = ?
+		AND x >= ? AND x < ?
+		GROUP BY quality
+		ORDER BY quality
+		`,
+		channel,
+		start,
+		end,
+	)
+	if err = ddb.Scanner(qry, &rows); err != nil {
+		return
+	}
+
+	counts = []QualityCount{}
+	for _, row := range rows {
+		counts = append(counts, QualityCount{Quality: utils.QualityName(row.Quality), Count: row.Count})
+	}
+	return
+}
+
+/* FLAGS THE SAMPLES OF channel AT xs */
+func (ddb *DatasetDatabase) SetSampleQuality(channel string, xs []int64, q uint8) (err error) {
+	for i := 0; i < len(xs); i += SAMPLE_BATCH_SIZE {
+		batch := xs[i:min(i+SAMPLE_BATCH_SIZE, len(xs))]
+		if res := ddb.Exec(`
+			UPDATE `+TBL_SAMPLES+`
+			SET quality = ?
+			WHERE channel = ?
+			AND x IN ?
+			`,
+			q,
+			channel,
+			batch,
+		); res.Error != nil {
+			return fmt.Errorf("%s: %s", SAMPLE_WRITE_ERR, res.Error.Error())
+		}
+	}
+	return
+}
+
+/*
+FLAGS THE SAMPLES OF channel OVER [ start, end ); end == 0 MEANS OPEN ENDED
+AN EMPTY only FLAGS EVERY SAMPLE, OTHERWISE ONLY THOSE CURRENTLY FLAGGED WITH ONE OF only
+*/
+func (ddb *DatasetDatabase) SetSampleQualityRange(channel string, start, end int64, q uint8, only utils.QualityMask) (count int64, err error) {
+
+	if end == 0 {
+		end = int64(^uint64(0) >> 1)
+	}
+
+	where := ""
+	args := []interface{}{q, channel, start, end}
+	if only != 0 {
+		/* []uint8 WOULD BIND AS A BLOB */
+		qs := []int{}
+		for _, f := range only.Flags() {
+			qs = append(qs, int(f))
+		}
+		where = "AND quality IN ?"
+		args = append(args, qs)
+	}
+
+	res := ddb.Exec(`
+		UPDATE `+TBL_SAMPLES+`
+		SET quality = ?
+		WHERE channel = ?
+		AND x >= ? AND x < ?
+		`+where,
+		args...,
+	)
+	if res.Error != nil {
+		err = fmt.Errorf("%s: %s", SAMPLE_WRITE_ERR, res.Error.Error())
+	}
+	count = res.RowsAffected
+	return
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+const GIZMO_WRITE_ERR = "error writing gizmo record to main database"
+
+func GetGizmoList() (gizs []Gizmo, err error) {
+	qry := MDB.Raw(`
+		SELECT *
+		FROM ` + TBL_GIZMOS + `
+		WHERE deleted_at = 0
+		ORDER BY serial
+	`)
+	err = MDB.Scanner(qry, &gizs)
+	return
+}
+func GetGizmoByID(id int64) (giz Gizmo, err error) {
+
+	qry := MDB.Raw(`
+		SELECT * 
+		FROM `+TBL_GIZMOS+`
+		WHERE id = ?
+		AND deleted_at = 0
+		`,
+		id,
+	)
+
+	if err = MDB.Scanner(qry, &giz); err != nil {
+		return
+	}
+
+	if giz.ID == 0 {
+		err = fmt.Errorf("gizmo with id %d does not exist", id)
+		return
+	}
+
+	return
+}
+/* INCLUDES RETIRED GIZMOS; SERIALS ARE NEVER REUSED */
+func GetGizmoBySerial(serial string) (giz Gizmo, err error) {
+
+	qry := MDB.Raw(`
+		SELECT * 
+		FROM `+TBL_GIZMOS+`
+		WHERE serial = ?
+		`,
+		serial,
+	)
+
+	if err = MDB.Scanner(qry, &giz); err != nil {
+		return
+	}
+
+	if giz.ID == 0 {
+		err = fmt.Errorf("gizmo with serial %s does not exist", serial)
+		return
+	}
+
+	return
+}
+
+func (giz *Gizmo) Create(uid int64) (err error) {
+	giz.CreatedBy = uid
+	giz.UpdatedBy = uid
+	if res := MDB.Create(giz); res.Error != nil {
+		err = fmt.Errorf("%s: %s", GIZMO_WRITE_ERR, res.Error.Error())
+	}
+	return
+}
+
+func (giz *Gizmo) Update(uid int64) (err error) {
+	giz.UpdatedBy = uid
+	if res := MDB.Save(giz); res.Error != nil {
+		err = fmt.Errorf("%s: %s", GIZMO_WRITE_ERR, res.Error.Error())
+	}
+	return
+}
+
+/* SOFT DELETE; DATASETS AND CALIBRATIONS STILL REFERENCE THE RECORD */
+func (giz *Gizmo) Delete(uid int64) (err error) {
+	giz.DeletedAt = time.Now().UTC().UnixMilli()
+	giz.Status = GIZMO_STATUS_RETIRED
+	return giz.Update(uid)
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+const JOB_WRITE_ERR = "error writing job record to main database"
+
+func GetJobByID(id int64) (job Job, err error) {
+
+	qry := MDB.Raw(`
+		SELECT * 
+		FROM `+TBL_JOBS+`
+		WHERE id = ?
+		`,
+		id,
+	)
+
+	if err = MDB.Scanner(qry, &job); err != nil {
+		return
+	}
+
+	if job.ID == 0 {
+		err = fmt.Errorf("job with id %d does not exist", id)
+		return
+	}
+
+	return
+}
+func GetJobListByOwner(owner int64) (jobs []Job, err error) {
+	qry := MDB.Raw(`
+		SELECT *
+		FROM `+TBL_JOBS+`
+		WHERE owner = ?
+		ORDER BY id DESC
+		`,
+		owner,
+	)
+	err = MDB.Scanner(qry, &jobs)
+	return
+}
+
+func (job *Job) Write() (err error) {
+	if res := MDB.Save(job); res.Error != nil {
+		err = fmt.Errorf("%s: %s", JOB_WRITE_ERR, res.Error.Error())
+	}
+	return
+}
+
+/* JOBS STILL MARKED RUNNING FROM A PREVIOUS PROCESS CAN NEVER FINISH */
+func FailInterruptedJobs() (err error) {
+	res := MDB.Exec(`
+		UPDATE `+TBL_JOBS+`
+		SET state = ?, error = ?, ended_at = ?
+		WHERE state IN ( ?, ? )
+		`,
+		JOB_STATE_FAILED,
+		"interrupted by service restart",
+		time.Now().UTC().UnixMilli(),
+		JOB_STATE_QUEUED,
+		JOB_STATE_RUNNING,
+	)
+	if res.Error != nil {
+		err = fmt.Errorf("%s: %s", JOB_WRITE_ERR, res.Error.Error())
+	}
+	return
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+const NOTE_WRITE_ERR = "error writing notification record to main database"
+
+/* USERS MATCHING THE NOTIFICATION'S TARGETS */
+func (note *Notification) GetRecipientUserList() (usrs []User, err error) {
+	qry := MDB.Raw(`
+		SELECT *
+		FROM `+TBL_USERS+`
+		WHERE ( ? = '' OR role = ? )
+		AND ( ? = '' OR org = ? )
+		AND ( ? = 0 OR id = ? )
+		AND deleted_at = 0
+		`,
+		note.TargetRole, note.TargetRole,
+		note.TargetOrg, note.TargetOrg,
+		note.TargetUser, note.TargetUser,
+	)
+	err = MDB.Scanner(qry, &usrs)
+	return
+}
+
+/* WRITES THE NOTIFICATION AND ONE RECIPIENT ROW PER TARGETED USER */
+func (note *Notification) Write() (uids []int64, err error) {
+
+	usrs, err := note.GetRecipientUserList()
+	if err != nil {
+		return
+	}
+	if len(usrs) == 0 {
+		err = fmt.Errorf("no users match the notification targets")
+		return
+	}
+
+	tx := MDB.Begin()
+	if res := tx.Create(note); res.Error != nil {
+		tx.Rollback()
+		err = fmt.Errorf("%s: %s", NOTE_WRITE_ERR, res.Error.Error())
+		return
+	}
+
+	recs := []NotificationRecipient{}
+	for _, usr := range usrs {
+		rec := NotificationRecipient{NID: note.ID, UID: usr.ID}
+		rec.CreatedBy = note.CreatedBy
+		recs = append(recs, rec)
+		uids = append(uids, usr.ID)
+	}
+	if res := tx.Create(&recs); res.Error != nil {
+		tx.Rollback()
+		err = fmt.Errorf("%s: %s", NOTE_WRITE_ERR, res.Error.Error())
+		return
+	}
+
+	if res := tx.Commit(); res.Error != nil {
+		err = fmt.Errorf("%s: %s", NOTE_WRITE_ERR, res.Error.Error())
+	}
+	return
+}
+
+func GetNotificationListByUser(uid int64, unreadOnly bool) (notes []NotificationResponse, err error) {
+	qry := MDB.Raw(`
+		SELECT n.id, n.title, n.body, n.level, n.created_at, n.created_by, r.read_at
+		FROM `+TBL_NOTES+` n
+		JOIN `+TBL_NOTE_RECS+` r ON r.nid = n.id
+		WHERE r.uid = ?
+		AND r.dismissed_at = 0
+		AND ( ? = FALSE OR r.read_at = 0 )
+		ORDER BY n.id DESC
+		`,
+		uid,
+		unreadOnly,
+	)
+	err = MDB.Scanner(qry, &notes)
+	return
+}
+
+/* SETS read_at OR dismissed_at FOR GIVEN USER'S COPY OF A NOTIFICATION */
+func MarkNotification(nid, uid int64, col string) (err error) {
+	if col != "read_at" && col != "dismissed_at" {
+		return fmt.Errorf("invalid notification column: %s", col)
+	}
+
+	res := MDB.Exec(`
+		UPDATE `+TBL_NOTE_RECS+`
+		SET `+col+` = ?, updated_at = ?, updated_by = ?
+		WHERE nid = ? AND uid = ?
+		`,
+		time.Now().UTC().UnixMilli(),
+		time.Now().UTC().UnixMilli(),
+		uid,
+		nid,
+		uid,
+	)
+	if res.Error != nil {
+		return fmt.Errorf("%s: %s", NOTE_WRITE_ERR, res.Error.Error())
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("notification with id %d does not exist", nid)
+	}
+	return
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"fmt"
+)
+
+const PROCESS_WRITE_ERR = "error writing process record to main database"
+
+func GetProcessList(gid int64) (procs []Process, err error) {
+	qry := MDB.Raw(`
+		SELECT *
+		FROM `+TBL_PROCS+`
+		WHERE ( ? = 0 OR gid = ? )
+		AND deleted_at = 0
+		ORDER BY start DESC
+		`,
+		gid, gid,
+	)
+	err = MDB.Scanner(qry, &procs)
+	return
+}
+func GetProcessByID(id int64) (proc Process, err error) {
+
+	qry := MDB.Raw(`
+		SELECT * 
+		FROM `+TBL_PROCS+`
+		WHERE id = ?
+		AND deleted_at = 0
+		`,
+		id,
+	)
+
+	if err = MDB.Scanner(qry, &proc); err != nil {
+		return
+	}
+
+	if proc.ID == 0 {
+		err = fmt.Errorf("process with id %d does not exist", id)
+		return
+	}
+
+	return
+}
+
+func (proc *Process) Create(uid int64) (err error) {
+	proc.CreatedBy = uid
+	proc.UpdatedBy = uid
+	if res := MDB.Create(proc); res.Error != nil {
+		err = fmt.Errorf("%s: %s", PROCESS_WRITE_ERR, res.Error.Error())
+	}
+	return
+}
+
+func (proc *Process) Update(uid int64) (err error) {
+	proc.UpdatedBy = uid
+	if res := MDB.Save(proc); res.Error != nil {
+		err = fmt.Errorf("%s: %s", PROCESS_WRITE_ERR, res.Error.Error())
+	}
+	return
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"fmt"
+)
+
+const SCORE_WRITE_ERR = "error writing score rule set to main database"
+
+/* NEWEST VERSION FIRST */
+func GetScoreRuleSetListByVariate(vid int64) (sets []ScoreRuleSet, err error) {
+	qry := MDB.Raw(`
+		SELECT *
+		FROM `+TBL_SCORES+`
+		WHERE vid = ?
+		AND deleted_at = 0
+		ORDER BY version DESC
+		`,
+		vid,
+	)
+	err = MDB.Scanner(qry, &sets)
+	return
+}
+
+/* THE VARIATE'S CURRENT RULE SET; nil IF IT HAS NONE */
+func GetLatestScoreRuleSet(vid int64) (rs *ScoreRuleSet, err error) {
+	sets := []ScoreRuleSet{}
+	qry := MDB.Raw(`
+		SELECT *
+		FROM `+TBL_SCORES+`
+		WHERE vid = ?
+		AND deleted_at = 0
+		ORDER BY version DESC
+		LIMIT 1
+		`,
+		vid,
+	)
+	if err = MDB.Scanner(qry, &sets); err != nil || len(sets) == 0 {
+		return
+	}
+	rs = &sets[0]
+	return
+}
+
+/* WRITES rs AS THE VARIATE'S NEXT VERSION; THE UNIQUE (vid, version) INDEX REJECTS A CONCURRENT WRITER */
+func (rs *ScoreRuleSet) Create(uid int64) (err error) {
+
+	last := struct{ Version int }{}
+	qry := MDB.Raw(`
+		SELECT COALESCE(MAX(version), 0) AS version
+		FROM `+TBL_SCORES+`
+		WHERE vid = ?
+		`,
+		rs.VID,
+	)
+	if err = MDB.Scanner(qry, &last); err != nil {
+		return fmt.Errorf("%s: %s", SCORE_WRITE_ERR, err.Error())
+	}
+
+	rs.Version = last.Version + 1
+	rs.CreatedBy = uid
+	rs.UpdatedBy = uid
+	if res := MDB.Create(rs); res.Error != nil {
+		err = fmt.Errorf("%s: %s", SCORE_WRITE_ERR, res.Error.Error())
+	}
+	return
+}
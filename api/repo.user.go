@@ -74,6 +74,7 @@ func (usr *User) UpdateUser(ussn UserSession) (err error) {
 	orgUser.Role = usr.Role
 	orgUser.Email = usr.Email
 	orgUser.Name = usr.Name
+	orgUser.Org = usr.Org
 	orgUser.UpdatedBy = ussn.USR.ID
 	if res := MDB.Save(&orgUser); res.Error != nil {
 		return fmt.Errorf("%s: %s", USER_WRITE_ERR, res.Error.Error())
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"fmt"
+
+	"jaQC-Go-API/utils"
+)
+
+const VARIATE_WRITE_ERR = "error writing variate record to main database"
+
+func GetVariateListByProcess(pid int64) (vrts []Variate, err error) {
+	qry := MDB.Raw(`
+		SELECT *
+		FROM `+TBL_VARS+`
+		WHERE pid = ?
+		AND deleted_at = 0
+		ORDER BY id
+		`,
+		pid,
+	)
+	err = MDB.Scanner(qry, &vrts)
+	return
+}
+func GetVariateByID(id int64) (vrt Variate, err error) {
+
+	qry := MDB.Raw(`
+		SELECT * 
+		FROM `+TBL_VARS+`
+		WHERE id = ?
+		AND deleted_at = 0
+		`,
+		id,
+	)
+
+	if err = MDB.Scanner(qry, &vrt); err != nil {
+		return
+	}
+
+	if vrt.ID == 0 {
+		err = fmt.Errorf("variate with id %d does not exist", id)
+		return
+	}
+
+	return
+}
+
+/* VARIATES RECORDING channel IN PROCESSES THAT WRITE TO DATASET did */
+func GetVariateListByDatasetChannel(did int64, channel string) (vrts []Variate, err error) {
+	qry := MDB.Raw(`
+		SELECT v.*
+		FROM `+TBL_VARS+` v
+		JOIN `+TBL_PROCS+` p ON p.id = v.pid
+		WHERE p.did = ?
+		AND v.channel = ?
+		AND v.deleted_at = 0
+		AND p.deleted_at = 0
+		ORDER BY v.id
+		`,
+		did,
+		channel,
+	)
+	err = MDB.Scanner(qry, &vrts)
+	return
+}
+
+/* VARIATES RECORDING channel IN PROCESSES RUN ON GIZMO gid */
+func GetVariateListByGizmoChannel(gid int64, channel string) (vrts []Variate, err error) {
+	qry := MDB.Raw(`
+		SELECT v.*
+		FROM `+TBL_VARS+` v
+		JOIN `+TBL_PROCS+` p ON p.id = v.pid
+		WHERE p.gid = ?
+		AND v.channel = ?
+		AND v.deleted_at = 0
+		AND p.deleted_at = 0
+		ORDER BY v.id
+		`,
+		gid,
+		channel,
+	)
+	err = MDB.Scanner(qry, &vrts)
+	return
+}
+
+func (vrt *Variate) Create(uid int64) (err error) {
+	vrt.CreatedBy = uid
+	vrt.UpdatedBy = uid
+	if res := MDB.Create(vrt); res.Error != nil {
+		err = fmt.Errorf("%s: %s", VARIATE_WRITE_ERR, res.Error.Error())
+	}
+	return
+}
+
+func (vrt *Variate) Update(uid int64) (err error) {
+	vrt.UpdatedBy = uid
+	if res := MDB.Save(vrt); res.Error != nil {
+		err = fmt.Errorf("%s: %s", VARIATE_WRITE_ERR, res.Error.Error())
+	}
+	return
+}
+
+/* CALIBRATED SAMPLES FOR THE VARIATE OVER [ start, end ), LESS THE QUALITY FLAGS IT EXCLUDES; end == 0 MEANS OPEN ENDED */
+func (vrt *Variate) GetTSXY(start, end int64) (eng utils.TSXY, err error) {
+	return vrt.GetTSXYExcluding(start, end, vrt.QualityMask())
+}
+
+func (vrt *Variate) GetTSXYExcluding(start, end int64, exclude utils.QualityMask) (eng utils.TSXY, err error) {
+	eng, _, err = vrt.GetTSXYQuality(start, end, exclude)
+	return
+}
+
+/* CALIBRATED SAMPLES AND THEIR QUALITY FLAGS; quals[i] BELONGS TO eng.X[i] */
+func (vrt *Variate) GetTSXYQuality(start, end int64, exclude utils.QualityMask) (eng utils.TSXY, quals []uint8, err error) {
+
+	proc, err := GetProcessByID(vrt.PID)
+	if err != nil {
+		return
+	}
+
+	ds, err := GetDatasetByID(proc.DID)
+	if err != nil {
+		return
+	}
+
+	ddb, err := ds.Open()
+	if err != nil {
+		return
+	}
+
+	raw, quals, err := ddb.GetSamples(vrt.Channel, start, end, exclude)
+	if err != nil {
+		return
+	}
+
+	cals, err := GetCalibrationListByChannel(proc.GID, vrt.Channel)
+	if err != nil {
+		return
+	}
+
+	eng, _ = ApplyCalibrations(cals, raw)
+	return
+}
@@ -2,7 +2,7 @@ package main
 
 import (
 	"flag"
-	// "fmt"
+	"fmt"
 
 	/* /api/jaqc/update_web imports **********/
     "crypto/sha256"
@@ -15,6 +15,7 @@ import (
 
 	"github.com/gofiber/fiber/v2" // go get github.com/gofiber/fiber/v2
 	"github.com/gofiber/fiber/v2/log"
+	"github.com/gofiber/websocket/v2" // go get github.com/gofiber/websocket/v2
 
 	"jaQC-Go-API/utils"
 	"jaQC-Go-API/api"
@@ -95,6 +96,14 @@ func RegisterManifestRoute(app *fiber.App) {
 }
 /* END /api/jaqc/update_web stuff **********/
 
+/* FLAG DEFAULTS FROM THE ENVIRONMENT, e.g. JAQC_DB_DRIVER=postgres */
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
 func main() {
 	
 	/* CREATE GOFIBER APPLICATION */
@@ -110,15 +119,43 @@ func main() {
 
 	
 	/* CHECK COMMAND LINE ARGUMENTS  ~$ go run . --clean */
-	clean := flag.Bool("clean", false, "archive, drop and recreate database")
+	clean := flag.Bool("clean", false, "archive dataset files, migrate the main database down to nothing and back up")
+	migrateTo := flag.Int64("migrate-to", utils.MIGRATE_LATEST, "migrate the main database up or down to this schema version")
+	migrateDryRun := flag.Bool("migrate-dry-run", false, "run pending migrations in a rolled back transaction and exit")
+	dbDriver := flag.String("db-driver", envOr("JAQC_DB_DRIVER", utils.DB_DRIVER_SQLITE), "main database backend: sqlite | postgres")
+	dbDSN := flag.String("db-dsn", os.Getenv("JAQC_DB_DSN"), "postgres connection string (db-driver=postgres)")
 	flag.Parse() // log.Info("FLAG -> clean : ", *clean)
 
+	/* A DRY RUN MUST NOT TOUCH ANYTHING, AND CLEAN ARCHIVES BEFORE THE DATABASE IS EVEN OPENED */
+	if *clean && *migrateDryRun {
+		utils.LogFatal(fmt.Errorf("--clean cannot be combined with --migrate-dry-run"))
+	}
+
 
 	/* FILE SYSTEM */
 	if err := api.ConfigureFileSystem(*clean); err != nil {
 		utils.LogFatal(err)
 	}
 
+	/* MAIN DATABASE */
+	api.MAIN_DB_CONFIG.Driver = *dbDriver
+	api.MAIN_DB_CONFIG.DSN = *dbDSN
+	if err := api.ConfigureMainDatabase(api.MAIN_DB_CONFIG, *clean, *migrateTo, *migrateDryRun); err != nil {
+		utils.LogFatal(err)
+	}
+	defer api.MDB.Disconnect()
+
+	/* MIGRATION RUNS ARE ONE-SHOT; DON'T SERVE FROM A PARTIAL SCHEMA */
+	if *migrateDryRun || *migrateTo != utils.MIGRATE_LATEST {
+		return
+	}
+	defer api.CloseDatasetDatabases()
+	defer api.FlushClusterStreams(nil)
+
+	if err := api.FailInterruptedJobs(); err != nil {
+		utils.LogErr(err)
+	}
+
 	/* AUTH / SECURITY */
 	api.ConfigureCORS(
 		app,
@@ -136,6 +173,7 @@ func main() {
 		api.JWT_ACCESS_DURATION,
 		api.JWT_REFRESH_DURATION,
 	)
+	JWT_AUTH := api.JWT.Authenticate
 
 	/* EMAIL */
 	api.ConfigureEmail(
@@ -146,6 +184,74 @@ func main() {
 	)
 	
 	/* API END POINTS */
+	app.Get("/api/ws", JWT_AUTH, api.HandleUserSessionAuth, api.HandleWSUpgrade, websocket.New(api.HandleWSConnect))
+	app.Get("/api/events", JWT_AUTH, api.HandleUserSessionAuth, api.HandleSSEConnect)
+
+	app.Get("/api/jobs", JWT_AUTH, api.RoleCheckViewer, api.HandleGetJobList)
+	app.Get("/api/jobs/:id", JWT_AUTH, api.RoleCheckViewer, api.HandleGetJob)
+	app.Post("/api/jobs/:id/cancel", JWT_AUTH, api.RoleCheckViewer, api.HandleCancelJob)
+
+	app.Post("/api/notifications", JWT_AUTH, api.RoleCheckAdmin, api.HandleSendNotification)
+	app.Get("/api/notifications", JWT_AUTH, api.RoleCheckViewer, api.HandleGetNotificationList)
+	app.Post("/api/notifications/:id/read", JWT_AUTH, api.RoleCheckViewer, api.HandleReadNotification)
+	app.Delete("/api/notifications/:id", JWT_AUTH, api.RoleCheckViewer, api.HandleDismissNotification)
+
+	app.Get("/api/gizmos", JWT_AUTH, api.RoleCheckViewer, api.HandleGetGizmoList)
+	app.Get("/api/gizmos/:id", JWT_AUTH, api.RoleCheckViewer, api.HandleGetGizmo)
+	app.Post("/api/gizmos", JWT_AUTH, api.RoleCheckAdmin, api.HandleCreateGizmo)
+	app.Put("/api/gizmos/:id", JWT_AUTH, api.RoleCheckAdmin, api.HandleUpdateGizmo)
+	app.Delete("/api/gizmos/:id", JWT_AUTH, api.RoleCheckAdmin, api.HandleDeleteGizmo)
+
+	app.Get("/api/gizmos/:id/calibrations", JWT_AUTH, api.RoleCheckViewer, api.HandleGetGizmoCalibrationList)
+	app.Post("/api/gizmos/:id/calibrations", JWT_AUTH, api.RoleCheckOperator, api.HandleCreateCalibration)
+	app.Get("/api/calibrations/:id", JWT_AUTH, api.RoleCheckViewer, api.HandleGetCalibration)
+	app.Post("/api/calibrations/:id/correct", JWT_AUTH, api.RoleCheckOperator, api.HandleCorrectCalibration)
+	app.Post("/api/calibrations/:id/reprocess", JWT_AUTH, api.RoleCheckOperator, api.HandleReprocessCalibration)
+
+	app.Get("/api/datasets", JWT_AUTH, api.RoleCheckViewer, api.HandleGetDatasetList)
+	app.Get("/api/gizmos/:id/datasets", JWT_AUTH, api.RoleCheckViewer, api.HandleGetGizmoDatasetList)
+	app.Post("/api/gizmos/:id/datasets", JWT_AUTH, api.RoleCheckOperator, api.HandleCreateDataset)
+	app.Get("/api/datasets/:id", JWT_AUTH, api.RoleCheckViewer, api.HandleGetDataset)
+	app.Get("/api/datasets/:id/stats", JWT_AUTH, api.RoleCheckViewer, api.HandleGetDatasetStats)
+	app.Post("/api/datasets/:id/import", JWT_AUTH, api.RoleCheckOperator, api.HandleImportDataset)
+	app.Get("/api/datasets/:id/quality", JWT_AUTH, api.RoleCheckViewer, api.HandleGetDatasetQuality)
+	app.Put("/api/datasets/:id/quality", JWT_AUTH, api.RoleCheckOperator, api.HandleUpdateDatasetQuality)
+	app.Delete("/api/datasets/:id", JWT_AUTH, api.RoleCheckAdmin, api.HandleDeleteDataset)
+
+	app.Get("/api/processes", JWT_AUTH, api.RoleCheckViewer, api.HandleGetProcessList)
+	app.Post("/api/processes", JWT_AUTH, api.RoleCheckOperator, api.HandleCreateProcess)
+	app.Get("/api/processes/:id", JWT_AUTH, api.RoleCheckViewer, api.HandleGetProcess)
+	app.Put("/api/processes/:id", JWT_AUTH, api.RoleCheckOperator, api.HandleUpdateProcess)
+	app.Get("/api/processes/:id/variates", JWT_AUTH, api.RoleCheckViewer, api.HandleGetProcessVariateList)
+	app.Post("/api/processes/:id/variates", JWT_AUTH, api.RoleCheckOperator, api.HandleCreateVariate)
+	app.Get("/api/processes/:id/aggregates", JWT_AUTH, api.RoleCheckViewer, api.HandleGetProcessAggregateList)
+	app.Get("/api/processes/:id/correlations", JWT_AUTH, api.RoleCheckViewer, api.HandleGetCorrelationMatrix)
+	app.Post("/api/processes/:id/correlations", JWT_AUTH, api.RoleCheckOperator, api.HandleStartCorrelation)
+	app.Get("/api/processes/:id/export", JWT_AUTH, api.RoleCheckViewer, api.HandleExportProcessSamples)
+	app.Get("/api/processes/:id/resample", JWT_AUTH, api.RoleCheckViewer, api.HandleGetProcessResample)
+	app.Get("/api/aggregates", JWT_AUTH, api.RoleCheckViewer, api.HandleGetAggregateList)
+	app.Get("/api/aggregates/export", JWT_AUTH, api.RoleCheckViewer, api.HandleExportAggregates)
+	app.Get("/api/variates/:id", JWT_AUTH, api.RoleCheckViewer, api.HandleGetVariate)
+	app.Get("/api/variates/:id/aggregates", JWT_AUTH, api.RoleCheckViewer, api.HandleGetVariateAggregateList)
+	app.Get("/api/variates/:id/series", JWT_AUTH, api.RoleCheckViewer, api.HandleGetVariateSeries)
+	app.Get("/api/variates/:id/regression", JWT_AUTH, api.RoleCheckViewer, api.HandleGetVariateRegression)
+	app.Get("/api/variates/:id/spectrum", JWT_AUTH, api.RoleCheckViewer, api.HandleGetVariateSpectrum)
+	app.Get("/api/variates/:id/changepoints", JWT_AUTH, api.RoleCheckViewer, api.HandleGetVariateChangePoints)
+	app.Put("/api/variates/:id/cluster", JWT_AUTH, api.RoleCheckOperator, api.HandleUpdateVariateCluster)
+	app.Put("/api/variates/:id/filters", JWT_AUTH, api.RoleCheckOperator, api.HandleUpdateVariateFilters)
+	app.Post("/api/variates/:id/clusters", JWT_AUTH, api.RoleCheckOperator, api.HandleStartClustering)
+	app.Get("/api/variates/:id/spc", JWT_AUTH, api.RoleCheckViewer, api.HandleGetVariateSPC)
+	app.Put("/api/variates/:id/spc", JWT_AUTH, api.RoleCheckOperator, api.HandleUpdateVariateSPC)
+	app.Post("/api/variates/:id/spc", JWT_AUTH, api.RoleCheckOperator, api.HandleStartSPC)
+	app.Get("/api/variates/:id/outliers", JWT_AUTH, api.RoleCheckViewer, api.HandleGetVariateOutliers)
+	app.Post("/api/variates/:id/outliers", JWT_AUTH, api.RoleCheckOperator, api.HandleStartOutlierFlagging)
+	app.Put("/api/variates/:id/exclude", JWT_AUTH, api.RoleCheckOperator, api.HandleUpdateVariateExclude)
+	app.Put("/api/variates/:id/spec", JWT_AUTH, api.RoleCheckOperator, api.HandleUpdateVariateSpec)
+	app.Get("/api/variates/:id/capability", JWT_AUTH, api.RoleCheckViewer, api.HandleGetVariateCapabilityList)
+	app.Post("/api/variates/:id/capability", JWT_AUTH, api.RoleCheckOperator, api.HandleCreateVariateCapability)
+	app.Get("/api/variates/:id/score-rules", JWT_AUTH, api.RoleCheckViewer, api.HandleGetVariateScoreRules)
+	app.Post("/api/variates/:id/score-rules", JWT_AUTH, api.RoleCheckOperator, api.HandleCreateVariateScoreRules)
+	app.Post("/api/variates/:id/score", JWT_AUTH, api.RoleCheckOperator, api.HandleStartScoring)
 
 
 
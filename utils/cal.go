@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+)
+
+/* EVALUATES c0 + c1*x + c2*x^2 ... USING HORNER'S METHOD */
+func Polynomial(coefs []float64, x float64) (y float64) {
+	for i := len(coefs) - 1; i >= 0; i-- {
+		y = y*x + coefs[i]
+	}
+	return
+}
+
+/* VALIDATES A LOOKUP TABLE; xs MUST BE STRICTLY INCREASING */
+func ValidateLookupTable(xs, ys []float64) (err error) {
+	if len(xs) < 2 {
+		return fmt.Errorf("lookup table needs at least 2 points")
+	}
+	if len(xs) != len(ys) {
+		return fmt.Errorf("lookup table x / y length mismatch: %d / %d", len(xs), len(ys))
+	}
+	for i := 1; i < len(xs); i++ {
+		if xs[i] <= xs[i-1] {
+			return fmt.Errorf("lookup table x values must be strictly increasing")
+		}
+	}
+	return
+}
+
+/* LINEAR INTERPOLATION IN A LOOKUP TABLE; EXTRAPOLATES FROM THE END SEGMENTS */
+func InterpolateTable(xs, ys []float64, x float64) float64 {
+	n := len(xs)
+	i := sort.SearchFloat64s(xs, x)
+	switch {
+	case i <= 0:
+		i = 1
+	case i >= n:
+		i = n - 1
+	}
+	x0, x1 := xs[i-1], xs[i]
+	y0, y1 := ys[i-1], ys[i]
+	return y0 + (x-x0)*(y1-y0)/(x1-x0)
+}
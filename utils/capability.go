@@ -0,0 +1,240 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+/* DEFAULTS FOR ZERO CapabilityConfig FIELDS */
+const CAPABILITY_SUBGROUP int = SPC_SUBGROUP_SIZE
+const CAPABILITY_CONFIDENCE float64 = 0.95
+
+/* ANDERSON-DARLING NEEDS AT LEAST 8 */
+const CAPABILITY_MIN_SAMPLES int = 8
+
+/* NORMALITY IS REJECTED BELOW THIS ANDERSON-DARLING p-VALUE */
+const AD_ALPHA float64 = 0.05
+
+/* SPECIFICATION LIMITS; A ONE SIDED SPEC LEAVES THE OTHER LIMIT nil */
+type Spec struct {
+	LSL    *float64 `gorm:"column:lsl" json:"lsl"`
+	USL    *float64 `gorm:"column:usl" json:"usl"`
+	Target *float64 `gorm:"column:target" json:"target"`
+}
+
+func (spec *Spec) Set() bool { return spec.LSL != nil || spec.USL != nil }
+
+func (spec *Spec) Validate() (err error) {
+	for _, v := range []*float64{spec.LSL, spec.USL, spec.Target} {
+		if v != nil && (math.IsNaN(*v) || math.IsInf(*v, 0)) {
+			return fmt.Errorf("spec limits must be finite")
+		}
+	}
+	switch {
+	case spec.LSL != nil && spec.USL != nil && *spec.USL <= *spec.LSL:
+		err = fmt.Errorf("spec usl must be above lsl")
+	case spec.Target != nil && spec.LSL != nil && *spec.Target < *spec.LSL:
+		err = fmt.Errorf("spec target is below lsl")
+	case spec.Target != nil && spec.USL != nil && *spec.Target > *spec.USL:
+		err = fmt.Errorf("spec target is above usl")
+	}
+	return
+}
+
+/*
+Subgroup IS THE RATIONAL SUBGROUP SIZE THE WITHIN ( SHORT TERM ) SIGMA COMES FROM:
+1 USES MOVING RANGES, 2 TO 25 AVERAGE RANGES, LARGER AVERAGE STD DEVS; SEE SPCEstimate
+Confidence IS THE TWO SIDED LEVEL OF THE INTERVALS
+*/
+type CapabilityConfig struct {
+	Subgroup   int     `json:"subgroup"`
+	Confidence float64 `json:"confidence"`
+}
+
+func (cfg *CapabilityConfig) Defaults() {
+	if cfg.Subgroup == 0 {
+		cfg.Subgroup = CAPABILITY_SUBGROUP
+	}
+	if cfg.Confidence == 0 {
+		cfg.Confidence = CAPABILITY_CONFIDENCE
+	}
+}
+
+func (cfg *CapabilityConfig) Validate() (err error) {
+	switch {
+	case cfg.Subgroup < 0:
+		err = fmt.Errorf("capability subgroup is negative")
+	case cfg.Confidence < 0 || cfg.Confidence >= 1:
+		err = fmt.Errorf("capability confidence must be between 0 and 1")
+	}
+	return
+}
+
+func (cfg *CapabilityConfig) chart() string {
+	switch {
+	case cfg.Subgroup == 1:
+		return SPC_CHART_IMR
+	case cfg.Subgroup > len(SPC_D2)+1:
+		return SPC_CHART_XBAR_S
+	}
+	return SPC_CHART_XBAR_R
+}
+
+/*
+Cp, Cpk USE THE WITHIN SIGMA; Pp, Ppk, Cpm THE OVERALL SIGMA
+AN INDEX THE SPEC CANNOT SUPPORT IS nil: Cp AND Pp NEED BOTH LIMITS, Cpm ALSO A TARGET
+*/
+type Capability struct {
+	N            int64   `json:"n"`
+	Mean         float64 `json:"mean"`
+	SigmaWithin  float64 `json:"sigma_within"`
+	SigmaOverall float64 `json:"sigma_overall"`
+
+	Cp    *float64 `gorm:"column:cp" json:"cp"`
+	CpLo  *float64 `gorm:"column:cp_lo" json:"cp_lo"`
+	CpHi  *float64 `gorm:"column:cp_hi" json:"cp_hi"`
+	Cpk   *float64 `gorm:"column:cpk" json:"cpk"`
+	CpkLo *float64 `gorm:"column:cpk_lo" json:"cpk_lo"`
+	CpkHi *float64 `gorm:"column:cpk_hi" json:"cpk_hi"`
+	Pp    *float64 `gorm:"column:pp" json:"pp"`
+	PpLo  *float64 `gorm:"column:pp_lo" json:"pp_lo"`
+	PpHi  *float64 `gorm:"column:pp_hi" json:"pp_hi"`
+	Ppk   *float64 `gorm:"column:ppk" json:"ppk"`
+	PpkLo *float64 `gorm:"column:ppk_lo" json:"ppk_lo"`
+	PpkHi *float64 `gorm:"column:ppk_hi" json:"ppk_hi"`
+	Cpm   *float64 `gorm:"column:cpm" json:"cpm"`
+	CpmLo *float64 `gorm:"column:cpm_lo" json:"cpm_lo"`
+	CpmHi *float64 `gorm:"column:cpm_hi" json:"cpm_hi"`
+
+	AD       float64 `gorm:"column:ad" json:"ad"` // ANDERSON-DARLING A*2, ADJUSTED FOR n
+	ADPValue float64 `gorm:"column:ad_p_value" json:"ad_p_value"`
+	Normal   bool    `json:"normal"` // ADPValue >= AD_ALPHA; THE INDICES ASSUME NORMALITY
+}
+
+/* CAPABILITY OF THE SAMPLES IN ts AGAINST spec; ts IN TIME ORDER SO SUBGROUPS ARE CONSECUTIVE SAMPLES */
+func ComputeCapability(ts TSXY, spec Spec, cfg CapabilityConfig) (pcap Capability, err error) {
+
+	if !spec.Set() {
+		err = fmt.Errorf("capability needs an lsl or usl")
+		return
+	}
+	if err = spec.Validate(); err != nil {
+		return
+	}
+	n := len(ts.Y)
+	if n < CAPABILITY_MIN_SAMPLES {
+		err = fmt.Errorf("capability needs at least %d samples, has %d", CAPABILITY_MIN_SAMPLES, n)
+		return
+	}
+
+	ys := make([]float64, n)
+	for i, y := range ts.Y {
+		ys[i] = float64(y)
+	}
+	mean, variance := MeanVariance(ts.Y)
+	pcap.N = int64(n)
+	pcap.Mean = mean
+	pcap.SigmaOverall = math.Sqrt(variance)
+	if pcap.SigmaOverall == 0 {
+		err = fmt.Errorf("capability samples have no variation")
+		return
+	}
+
+	lim, err := SPCEstimate(SPCConfig{Chart: cfg.chart()}, Subgroups(ts, cfg.Subgroup))
+	if err != nil {
+		err = fmt.Errorf("capability within sigma: %s", err.Error())
+		return
+	}
+	pcap.SigmaWithin = lim.Sigma
+
+	/* DEGREES OF FREEDOM TAKEN AS n - 1 FOR BOTH SIGMAS */
+	df := float64(n - 1)
+	alpha := 1 - cfg.Confidence
+	z := distuv.UnitNormal.Quantile(1 - alpha/2)
+	chi := distuv.ChiSquared{K: df}
+	chiLo, chiHi := math.Sqrt(chi.Quantile(alpha/2)/df), math.Sqrt(chi.Quantile(1-alpha/2)/df)
+
+	/* CHI-SQUARE INTERVAL FOR Cp AND Pp */
+	potential := func(sigma float64) (v, lo, hi *float64) {
+		if spec.LSL == nil || spec.USL == nil {
+			return
+		}
+		c := (*spec.USL - *spec.LSL) / (6 * sigma)
+		return ptr(c), ptr(c * chiLo), ptr(c * chiHi)
+	}
+	/* BISSELL'S NORMAL APPROXIMATION FOR Cpk AND Ppk */
+	actual := func(sigma float64) (v, lo, hi *float64) {
+		c := math.Inf(1)
+		if spec.USL != nil {
+			c = (*spec.USL - mean) / (3 * sigma)
+		}
+		if spec.LSL != nil {
+			c = math.Min(c, (mean-*spec.LSL)/(3*sigma))
+		}
+		half := z * math.Sqrt(1/(9*float64(n))+c*c/(2*df))
+		return ptr(c), ptr(c - half), ptr(c + half)
+	}
+
+	pcap.Cp, pcap.CpLo, pcap.CpHi = potential(pcap.SigmaWithin)
+	pcap.Cpk, pcap.CpkLo, pcap.CpkHi = actual(pcap.SigmaWithin)
+	pcap.Pp, pcap.PpLo, pcap.PpHi = potential(pcap.SigmaOverall)
+	pcap.Ppk, pcap.PpkLo, pcap.PpkHi = actual(pcap.SigmaOverall)
+
+	/* TAGUCHI, WITH BOYLES' CHI-SQUARE INTERVAL */
+	if spec.LSL != nil && spec.USL != nil && spec.Target != nil {
+		off := (mean - *spec.Target) / pcap.SigmaOverall
+		c := (*spec.USL - *spec.LSL) / (6 * math.Sqrt(variance+(mean-*spec.Target)*(mean-*spec.Target)))
+		nu := float64(n) * (1 + off*off) * (1 + off*off) / (1 + 2*off*off)
+		chim := distuv.ChiSquared{K: nu}
+		pcap.Cpm = ptr(c)
+		pcap.CpmLo = ptr(c * math.Sqrt(chim.Quantile(alpha/2)/float64(n)))
+		pcap.CpmHi = ptr(c * math.Sqrt(chim.Quantile(1-alpha/2)/float64(n)))
+	}
+
+	pcap.AD, pcap.ADPValue = AndersonDarling(ys, mean, pcap.SigmaOverall)
+	pcap.Normal = pcap.ADPValue >= AD_ALPHA
+	return
+}
+
+/*
+ANDERSON-DARLING TEST OF ys AGAINST A NORMAL WITH ESTIMATED mean AND sigma
+RETURNS STEPHENS' ADJUSTED A*2 AND D'AGOSTINO AND STEPHENS' p-VALUE; SORTS ys IN PLACE
+*/
+func AndersonDarling(ys []float64, mean, sigma float64) (a2, p float64) {
+
+	sort.Float64s(ys)
+	n := float64(len(ys))
+
+	/* KEEPS ln FINITE FOR POINTS FAR IN THE TAILS */
+	cdf := func(y float64) float64 {
+		f := distuv.UnitNormal.CDF((y - mean) / sigma)
+		return math.Min(math.Max(f, 1e-300), 1-1e-16)
+	}
+
+	var sum float64
+	for i := range ys {
+		sum += float64(2*i+1) * (math.Log(cdf(ys[i])) + math.Log(1-cdf(ys[len(ys)-1-i])))
+	}
+	a2 = (-n - sum/n) * (1 + 0.75/n + 2.25/(n*n))
+
+	switch {
+	case a2 >= 150:
+		/* THE FIRST FIT TURNS BACK UP NEAR ITS VERTEX, a2 = 153; p IS ALREADY BELOW 1e-300 */
+		p = 0
+	case a2 >= 0.6:
+		p = math.Exp(1.2937 - 5.709*a2 + 0.0186*a2*a2)
+	case a2 >= 0.34:
+		p = math.Exp(0.9177 - 4.279*a2 - 1.38*a2*a2)
+	case a2 >= 0.2:
+		p = 1 - math.Exp(-8.318+42.796*a2-59.938*a2*a2)
+	default:
+		p = 1 - math.Exp(-13.436+101.14*a2-223.73*a2*a2)
+	}
+	p = math.Min(math.Max(p, 0), 1)
+	return
+}
+
+func ptr(v float64) *float64 { return &v }
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+/* n SAMPLES 1 ms APART FROM gen */
+func genTS(n int, gen func(i int) float64) (ts TSXY) {
+	for i := 0; i < n; i++ {
+		ts.X = append(ts.X, int64(i))
+		ts.Y = append(ts.Y, float32(gen(i)))
+	}
+	return
+}
+
+func TestCapability(t *testing.T) {
+
+	/* MEAN 10, SIGMA 1, SPEC 10 +/- 3 SIGMA: EVERY INDEX IS ABOUT 1 */
+	rnd := rand.New(rand.NewSource(1))
+	normal := genTS(2000, func(int) float64 { return 10 + rnd.NormFloat64() })
+	uniform := genTS(2000, func(i int) float64 { return float64(i % 100) })
+
+	tests := []struct {
+		name   string
+		ts     TSXY
+		spec   Spec
+		cp     bool // Cp AND Pp ARE SET
+		cpm    bool
+		normal bool
+	}{
+		{"two sided with target", normal, Spec{LSL: ptr(7.0), USL: ptr(13.0), Target: ptr(10.0)}, true, true, true},
+		{"two sided", normal, Spec{LSL: ptr(7.0), USL: ptr(13.0)}, true, false, true},
+		{"upper only", normal, Spec{USL: ptr(13.0)}, false, false, true},
+		{"not normal", uniform, Spec{LSL: ptr(-49.5), USL: ptr(148.5)}, true, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := CapabilityConfig{}
+			cfg.Defaults()
+			pcap, err := ComputeCapability(tt.ts, tt.spec, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (pcap.Cp != nil) != tt.cp || (pcap.Pp != nil) != tt.cp || (pcap.Cpm != nil) != tt.cpm {
+				t.Fatalf("cp %v, pp %v, cpm %v", pcap.Cp, pcap.Pp, pcap.Cpm)
+			}
+			if pcap.Normal != tt.normal {
+				t.Fatalf("normal %t, ad p-value %g", pcap.Normal, pcap.ADPValue)
+			}
+			if !tt.normal {
+				return
+			}
+			for _, idx := range [][3]*float64{
+				{pcap.Cp, pcap.CpLo, pcap.CpHi},
+				{pcap.Cpk, pcap.CpkLo, pcap.CpkHi},
+				{pcap.Pp, pcap.PpLo, pcap.PpHi},
+				{pcap.Ppk, pcap.PpkLo, pcap.PpkHi},
+				{pcap.Cpm, pcap.CpmLo, pcap.CpmHi},
+			} {
+				if idx[0] == nil {
+					continue
+				}
+				if math.Abs(*idx[0]-1) > 0.1 || !(*idx[1] < *idx[0] && *idx[0] < *idx[2]) {
+					t.Fatalf("index %g outside [ %g, %g ] or far from 1", *idx[0], *idx[1], *idx[2])
+				}
+			}
+		})
+	}
+}
+
+func TestCapabilityErrors(t *testing.T) {
+	ramp := genTS(20, func(i int) float64 { return float64(i) })
+	tests := []struct {
+		name string
+		ts   TSXY
+		spec Spec
+	}{
+		{"no limits", ramp, Spec{}},
+		{"usl below lsl", ramp, Spec{LSL: ptr(5.0), USL: ptr(1.0)}},
+		{"too few samples", genTS(CAPABILITY_MIN_SAMPLES-1, func(i int) float64 { return float64(i) }), Spec{USL: ptr(1.0)}},
+		{"no variation", genTS(20, func(int) float64 { return 3 }), Spec{USL: ptr(5.0)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ComputeCapability(tt.ts, tt.spec, CapabilityConfig{Subgroup: 1, Confidence: 0.95}); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
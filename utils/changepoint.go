@@ -0,0 +1,226 @@
+package utils
+
+import (
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+/* IN-CONTROL RUN LENGTH IN THE TENS OF THOUSANDS OF SAMPLES; SHIFTS OF 2 STD DEVS AND UP ARE STILL CAUGHT WITHIN A FEW */
+const CUSUM_THRESHOLD float64 = 8 // h, IN STD DEVS
+const CUSUM_DRIFT float64 = 1     // k, IN STD DEVS
+const CHANGE_MIN_SIZE int = 5
+
+/* THE FIRST SAMPLE OF A NEW SEGMENT */
+type ChangePoint struct {
+	Index      int     `json:"index"`
+	X          int64   `json:"x"`
+	Confidence float64 `json:"confidence"` // 1 - p OF A WELCH t-TEST BETWEEN THE SEGMENTS EITHER SIDE
+}
+
+/*
+SELF-STARTING TWO SIDED CUSUM ON ROBUSTLY STANDARDIZED Y; EACH SAMPLE IS COMPARED WITH THE MEAN
+OF ITS SEGMENT SO FAR, AND A CHANGE IS PLACED WHERE THE ALARMING SUM LAST LEFT ZERO
+threshold ( h ) AND drift ( k ) ARE IN STD DEVS; ZERO TAKES THE DEFAULTS
+*/
+func CUSUM(ts TSXY, threshold, drift float64, minSize int) (cps []ChangePoint) {
+
+	if threshold <= 0 {
+		threshold = CUSUM_THRESHOLD
+	}
+	if drift <= 0 {
+		drift = CUSUM_DRIFT
+	}
+	if minSize < 2 {
+		minSize = CHANGE_MIN_SIZE
+	}
+
+	n := len(ts.Y)
+	sigma := NoiseSigma(ts.Y)
+	if n < 2*minSize || sigma == 0 {
+		return
+	}
+
+	idx := []int{}
+	seg := 0
+	for seg+minSize < n {
+
+		var sum float64
+		for _, y := range ts.Y[seg : seg+minSize] {
+			sum += float64(y)
+		}
+
+		var hi, lo float64
+		hiZero, loZero := seg+minSize-1, seg+minSize-1
+		next := -1
+		for i := seg + minSize; i < n; i++ {
+			mu := sum / float64(i-seg)
+			z := (float64(ts.Y[i]) - mu) / sigma
+			if hi = math.Max(0, hi+z-drift); hi == 0 {
+				hiZero = i
+			}
+			if lo = math.Max(0, lo-z-drift); lo == 0 {
+				loZero = i
+			}
+			if hi > threshold {
+				next = hiZero + 1
+				break
+			}
+			if lo > threshold {
+				next = loZero + 1
+				break
+			}
+			sum += float64(ts.Y[i])
+		}
+		if next < 0 || n-next < minSize {
+			break
+		}
+		if next-seg < minSize {
+			next = seg + minSize
+		}
+		idx = append(idx, next)
+		seg = next
+	}
+
+	return changePoints(ts, idx)
+}
+
+/*
+PRUNED EXACT LINEAR TIME SEGMENTATION FOR CHANGES IN MEAN ( KILLICK ET AL. 2012 )
+COST IS THE SQUARED ERROR OF ROBUSTLY STANDARDIZED Y; penalty <= 0 TAKES 2 ln(n)
+*/
+func PELT(ts TSXY, penalty float64, minSize int) (cps []ChangePoint) {
+
+	if minSize < 2 {
+		minSize = CHANGE_MIN_SIZE
+	}
+
+	n := len(ts.Y)
+	sigma := NoiseSigma(ts.Y)
+	if n < 2*minSize || sigma == 0 {
+		return
+	}
+	if penalty <= 0 {
+		penalty = 2 * math.Log(float64(n))
+	}
+
+	/* PREFIX SUMS OF z AND z^2 MAKE EACH SEGMENT COST O(1) */
+	s1 := make([]float64, n+1)
+	s2 := make([]float64, n+1)
+	for i, y := range ts.Y {
+		z := float64(y) / sigma
+		s1[i+1] = s1[i] + z
+		s2[i+1] = s2[i] + z*z
+	}
+	cost := func(s, t int) float64 {
+		sum := s1[t] - s1[s]
+		return s2[t] - s2[s] - sum*sum/float64(t-s)
+	}
+
+	F := make([]float64, n+1)
+	last := make([]int, n+1)
+	F[0] = -penalty
+	R := []int{0}
+
+	for t := minSize; t <= n; t++ {
+
+		F[t] = math.Inf(1)
+		for _, s := range R {
+			if t-s < minSize {
+				continue
+			}
+			if v := F[s] + cost(s, t) + penalty; v < F[t] {
+				F[t], last[t] = v, s
+			}
+		}
+
+		/* DROP STARTS THAT CAN NEVER BE OPTIMAL AGAIN */
+		keep := R[:0]
+		for _, s := range R {
+			if t-s < minSize || F[s]+cost(s, t) <= F[t] {
+				keep = append(keep, s)
+			}
+		}
+		R = append(keep, t)
+	}
+
+	idx := []int{}
+	for t := last[n]; t > 0; t = last[t] {
+		idx = append(idx, t)
+	}
+	sort.Ints(idx)
+
+	return changePoints(ts, idx)
+}
+
+/* STD DEV OF THE NOISE FROM THE MAD OF FIRST DIFFERENCES, SO STEPS DON'T INFLATE IT */
+func NoiseSigma(ys []float32) float64 {
+	if len(ys) < 2 {
+		return 0
+	}
+	diffs := make([]float64, len(ys)-1)
+	for i := 1; i < len(ys); i++ {
+		diffs[i-1] = math.Abs(float64(ys[i]) - float64(ys[i-1]))
+	}
+	sort.Float64s(diffs)
+	mad := stat.Quantile(0.5, stat.Empirical, diffs, nil)
+	if mad == 0 {
+		/* QUANTIZED DATA; FALL BACK TO THE MEAN ABSOLUTE DIFFERENCE */
+		mad = stat.Mean(diffs, nil) * 0.8453
+	}
+	return mad / (0.6745 * math.Sqrt2)
+}
+
+/* ATTACHES X AND A CONFIDENCE TO EACH BOUNDARY INDEX */
+func changePoints(ts TSXY, idx []int) (cps []ChangePoint) {
+	for i, b := range idx {
+		prev, next := 0, len(ts.Y)
+		if i > 0 {
+			prev = idx[i-1]
+		}
+		if i < len(idx)-1 {
+			next = idx[i+1]
+		}
+		conf := 1 - WelchPValue(ts.Y[prev:b], ts.Y[b:next])
+		if math.IsNaN(conf) {
+			conf = 0
+		}
+		cps = append(cps, ChangePoint{Index: b, X: ts.X[b], Confidence: conf})
+	}
+	return
+}
+
+/* TWO SIDED p-VALUE FOR A DIFFERENCE IN MEANS WITHOUT ASSUMING EQUAL VARIANCE */
+func WelchPValue(as, bs []float32) float64 {
+
+	na, nb := float64(len(as)), float64(len(bs))
+	if na < 2 || nb < 2 {
+		return math.NaN()
+	}
+
+	ma, va := MeanVariance(as)
+	mb, vb := MeanVariance(bs)
+	sa, sb := va/na, vb/nb
+	if sa+sb == 0 {
+		if ma == mb {
+			return 1
+		}
+		return 0
+	}
+
+	t := (ma - mb) / math.Sqrt(sa+sb)
+	df := (sa + sb) * (sa + sb) / (sa*sa/(na-1) + sb*sb/(nb-1))
+	dist := distuv.StudentsT{Mu: 0, Sigma: 1, Nu: df}
+	return 2 * dist.Survival(math.Abs(t))
+}
+
+/* SAMPLE MEAN AND VARIANCE IN float64 */
+func MeanVariance(ys []float32) (mean, variance float64) {
+	xs := make([]float64, len(ys))
+	for i, y := range ys {
+		xs[i] = float64(y)
+	}
+	return stat.MeanVariance(xs, nil)
+}
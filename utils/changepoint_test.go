@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestChangePoints(t *testing.T) {
+
+	/* UNIT NOISE WITH A 5 SIGMA STEP AT step */
+	const n, step = 20000, 12000
+	rnd := rand.New(rand.NewSource(1))
+	stepped := genTS(n, func(i int) float64 {
+		if i >= step {
+			return 5 + rnd.NormFloat64()
+		}
+		return rnd.NormFloat64()
+	})
+	flat := genTS(n/4, func(int) float64 { return rnd.NormFloat64() })
+
+	tests := []struct {
+		name string
+		find func(ts TSXY) []ChangePoint
+		ts   TSXY
+		want []int
+	}{
+		{"pelt step", func(ts TSXY) []ChangePoint { return PELT(ts, 0, 0) }, stepped, []int{step}},
+		{"cusum step", func(ts TSXY) []ChangePoint { return CUSUM(ts, 0, 0, 0) }, stepped, []int{step}},
+		{"pelt flat", func(ts TSXY) []ChangePoint { return PELT(ts, 0, 0) }, flat, nil},
+		{"cusum flat", func(ts TSXY) []ChangePoint { return CUSUM(ts, 0, 0, 0) }, flat, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cps := tt.find(tt.ts)
+			if len(cps) != len(tt.want) {
+				t.Fatalf("%d change points %v, want %v", len(cps), cps, tt.want)
+			}
+			for i, cp := range cps {
+				/* CUSUM PLACES THE CHANGE WHERE ITS SUM LAST LEFT ZERO, WHICH CAN LEAD THE STEP A LITTLE */
+				if d := cp.Index - tt.want[i]; d < -3 || d > 3 || cp.X != tt.ts.X[cp.Index] {
+					t.Fatalf("change point %v, want index %d", cp, tt.want[i])
+				}
+				if cp.Confidence < 0.99 {
+					t.Fatalf("confidence %g", cp.Confidence)
+				}
+			}
+		})
+	}
+}
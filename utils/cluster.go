@@ -0,0 +1,262 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+)
+
+const CLUSTER_METHOD_THRESHOLD string = "threshold"
+const CLUSTER_METHOD_CUSUM string = "cusum"
+const CLUSTER_METHOD_PELT string = "pelt"
+
+/*
+threshold: A CLUSTER GROWS WHILE ITS |SLOPE| AND DEVIATION STAY INSIDE THE LIMITS
+cusum / pelt: CLUSTERS ARE THE SEGMENTS BETWEEN CHANGE POINTS; MinSize IS THE SHORTEST SEGMENT
+*/
+type ClusterConfig struct {
+	Method   string  `json:"method"`    // threshold ( DEFAULT ) | cusum | pelt
+	MinSize  int     `json:"min_size"`  // SAMPLES BEFORE A REGION COUNTS AS A CLUSTER
+	MaxSize  int     `json:"max_size"`  // A CLUSTER THIS LONG IS CLOSED AND A NEW ONE STARTED; 0 IS UNLIMITED
+	MaxSlope float32 `json:"max_slope"` // Y UNITS PER SECOND; 0 IS UNLIMITED
+	MaxDevi  float32 `json:"max_devi"`  // STD DEV OF Y ABOUT THE CLUSTER MEAN
+	MaxGap   int64   `json:"max_gap"`   // ms; A LONGER GAP CLOSES THE CLUSTER; 0 IS UNLIMITED
+
+	Threshold float64 `json:"threshold"` // CUSUM ALARM LEVEL IN STD DEVS; 0 IS CUSUM_THRESHOLD
+	Drift     float64 `json:"drift"`     // CUSUM SLACK IN STD DEVS; 0 IS CUSUM_DRIFT
+	Penalty   float64 `json:"penalty"`   // PELT COST OF ONE MORE SEGMENT; 0 IS 2 ln(n)
+}
+
+func (cfg *ClusterConfig) ChangePoints() bool {
+	return cfg.Method == CLUSTER_METHOD_CUSUM || cfg.Method == CLUSTER_METHOD_PELT
+}
+
+func (cfg *ClusterConfig) Validate() (err error) {
+	switch cfg.Method {
+	case "", CLUSTER_METHOD_THRESHOLD, CLUSTER_METHOD_CUSUM, CLUSTER_METHOD_PELT:
+	default:
+		return fmt.Errorf("invalid cluster method: %s", cfg.Method)
+	}
+	switch {
+	case cfg.MinSize < 2:
+		err = fmt.Errorf("cluster min_size must be at least 2")
+	case cfg.MaxSize != 0 && cfg.MaxSize < cfg.MinSize:
+		err = fmt.Errorf("cluster max_size is below min_size")
+	case cfg.MaxSlope < 0:
+		err = fmt.Errorf("cluster max_slope is negative")
+	case cfg.MaxDevi < 0 || (cfg.MaxDevi == 0 && !cfg.ChangePoints()):
+		err = fmt.Errorf("cluster max_devi must be positive")
+	case cfg.Threshold < 0 || cfg.Drift < 0 || cfg.Penalty < 0:
+		err = fmt.Errorf("cluster threshold, drift and penalty must not be negative")
+	case cfg.MaxGap < 0:
+		err = fmt.Errorf("cluster max_gap is negative")
+	}
+	return
+}
+
+/* ONE CLOSED CLUSTER; Slope IS PER SECOND */
+type ClusterStats struct {
+	Start int64
+	End   int64
+	Size  int64
+	Min   float32
+	Max   float32
+	Mean  float32
+	Slope float32
+	Devi  float32
+}
+
+/* SEGMENTS A SERIES ONE SAMPLE AT A TIME; OnClose IS CALLED AS EACH CLUSTER ENDS */
+type Clusterer struct {
+	Config  ClusterConfig
+	OnClose func(cs ClusterStats)
+
+	buf TSXY
+
+	/* RUNNING SUMS OVER buf, RELATIVE TO ( x0, y0 ), SO EACH PUSH IS O(1) */
+	x0                       int64
+	y0                       float64
+	n, sx, sy, sxx, sxy, syy float64
+}
+
+/* x MUST INCREASE; A STEP BACK IS TREATED LIKE A GAP */
+func (clr *Clusterer) Push(x int64, y float32) {
+
+	if n := len(clr.buf.X); n > 0 {
+		last := clr.buf.X[n-1]
+		if x <= last || (clr.Config.MaxGap > 0 && x-last > clr.Config.MaxGap) {
+			clr.Flush()
+		}
+	}
+
+	if len(clr.buf.X) == 0 {
+		clr.x0, clr.y0 = x, float64(y)
+	}
+	clr.add(x, y)
+
+	/* CHANGE POINTS NEED CONTEXT; SEGMENT A FULL BUFFER BUT HOLD BACK ITS LAST, STILL OPEN SEGMENT */
+	if clr.Config.ChangePoints() {
+		if clr.Config.MaxSize > 0 && len(clr.buf.X) >= clr.Config.MaxSize {
+			clr.segment(true)
+		}
+		return
+	}
+
+	if clr.stable() {
+		if clr.Config.MaxSize > 0 && len(clr.buf.X) >= clr.Config.MaxSize {
+			clr.Flush()
+		}
+		return
+	}
+
+	/* THE NEW SAMPLE BROKE THE LIMITS; CLOSE WHAT CAME BEFORE IF IT IS BIG ENOUGH */
+	if len(clr.buf.X)-1 >= clr.Config.MinSize {
+		clr.sub(x, y)
+		clr.buf.X = clr.buf.X[:len(clr.buf.X)-1]
+		clr.buf.Y = clr.buf.Y[:len(clr.buf.Y)-1]
+		clr.Flush()
+		clr.Push(x, y)
+		return
+	}
+
+	/* OTHERWISE WE ARE IN AN UNSTABLE STRETCH; SLIDE PAST THE OLDEST SAMPLES */
+	for len(clr.buf.X) > 1 && !clr.stable() {
+		clr.sub(clr.buf.X[0], clr.buf.Y[0])
+		clr.buf.X = clr.buf.X[1:]
+		clr.buf.Y = clr.buf.Y[1:]
+	}
+}
+
+/* CLOSES THE OPEN CLUSTER; ONE SHORTER THAN MinSize IS DROPPED */
+func (clr *Clusterer) Flush() {
+
+	if clr.Config.ChangePoints() {
+		clr.segment(false)
+		return
+	}
+
+	if len(clr.buf.X) >= clr.Config.MinSize && clr.OnClose != nil {
+		clr.OnClose(ClusterTSXY(clr.buf))
+	}
+
+	clr.buf.X = clr.buf.X[:0]
+	clr.buf.Y = clr.buf.Y[:0]
+	clr.n, clr.sx, clr.sy, clr.sxx, clr.sxy, clr.syy = 0, 0, 0, 0, 0, 0
+}
+
+/* CLOSES EACH SEGMENT BETWEEN CHANGE POINTS; WITH hold THE LAST ONE STAYS IN THE BUFFER */
+func (clr *Clusterer) segment(hold bool) {
+
+	var cps []ChangePoint
+	switch clr.Config.Method {
+	case CLUSTER_METHOD_CUSUM:
+		cps = CUSUM(clr.buf, clr.Config.Threshold, clr.Config.Drift, clr.Config.MinSize)
+	case CLUSTER_METHOD_PELT:
+		cps = PELT(clr.buf, clr.Config.Penalty, clr.Config.MinSize)
+	}
+
+	bounds := []int{0}
+	for _, cp := range cps {
+		bounds = append(bounds, cp.Index)
+	}
+	bounds = append(bounds, len(clr.buf.X))
+
+	/* A HELD SEGMENT FILLING MOST OF THE BUFFER WOULD BE RE-SEGMENTED ON EVERY PUSH; CLOSE IT INSTEAD */
+	segs := len(bounds) - 1
+	keep := hold && bounds[segs]-bounds[segs-1] <= clr.Config.MaxSize/2
+	if keep {
+		segs--
+	}
+	for i := 0; i < segs; i++ {
+		a, b := bounds[i], bounds[i+1]
+		if b-a >= clr.Config.MinSize && clr.OnClose != nil {
+			clr.OnClose(ClusterTSXY(TSXY{X: clr.buf.X[a:b], Y: clr.buf.Y[a:b]}))
+		}
+	}
+
+	if !keep {
+		clr.buf.X = clr.buf.X[:0]
+		clr.buf.Y = clr.buf.Y[:0]
+		return
+	}
+	rest := bounds[segs]
+	n := copy(clr.buf.X, clr.buf.X[rest:])
+	copy(clr.buf.Y, clr.buf.Y[rest:])
+	clr.buf.X = clr.buf.X[:n]
+	clr.buf.Y = clr.buf.Y[:n]
+}
+
+/* SAMPLES IN THE OPEN CLUSTER */
+func (clr *Clusterer) Pending() int { return len(clr.buf.X) }
+
+func (clr *Clusterer) add(x int64, y float32) {
+	clr.buf.X = append(clr.buf.X, x)
+	clr.buf.Y = append(clr.buf.Y, y)
+	clr.sum(x, y, 1)
+}
+
+func (clr *Clusterer) sub(x int64, y float32) { clr.sum(x, y, -1) }
+
+func (clr *Clusterer) sum(x int64, y float32, sign float64) {
+	dx := float64(x-clr.x0) / 1e3
+	dy := float64(y) - clr.y0
+	clr.n += sign
+	clr.sx += sign * dx
+	clr.sy += sign * dy
+	clr.sxx += sign * dx * dx
+	clr.sxy += sign * dx * dy
+	clr.syy += sign * dy * dy
+}
+
+func (clr *Clusterer) stable() bool {
+
+	if clr.n < 2 {
+		return true
+	}
+
+	mean := clr.sy / clr.n
+	devi := math.Sqrt(math.Max(0, clr.syy/clr.n-mean*mean))
+	if devi > float64(clr.Config.MaxDevi) {
+		return false
+	}
+
+	if clr.Config.MaxSlope > 0 {
+		den := clr.n*clr.sxx - clr.sx*clr.sx
+		if den > 0 {
+			slope := (clr.n*clr.sxy - clr.sx*clr.sy) / den
+			if math.Abs(slope) > float64(clr.Config.MaxSlope) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+/* STATS FOR ONE CLUSTER; X IS TAKEN IN SECONDS FROM THE FIRST SAMPLE */
+func ClusterTSXY(ts TSXY) (cs ClusterStats) {
+
+	n := len(ts.X)
+	if n == 0 {
+		return
+	}
+
+	cs.Start = ts.X[0]
+	cs.End = ts.X[n-1]
+	cs.Size = int64(n)
+	cs.Min, cs.Max = ts.Y[0], ts.Y[0]
+
+	xs := make([]float32, n)
+	var xSum, ySum float64
+	for i, x := range ts.X {
+		xs[i] = float32(x-cs.Start) / 1e3
+		xSum += float64(xs[i])
+		ySum += float64(ts.Y[i])
+		cs.Min = float32(math.Min(float64(cs.Min), float64(ts.Y[i])))
+		cs.Max = float32(math.Max(float64(cs.Max), float64(ts.Y[i])))
+	}
+	cs.Mean = float32(ySum / float64(n))
+
+	if n > 1 {
+		cs.Slope, _, cs.Devi = SlopeInterceptDeviation(float32(xSum/float64(n)), cs.Mean, xs, ts.Y)
+	}
+	return
+}
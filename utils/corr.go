@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+/* DROPS EVERY INDEX WHERE EITHER SERIES IS NaN */
+func PairwiseComplete(xs, ys []float64) (outX, outY []float64) {
+	for i := range xs {
+		if i >= len(ys) || math.IsNaN(xs[i]) || math.IsNaN(ys[i]) {
+			continue
+		}
+		outX = append(outX, xs[i])
+		outY = append(outY, ys[i])
+	}
+	return
+}
+
+func Pearson(xs, ys []float64) (r float64, err error) {
+	if len(xs) != len(ys) {
+		return 0, fmt.Errorf("correlation length mismatch: %d / %d", len(xs), len(ys))
+	}
+	if len(xs) < 3 {
+		return 0, fmt.Errorf("correlation needs at least 3 pairs; got %d", len(xs))
+	}
+	r = stat.Correlation(xs, ys, nil)
+	if math.IsNaN(r) {
+		return 0, fmt.Errorf("correlation undefined for a constant series")
+	}
+	return
+}
+
+/* PEARSON CORRELATION OF THE RANKS */
+func Spearman(xs, ys []float64) (r float64, err error) {
+	return Pearson(Ranks(xs), Ranks(ys))
+}
+
+/* 1-BASED RANKS; TIES GET THEIR AVERAGE RANK */
+func Ranks(vs []float64) (ranks []float64) {
+	idx := make([]int, len(vs))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return vs[idx[a]] < vs[idx[b]] })
+
+	ranks = make([]float64, len(vs))
+	for i := 0; i < len(idx); {
+		j := i
+		for j+1 < len(idx) && vs[idx[j+1]] == vs[idx[i]] {
+			j++
+		}
+		avg := float64(i+j)/2 + 1
+		for k := i; k <= j; k++ {
+			ranks[idx[k]] = avg
+		}
+		i = j + 1
+	}
+	return
+}
+
+/* TWO-TAILED P-VALUE FOR r OVER n PAIRS ( STUDENT'S t, n-2 DEGREES OF FREEDOM ) */
+func CorrelationPValue(r float64, n int) float64 {
+	if n < 3 {
+		return math.NaN()
+	}
+	if math.Abs(r) >= 1 {
+		return 0
+	}
+	df := float64(n - 2)
+	t := r * math.Sqrt(df/(1-r*r))
+	dist := distuv.StudentsT{Mu: 0, Sigma: 1, Nu: df}
+	return 2 * dist.Survival(math.Abs(t))
+}
+
+/* 
+PEARSON r OF xs[i] AGAINST ys[i+lag] FOR lag IN [ -maxLag, maxLag ]
+NaN ENTRIES ARE SKIPPED PAIRWISE; LAGS WITH TOO FEW PAIRS ARE OMITTED
+*/
+func CrossCorrelation(xs, ys []float64, maxLag int) (lags []int, rs []float64, ns []int) {
+	/* NO PAIRS LIE BEYOND THE ENDS */
+	for lag := max(-maxLag, 1-len(xs)); lag <= min(maxLag, len(ys)-1); lag++ {
+		var a, b []float64
+		for i := range xs {
+			j := i + lag
+			if j < 0 || j >= len(ys) || math.IsNaN(xs[i]) || math.IsNaN(ys[j]) {
+				continue
+			}
+			a = append(a, xs[i])
+			b = append(b, ys[j])
+		}
+		r, err := Pearson(a, b)
+		if err != nil {
+			continue
+		}
+		lags = append(lags, lag)
+		rs = append(rs, r)
+		ns = append(ns, len(a))
+	}
+	return
+}
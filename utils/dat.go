@@ -321,7 +321,10 @@ func MeanFloat32(slice []float32) float32 {
 	return mean
 }
 
-
+/*
+LEAST SQUARES LINE AND RMS DEVIATION ABOUT THE MEAN; THIS AND THE SlopeAndIntercept FUNCTIONS GIVE
+A SLOPE OF 0 WHEN X IS CONSTANT; CALLERS NEEDING AN ERROR THERE SHOULD USE PolyFit
+*/
 func SlopeInterceptDeviation(xMean, yMean float32, xs, ys []float32) (m, b, devi float32) {
 	
 	SumXYProd := float32(0)
@@ -339,7 +342,9 @@ func SlopeInterceptDeviation(xMean, yMean float32, xs, ys []float32) (m, b, devi
 		SumSqYs += (ys[i] - yMean) * (ys[i] - yMean)
 	}
 	
-	m = SumXYProd  /  SumSqXs
+	if SumSqXs > 0 {
+		m = SumXYProd  /  SumSqXs
+	}
 
 	b = yMean - (m * xMean)
 
@@ -361,7 +366,9 @@ func SlopeAndIntercept(xMean, yMean float32, xs, ys []float32) (m, b float32) {
 		SumSqXs += (xs[i] - xMean) * (xs[i] - xMean)
 	}
 	
-	m = SumXYProd  /  SumSqXs
+	if SumSqXs > 0 {
+		m = SumXYProd  /  SumSqXs
+	}
 
 	b = yMean - (m * xMean)
 
@@ -385,7 +392,9 @@ func SlopeAndIntercept_XInt32(xs []int32, ys []float32) (m float32, b float32) {
 		SumSqXs += (float32(xs[i]) - xMean) * (float32(xs[i]) - xMean)
 	}
 	
-	m = SumXYProd  /  SumSqXs
+	if SumSqXs > 0 {
+		m = SumXYProd  /  SumSqXs
+	}
 
 	b = yMean - (m * xMean)
 
@@ -438,6 +447,15 @@ func (v TSXY) TSD(margin float32) TimeSeriesData {
 	return TimeSeriesData{TSDPoints(v), min, max}
 }
 
+/* AS TSD, WITH AT MOST maxPoints POINTS; Min AND Max STILL COVER EVERY SAMPLE */
+func (v TSXY) TSDSampled(margin float32, method string, maxPoints int) TimeSeriesData {
+	if len(v.Y) == 0 {
+		return TimeSeriesData{Data: []TSDPoint{}}
+	}
+	min, max := v.MinMax(margin)
+	return TimeSeriesData{TSDPoints(Downsample(v, method, maxPoints)), min, max}
+}
+
 type TSValues interface {
 	TSXs() []int64
 	TSYs() []float32
@@ -449,7 +467,7 @@ type TSDPoint struct {
 
 func TSDPoints(v TSValues) []TSDPoint {
 	xs, ys := v.TSXs(), v.TSYs()
-	points := []TSDPoint{}
+	points := make([]TSDPoint, 0, len(xs))
 	for i, x := range xs {
 		point := TSDPoint{}
 		point.X = x
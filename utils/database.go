@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+const DB_DRIVER_SQLITE string = "sqlite"
+const DB_DRIVER_POSTGRES string = "postgres"
+
+/* WHAT THE API NEEDS FROM A DATABASE; IMPLEMENTED BY SQLiteClient AND PostgresClient */
+type DBClient interface {
+	Connect() error
+	Disconnect() error
+	ConnectionOK() bool
+	Scanner(qry *gorm.DB, out interface{}) error
+	Gorm() *gorm.DB
+	Driver() string
+	ConnName() string // SAFE TO LOG
+}
+
+/* SELECTS THE BACKEND; Dir / Name ARE FOR SQLITE, DSN IS FOR POSTGRES */
+type DBConfig struct {
+	Driver string
+	Dir    string
+	Name   string
+	DSN    string
+}
+
+func ConfigureDBClient(cfg DBConfig) (dbc DBClient, err error) {
+
+	switch cfg.Driver {
+
+	case DB_DRIVER_SQLITE, "":
+		if err = ConfirmDirectory(cfg.Dir); err != nil {
+			return
+		}
+		client, cfg_err := ConfigureDBSQLiteClient(cfg.Dir, cfg.Name)
+		if cfg_err != nil {
+			return nil, cfg_err
+		}
+		dbc = &client
+
+	case DB_DRIVER_POSTGRES:
+		client, cfg_err := ConfigureDBPostgresClient(cfg.DSN)
+		if cfg_err != nil {
+			return nil, cfg_err
+		}
+		dbc = &client
+
+	default:
+		err = fmt.Errorf("unknown database driver: %s", cfg.Driver)
+	}
+	return
+}
+
+/* SHARED BY BOTH CLIENTS */
+func scanAll(db *gorm.DB, qry *gorm.DB, out interface{}) (err error) {
+
+	rows, err := qry.Rows()
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		db.ScanRows(rows, out)
+	}
+
+	return
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2/log"
+
+	"gorm.io/driver/postgres" // go get gorm.io/driver/postgres
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type PostgresClient struct {
+	DSN string // postgres://user:pw@host:5432/db OR "host=... user=... dbname=..."
+	*gorm.DB
+	*sync.RWMutex
+	Connected bool
+}
+
+func ConfigureDBPostgresClient(dsn string) (dbc PostgresClient, err error) {
+
+	if strings.TrimSpace(dsn) == "" {
+		err = fmt.Errorf(ERR_DB_CONNSTR_EMPTY)
+		return
+	}
+
+	dbc.DSN = dsn
+	dbc.RWMutex = &sync.RWMutex{}
+	return
+}
+
+func (client *PostgresClient) Connect() (err error) {
+
+	if client.DSN == "" {
+		return fmt.Errorf(ERR_DB_CONNSTR_EMPTY)
+	}
+
+	if client.DB, err = gorm.Open(postgres.Open(client.DSN), &gorm.Config{}); err != nil {
+		log.Info("(*PostgresClient) Connect() -> FAILED :", client.ConnName())
+		return err
+	}
+
+	client.DB.Logger = logger.Default.LogMode(logger.Error)
+
+	client.Connected = true
+	return
+}
+
+func (client *PostgresClient) Disconnect() (err error) {
+
+	if client.DB == nil {
+		return
+	}
+
+	db, err := client.DB.DB()
+	if err != nil {
+		return err
+	}
+
+	if err = db.Close(); err != nil {
+		log.Info("(*PostgresClient) Disconnect() -> FAILED :", client.ConnName())
+		return err
+	}
+
+	client.Connected = false
+	return
+}
+
+func (client *PostgresClient) ConnectionOK() bool {
+
+	if client.Connected {
+		db, err := client.DB.DB()
+		client.Connected = err == nil && db.Ping() == nil
+	}
+
+	return client.Connected
+}
+
+func (client *PostgresClient) Scanner(qry *gorm.DB, out interface{}) (err error) {
+	return scanAll(client.DB, qry, out)
+}
+
+func (client *PostgresClient) Gorm() *gorm.DB { return client.DB }
+
+func (client *PostgresClient) Driver() string { return DB_DRIVER_POSTGRES }
+
+var pgPasswordKV = regexp.MustCompile(`password=\S+`)
+
+/* THE DSN WITH ITS PASSWORD REMOVED */
+func (client *PostgresClient) ConnName() string {
+	if u, err := url.Parse(client.DSN); err == nil && u.Scheme != "" {
+		return u.Redacted()
+	}
+	return pgPasswordKV.ReplaceAllString(client.DSN, "password=xxxxx")
+}
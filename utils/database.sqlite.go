@@ -13,6 +13,8 @@ import (
 	"gorm.io/driver/sqlite" // go get gorm.io/driver/sqlite
 )
 
+const SQLITE_BUSY_TIMEOUT int = 5000 // ms
+
 type SQLiteClient struct {
 	Conn string
 	Segs    int
@@ -79,7 +81,12 @@ func (client *SQLiteClient) Connect() (err error) {
 	}
 	// fmt.Printf("\n(*SQLiteClient) Connect() -> db_name: %s \n", db_name)
 
-	if client.DB, err = gorm.Open(sqlite.Open(client.Conn), &gorm.Config{}); err != nil {
+	/*
+	SQLITE ONLY ENFORCES FOREIGN KEYS WHEN ASKED, PER CONNECTION
+	WAL LETS A LONG READ ( AN EXPORT ) RUN ALONGSIDE WRITES; A WRITER WAITING ON ANOTHER RETRIES FOR busy_timeout ms
+	*/
+	dsn := fmt.Sprintf("%s?_foreign_keys=on&_journal_mode=WAL&_busy_timeout=%d", client.Conn, SQLITE_BUSY_TIMEOUT)
+	if client.DB, err = gorm.Open(sqlite.Open(dsn), &gorm.Config{}); err != nil {
 		log.Info("(*SQLiteClient) Connect() -> FAILED :", db_name)
 		return err
 	}
@@ -132,19 +139,14 @@ func (client *SQLiteClient) ConnectionOK() bool {
 }
 
 func (client *SQLiteClient) Scanner(qry *gorm.DB, out interface{}) (err error) {
+	return scanAll(client.DB, qry, out)
+}
 
-	rows, err := qry.Rows()
-	if err != nil {
-		return
-	}
-	defer rows.Close()
+func (client *SQLiteClient) Gorm() *gorm.DB { return client.DB }
 
-	for rows.Next() {
-		client.ScanRows(rows, out)
-	}
+func (client *SQLiteClient) Driver() string { return DB_DRIVER_SQLITE }
 
-	return
-}
+func (client *SQLiteClient) ConnName() string { return client.Conn }
 
 func (client *SQLiteClient) InitializeDatabaseClient(conn_str string, segs int) (err error) {
 
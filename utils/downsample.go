@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+)
+
+const DOWNSAMPLE_LTTB string = "lttb"
+const DOWNSAMPLE_MINMAX string = "minmax"
+
+func ValidateDownsampleMethod(method string) (err error) {
+	switch method {
+	case "", DOWNSAMPLE_LTTB, DOWNSAMPLE_MINMAX:
+	default:
+		err = fmt.Errorf("invalid downsample method: %s", method)
+	}
+	return
+}
+
+/* AT MOST maxPoints OF ts; lttb ( DEFAULT ) | minmax; maxPoints < 1 OR A SHORT SERIES RETURNS ts ITSELF */
+func Downsample(ts TSXY, method string, maxPoints int) TSXY {
+	if maxPoints < 1 || len(ts.X) <= maxPoints {
+		return ts
+	}
+	if method == DOWNSAMPLE_MINMAX {
+		return MinMaxBuckets(ts, maxPoints)
+	}
+	return LTTB(ts, maxPoints)
+}
+
+/*
+LARGEST-TRIANGLE-THREE-BUCKETS ( STEINARSSON 2013 )
+KEEPS THE FIRST AND LAST SAMPLES AND, FROM EACH BUCKET BETWEEN, THE ONE FORMING THE LARGEST TRIANGLE
+WITH THE LAST KEPT SAMPLE AND THE MEAN OF THE NEXT BUCKET; ONLY THE OUTPUT COLUMNS ARE ALLOCATED
+*/
+func LTTB(ts TSXY, threshold int) (out TSXY) {
+
+	n := len(ts.X)
+	if threshold >= n {
+		return ts
+	}
+	if threshold < 3 {
+		return MinMaxBuckets(ts, threshold)
+	}
+
+	out.X = make([]int64, 0, threshold)
+	out.Y = make([]float32, 0, threshold)
+	out.X = append(out.X, ts.X[0])
+	out.Y = append(out.Y, ts.Y[0])
+
+	/* X RELATIVE TO THE FIRST SAMPLE KEEPS THE AREAS WELL CONDITIONED */
+	x0 := ts.X[0]
+	every := float64(n-2) / float64(threshold-2)
+	a := 0
+
+	for b := 0; b < threshold-2; b++ {
+
+		/* MEAN OF THE NEXT BUCKET; THE LAST SAMPLE STANDS IN FOR THE BUCKET AFTER THE LAST */
+		nextLo := int(float64(b+1)*every) + 1
+		nextHi := int(float64(b+2)*every) + 1
+		if nextHi > n {
+			nextHi = n
+		}
+		var avgX, avgY float64
+		if nextLo >= n-1 {
+			avgX, avgY = float64(ts.X[n-1]-x0), float64(ts.Y[n-1])
+		} else {
+			for i := nextLo; i < nextHi; i++ {
+				avgX += float64(ts.X[i] - x0)
+				avgY += float64(ts.Y[i])
+			}
+			avgX /= float64(nextHi - nextLo)
+			avgY /= float64(nextHi - nextLo)
+		}
+
+		lo := int(float64(b)*every) + 1
+		hi := int(float64(b+1)*every) + 1
+		ax, ay := float64(ts.X[a]-x0), float64(ts.Y[a])
+
+		best, bestArea := lo, -1.0
+		for i := lo; i < hi; i++ {
+			area := math.Abs((ax-avgX)*(float64(ts.Y[i])-ay) - (ax-float64(ts.X[i]-x0))*(avgY-ay))
+			if area > bestArea {
+				best, bestArea = i, area
+			}
+		}
+
+		out.X = append(out.X, ts.X[best])
+		out.Y = append(out.Y, ts.Y[best])
+		a = best
+	}
+
+	out.X = append(out.X, ts.X[n-1])
+	out.Y = append(out.Y, ts.Y[n-1])
+	return
+}
+
+/* THE MIN AND MAX SAMPLE OF EACH OF maxPoints / 2 BUCKETS, IN TIME ORDER, SO NO PEAK IS LOST */
+func MinMaxBuckets(ts TSXY, maxPoints int) (out TSXY) {
+
+	n := len(ts.X)
+	if maxPoints >= n {
+		return ts
+	}
+	buckets := maxPoints / 2
+	if buckets < 1 {
+		buckets = 1
+	}
+
+	out.X = make([]int64, 0, 2*buckets)
+	out.Y = make([]float32, 0, 2*buckets)
+	every := float64(n) / float64(buckets)
+
+	for b := 0; b < buckets; b++ {
+		lo, hi := int(float64(b)*every), int(float64(b+1)*every)
+		if b == buckets-1 {
+			hi = n
+		}
+		if lo >= hi {
+			continue
+		}
+
+		iMin, iMax := lo, lo
+		for i := lo + 1; i < hi; i++ {
+			if ts.Y[i] < ts.Y[iMin] {
+				iMin = i
+			}
+			if ts.Y[i] > ts.Y[iMax] {
+				iMax = i
+			}
+		}
+
+		first, second := iMin, iMax
+		if first > second {
+			first, second = second, first
+		}
+		out.X = append(out.X, ts.X[first])
+		out.Y = append(out.Y, ts.Y[first])
+		if second != first && len(out.X) < maxPoints {
+			out.X = append(out.X, ts.X[second])
+			out.Y = append(out.Y, ts.Y[second])
+		}
+	}
+	return
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+/* A SLOW SINE WITH ONE SPIKE AT spike */
+func sineTS(n, spike int) (ts TSXY) {
+	for i := 0; i < n; i++ {
+		y := float32(math.Sin(float64(i) / 50))
+		if i == spike {
+			y = 10
+		}
+		ts.X = append(ts.X, int64(1000+10*i))
+		ts.Y = append(ts.Y, y)
+	}
+	return
+}
+
+func TestDownsample(t *testing.T) {
+
+	ts := sineTS(1000, 437)
+
+	tests := []struct {
+		name   string
+		method string
+		max    int
+		size   int
+	}{
+		{"lttb 3", DOWNSAMPLE_LTTB, 3, 3},
+		{"lttb 10", DOWNSAMPLE_LTTB, 10, 10},
+		{"lttb 100", DOWNSAMPLE_LTTB, 100, 100},
+		{"lttb 999", DOWNSAMPLE_LTTB, 999, 999},
+		{"lttb whole series", DOWNSAMPLE_LTTB, 1000, 1000},
+		{"minmax 100", DOWNSAMPLE_MINMAX, 100, 100},
+		{"no limit", DOWNSAMPLE_LTTB, 0, 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := Downsample(ts, tt.method, tt.max)
+			if len(out.X) != tt.size || len(out.Y) != tt.size {
+				t.Fatalf("%d x / %d y, want %d", len(out.X), len(out.Y), tt.size)
+			}
+			if !slices.IsSorted(out.X) {
+				t.Fatal("x out of order")
+			}
+			if tt.method == DOWNSAMPLE_LTTB && (out.X[0] != ts.X[0] || out.X[tt.size-1] != ts.X[999]) {
+				t.Fatalf("endpoints %d, %d, want %d, %d", out.X[0], out.X[tt.size-1], ts.X[0], ts.X[999])
+			}
+			if tt.size > 3 && !slices.Contains(out.Y, 10) {
+				t.Fatal("spike dropped")
+			}
+		})
+	}
+}
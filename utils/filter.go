@@ -0,0 +1,465 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+const FILTER_EMA string = "ema"
+const FILTER_MEDIAN string = "median"
+const FILTER_SAVGOL string = "savgol"
+const FILTER_BUTTERWORTH string = "butterworth"
+const FILTER_KALMAN string = "kalman"
+
+/* DEFAULTS FOR A ZERO Order */
+const FILTER_SAVGOL_ORDER int = 2
+const FILTER_BUTTERWORTH_ORDER int = 2
+
+const FILTER_SAVGOL_MAX_ORDER int = 5
+const FILTER_BUTTERWORTH_MAX_ORDER int = 8
+const FILTER_CHAIN_MAX int = 8
+
+/* median AND savgol: THE LONGEST WINDOW, AND THE MOST SAMPLES EITHER SIDE OF THE CENTER IT WILL HOLD */
+const FILTER_MAX_WINDOW int64 = 600000 // ms
+const FILTER_WINDOW_MAX_SIDE int = 500
+
+/* DISTINCT SAMPLE INTERVALS A butterworth FILTER KEEPS DISCRETIZATIONS FOR */
+const FILTER_BUTTERWORTH_CACHE int = 1024
+
+/*
+ONE STAGE OF A FILTER CHAIN; EVERY STAGE WORKS IN TIME, NOT SAMPLES, SO IRREGULAR SAMPLING IS HANDLED
+ema:         EXPONENTIAL MOVING AVERAGE WITH TIME CONSTANT Tau
+median:      MEDIAN OF THE SAMPLES WITHIN Window / 2 EITHER SIDE
+savgol:      SAVITZKY-GOLAY; LEAST SQUARES POLYNOMIAL OF Order OVER THE SAMPLES WITHIN Window / 2 EITHER SIDE
+butterworth: LOW-PASS OF Order POLES AT Cutoff, DISCRETIZED EXACTLY FOR EACH SAMPLE INTERVAL; CAUSAL
+kalman:      LOCAL LEVEL MODEL; THE LEVEL DRIFTS WITH VARIANCE Q PER SECOND, MEASUREMENTS HAVE VARIANCE R
+median AND savgol HOLD EACH SAMPLE UNTIL ONE ARRIVES MORE THAN Window / 2 AFTER IT, OR FILTER_WINDOW_MAX_SIDE DO;
+FAST SAMPLING THEREFORE NARROWS THE WINDOW TO FILTER_WINDOW_MAX_SIDE SAMPLES EITHER SIDE
+*/
+type FilterConfig struct {
+	Name   string  `json:"name"`   // ema | median | savgol | butterworth | kalman
+	Tau    int64   `json:"tau"`    // ms; ema
+	Window int64   `json:"window"` // ms; median, savgol
+	Order  int     `json:"order"`  // savgol, butterworth
+	Cutoff float64 `json:"cutoff"` // Hz; butterworth
+	Q      float64 `json:"q"`      // kalman
+	R      float64 `json:"r"`      // kalman
+}
+
+func (cfg *FilterConfig) Defaults() {
+	if cfg.Order == 0 {
+		switch cfg.Name {
+		case FILTER_SAVGOL:
+			cfg.Order = FILTER_SAVGOL_ORDER
+		case FILTER_BUTTERWORTH:
+			cfg.Order = FILTER_BUTTERWORTH_ORDER
+		}
+	}
+}
+
+func (cfg *FilterConfig) Validate() (err error) {
+	switch cfg.Name {
+	case FILTER_EMA:
+		if cfg.Tau <= 0 {
+			err = fmt.Errorf("ema filter tau must be positive")
+		}
+	case FILTER_MEDIAN, FILTER_SAVGOL:
+		switch {
+		case cfg.Window <= 0 || cfg.Window > FILTER_MAX_WINDOW:
+			err = fmt.Errorf("%s filter window must be 1 to %d ms", cfg.Name, FILTER_MAX_WINDOW)
+		case cfg.Name == FILTER_SAVGOL && (cfg.Order < 0 || cfg.Order > FILTER_SAVGOL_MAX_ORDER):
+			err = fmt.Errorf("savgol filter order must be 0 to %d", FILTER_SAVGOL_MAX_ORDER)
+		}
+	case FILTER_BUTTERWORTH:
+		switch {
+		case cfg.Cutoff <= 0 || math.IsInf(cfg.Cutoff, 0) || math.IsNaN(cfg.Cutoff):
+			err = fmt.Errorf("butterworth filter cutoff must be positive")
+		case cfg.Order < 0 || cfg.Order > FILTER_BUTTERWORTH_MAX_ORDER:
+			err = fmt.Errorf("butterworth filter order must be 1 to %d", FILTER_BUTTERWORTH_MAX_ORDER)
+		}
+	case FILTER_KALMAN:
+		if !(cfg.Q > 0) || !(cfg.R > 0) || math.IsInf(cfg.Q, 0) || math.IsInf(cfg.R, 0) {
+			err = fmt.Errorf("kalman filter q and r must be positive")
+		}
+	default:
+		err = fmt.Errorf("invalid filter: %s", cfg.Name)
+	}
+	return
+}
+
+/* FILTERS APPLIED IN ORDER; EMPTY PASSES SAMPLES THROUGH */
+type FilterChain []FilterConfig
+
+func (chain FilterChain) Validate() (err error) {
+	if len(chain) > FILTER_CHAIN_MAX {
+		return fmt.Errorf("filter chain has %d filters, limit %d", len(chain), FILTER_CHAIN_MAX)
+	}
+	for i := range chain {
+		cfg := chain[i]
+		cfg.Defaults()
+		if err = cfg.Validate(); err != nil {
+			return fmt.Errorf("filter %d: %s", i+1, err.Error())
+		}
+	}
+	return
+}
+
+/* A FRESH FILTER FOR THE CHAIN; THE CHAIN MUST BE VALID */
+func (chain FilterChain) New() Filter {
+	stages := make(pipeline, len(chain))
+	for i := range chain {
+		cfg := chain[i]
+		cfg.Defaults()
+		stages[i] = NewFilter(cfg)
+	}
+	return stages
+}
+
+/* FILTERS A WHOLE SERIES; ts.X MUST BE SORTED */
+func (chain FilterChain) Apply(ts TSXY) (out TSXY) {
+	if len(chain) == 0 {
+		return ts
+	}
+	out.X = make([]int64, 0, len(ts.X))
+	out.Y = make([]float32, 0, len(ts.Y))
+	emit := func(x int64, y float64) {
+		out.X = append(out.X, x)
+		out.Y = append(out.Y, float32(y))
+	}
+	f := chain.New()
+	for i := range ts.X {
+		f.Push(ts.X[i], float64(ts.Y[i]), emit)
+	}
+	f.Flush(emit)
+	return
+}
+
+type Emit func(x int64, y float64)
+
+/*
+A STREAMING FILTER; Push TAKES SAMPLES IN TIME ORDER AND CALLS emit FOR EVERY SAMPLE IT CAN FINISH
+Flush FINISHES THE SAMPLES STILL HELD AND RESETS THE FILTER
+*/
+type Filter interface {
+	Push(x int64, y float64, emit Emit)
+	Flush(emit Emit)
+}
+
+/* cfg MUST BE VALID, WITH DEFAULTS APPLIED */
+func NewFilter(cfg FilterConfig) Filter {
+	switch cfg.Name {
+	case FILTER_EMA:
+		return &emaFilter{tau: float64(cfg.Tau)}
+	case FILTER_MEDIAN:
+		return &windowFilter{half: cfg.Window / 2, smooth: medianAt}
+	case FILTER_SAVGOL:
+		order := cfg.Order
+		half := cfg.Window / 2
+		return &windowFilter{half: half, smooth: func(win []filterPoint, at int) float64 {
+			return savgolAt(win, at, order, half)
+		}}
+	case FILTER_BUTTERWORTH:
+		return newButterworth(cfg.Order, cfg.Cutoff)
+	case FILTER_KALMAN:
+		return &kalmanFilter{q: cfg.Q, r: cfg.R}
+	}
+	return pipeline{}
+}
+
+type pipeline []Filter
+
+func (stages pipeline) Push(x int64, y float64, emit Emit) {
+	if len(stages) == 0 {
+		emit(x, y)
+		return
+	}
+	stages[0].Push(x, y, func(x int64, y float64) { stages[1:].Push(x, y, emit) })
+}
+
+func (stages pipeline) Flush(emit Emit) {
+	if len(stages) == 0 {
+		return
+	}
+	stages[0].Flush(func(x int64, y float64) { stages[1:].Push(x, y, emit) })
+	stages[1:].Flush(emit)
+}
+
+/* EMA ***********************************************************************************/
+type emaFilter struct {
+	tau    float64 // ms
+	y      float64
+	last   int64
+	primed bool
+}
+
+func (f *emaFilter) Push(x int64, y float64, emit Emit) {
+	if !f.primed {
+		f.y, f.primed = y, true
+	} else if dt := float64(x - f.last); dt > 0 {
+		f.y += (1 - math.Exp(-dt/f.tau)) * (y - f.y)
+	}
+	f.last = x
+	emit(x, f.y)
+}
+
+func (f *emaFilter) Flush(emit Emit) { f.primed = false }
+
+/* CENTERED TIME WINDOWS *****************************************************************/
+type filterPoint struct {
+	x int64
+	y float64
+}
+
+type windowFilter struct {
+	half   int64
+	buf    []filterPoint
+	next   int // THE NEXT SAMPLE TO EMIT
+	smooth func(win []filterPoint, at int) float64
+}
+
+func (f *windowFilter) Push(x int64, y float64, emit Emit) {
+	f.buf = append(f.buf, filterPoint{x, y})
+	for f.next < len(f.buf) && (x > f.buf[f.next].x+f.half || len(f.buf)-1-f.next >= FILTER_WINDOW_MAX_SIDE) {
+		f.emitNext(emit)
+	}
+}
+
+func (f *windowFilter) Flush(emit Emit) {
+	for f.next < len(f.buf) {
+		f.emitNext(emit)
+	}
+	f.buf, f.next = nil, 0
+}
+
+func (f *windowFilter) emitNext(emit Emit) {
+	t := f.buf[f.next].x
+
+	/* SAMPLES BEFORE THE WINDOW ARE NEVER NEEDED AGAIN; SO buf NEVER HOLDS MORE THAN 2 * FILTER_WINDOW_MAX_SIDE + 1 */
+	lo := max(0, f.next-FILTER_WINDOW_MAX_SIDE)
+	for f.buf[lo].x < t-f.half {
+		lo++
+	}
+	f.buf, f.next = f.buf[lo:], f.next-lo
+
+	hi := f.next
+	for hi < len(f.buf) && hi-f.next <= FILTER_WINDOW_MAX_SIDE && f.buf[hi].x <= t+f.half {
+		hi++
+	}
+	emit(t, f.smooth(f.buf[:hi], f.next))
+	f.next++
+}
+
+func medianAt(win []filterPoint, at int) float64 {
+	ys := make([]float64, len(win))
+	for i := range win {
+		ys[i] = win[i].y
+	}
+	return Median(ys)
+}
+
+/* THE LOCAL POLYNOMIAL AT win[at]; DROPS TO A LOWER ORDER WHEN THE WINDOW HAS TOO FEW DISTINCT TIMES */
+func savgolAt(win []filterPoint, at, order int, half int64) float64 {
+
+	t, scale := win[at].x, float64(half)
+	if scale == 0 {
+		scale = 1
+	}
+
+	for p := min(order, len(win)-1) + 1; p > 1; p-- {
+
+		/* NORMAL EQUATIONS IN u = ( x - t ) / half; THE VALUE AT t IS THE CONSTANT TERM */
+		ata := make([][]float64, p)
+		atb := make([]float64, p)
+		for i := range ata {
+			ata[i] = make([]float64, p)
+		}
+		for _, pt := range win {
+			u := float64(pt.x-t) / scale
+			pows := make([]float64, p)
+			pows[0] = 1
+			for k := 1; k < p; k++ {
+				pows[k] = pows[k-1] * u
+			}
+			for r := 0; r < p; r++ {
+				atb[r] += pows[r] * pt.y
+				for c := 0; c < p; c++ {
+					ata[r][c] += pows[r] * pows[c]
+				}
+			}
+		}
+		if cs, ok := solveSmall(ata, atb); ok {
+			return cs[0]
+		}
+	}
+
+	var sum float64
+	for _, pt := range win {
+		sum += pt.y
+	}
+	return sum / float64(len(win))
+}
+
+/* GAUSSIAN ELIMINATION WITH PARTIAL PIVOTING; a AND b ARE OVERWRITTEN */
+func solveSmall(a [][]float64, b []float64) (x []float64, ok bool) {
+	n := len(b)
+	for c := 0; c < n; c++ {
+		piv := c
+		for r := c + 1; r < n; r++ {
+			if math.Abs(a[r][c]) > math.Abs(a[piv][c]) {
+				piv = r
+			}
+		}
+		if math.Abs(a[piv][c]) < 1e-12 {
+			return nil, false
+		}
+		a[c], a[piv] = a[piv], a[c]
+		b[c], b[piv] = b[piv], b[c]
+		for r := c + 1; r < n; r++ {
+			f := a[r][c] / a[c][c]
+			for k := c; k < n; k++ {
+				a[r][k] -= f * a[c][k]
+			}
+			b[r] -= f * b[c]
+		}
+	}
+	x = make([]float64, n)
+	for r := n - 1; r >= 0; r-- {
+		sum := b[r]
+		for k := r + 1; k < n; k++ {
+			sum -= a[r][k] * x[k]
+		}
+		x[r] = sum / a[r][r]
+	}
+	return x, true
+}
+
+/* BUTTERWORTH ***************************************************************************/
+
+/*
+A CASCADE OF CONTINUOUS TIME SECTIONS, ONE PER POLE PAIR PLUS ONE FOR AN ODD POLE
+EACH STEP DISCRETIZES THE SECTION FOR ITS OWN INTERVAL, HOLDING THE NEW SAMPLE OVER IT
+*/
+type butterworthFilter struct {
+	sections []*lowpassSection
+	last     int64
+	primed   bool
+}
+
+type lowpassSection struct {
+	a, b  *mat.Dense // CONTINUOUS x' = a x + b u; y = x[0]
+	state *mat.VecDense
+	disc  map[int64][2]*mat.Dense // dt -> ( Ad, Bd )
+}
+
+func newButterworth(order int, cutoff float64) *butterworthFilter {
+
+	wc := 2 * math.Pi * cutoff / 1000 // rad / ms
+	f := &butterworthFilter{}
+
+	for k := 1; k <= order/2; k++ {
+		zeta := math.Sin(math.Pi * float64(2*k-1) / float64(2*order))
+		f.sections = append(f.sections, &lowpassSection{
+			a: mat.NewDense(2, 2, []float64{0, 1, -wc * wc, -2 * zeta * wc}),
+			b: mat.NewDense(2, 1, []float64{0, wc * wc}),
+		})
+	}
+	if order%2 == 1 {
+		f.sections = append(f.sections, &lowpassSection{
+			a: mat.NewDense(1, 1, []float64{-wc}),
+			b: mat.NewDense(1, 1, []float64{wc}),
+		})
+	}
+	return f
+}
+
+func (f *butterworthFilter) Push(x int64, y float64, emit Emit) {
+
+	if !f.primed {
+		/* START AT REST ON THE FIRST SAMPLE, SO THERE IS NO STEP RESPONSE */
+		for _, sec := range f.sections {
+			n, _ := sec.a.Dims()
+			sec.state = mat.NewVecDense(n, nil)
+			sec.state.SetVec(0, y)
+			sec.disc = make(map[int64][2]*mat.Dense)
+		}
+		f.last, f.primed = x, true
+		emit(x, y)
+		return
+	}
+
+	dt := x - f.last
+	f.last = x
+	u := y
+	for _, sec := range f.sections {
+		u = sec.step(dt, u)
+	}
+	emit(x, u)
+}
+
+func (f *butterworthFilter) Flush(emit Emit) { f.primed = false }
+
+func (sec *lowpassSection) step(dt int64, u float64) float64 {
+	if dt <= 0 {
+		return sec.state.AtVec(0)
+	}
+
+	d, ok := sec.disc[dt]
+	if !ok {
+		/* exp( [ a b ; 0 0 ] dt ) = [ Ad Bd ; 0 1 ] */
+		n, _ := sec.a.Dims()
+		aug := mat.NewDense(n+1, n+1, nil)
+		for r := 0; r < n; r++ {
+			for c := 0; c < n; c++ {
+				aug.Set(r, c, sec.a.At(r, c)*float64(dt))
+			}
+			aug.Set(r, n, sec.b.At(r, 0)*float64(dt))
+		}
+		var e mat.Dense
+		e.Exp(aug)
+		d = [2]*mat.Dense{
+			mat.DenseCopyOf(e.Slice(0, n, 0, n)),
+			mat.DenseCopyOf(e.Slice(0, n, n, n+1)),
+		}
+		if len(sec.disc) >= FILTER_BUTTERWORTH_CACHE {
+			sec.disc = make(map[int64][2]*mat.Dense)
+		}
+		sec.disc[dt] = d
+	}
+
+	var next mat.VecDense
+	next.MulVec(d[0], sec.state)
+	for r := 0; r < next.Len(); r++ {
+		next.SetVec(r, next.AtVec(r)+d[1].At(r, 0)*u)
+	}
+	sec.state = &next
+	return next.AtVec(0)
+}
+
+/* KALMAN ********************************************************************************/
+type kalmanFilter struct {
+	q, r   float64
+	level  float64
+	p      float64 // VARIANCE OF level
+	last   int64
+	primed bool
+}
+
+func (f *kalmanFilter) Push(x int64, y float64, emit Emit) {
+	if !f.primed {
+		f.level, f.p, f.last, f.primed = y, f.r, x, true
+		emit(x, y)
+		return
+	}
+	if dt := float64(x-f.last) / 1000; dt > 0 {
+		f.p += f.q * dt
+	}
+	f.last = x
+	k := f.p / (f.p + f.r)
+	f.level += k * (y - f.level)
+	f.p *= 1 - k
+	emit(x, f.level)
+}
+
+func (f *kalmanFilter) Flush(emit Emit) { f.primed = false }
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFilterChainConstant(t *testing.T) {
+
+	/* IRREGULAR SAMPLING; EVERY FILTER SHOULD PASS A CONSTANT THROUGH, ONE OUTPUT PER SAMPLE */
+	ts := TSXY{}
+	x := int64(0)
+	for i := 0; i < 300; i++ {
+		x += int64(5 + i%7*3)
+		ts.X = append(ts.X, x)
+		ts.Y = append(ts.Y, 3)
+	}
+
+	tests := []struct {
+		name  string
+		chain FilterChain
+	}{
+		{"none", FilterChain{}},
+		{"ema", FilterChain{{Name: FILTER_EMA, Tau: 100}}},
+		{"median", FilterChain{{Name: FILTER_MEDIAN, Window: 100}}},
+		{"savgol", FilterChain{{Name: FILTER_SAVGOL, Window: 100}}},
+		{"butterworth", FilterChain{{Name: FILTER_BUTTERWORTH, Cutoff: 2}}},
+		{"kalman", FilterChain{{Name: FILTER_KALMAN, Q: 0.1, R: 1}}},
+		{"median then ema", FilterChain{{Name: FILTER_MEDIAN, Window: 100}, {Name: FILTER_EMA, Tau: 100}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.chain.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			out := tt.chain.Apply(ts)
+			if len(out.X) != len(ts.X) || len(out.Y) != len(ts.Y) {
+				t.Fatalf("%d x / %d y, want %d", len(out.X), len(out.Y), len(ts.X))
+			}
+			for i := range out.X {
+				if out.X[i] != ts.X[i] || math.Abs(float64(out.Y[i])-3) > 1e-5 {
+					t.Fatalf("sample %d: %d %g, want %d 3", i, out.X[i], out.Y[i], ts.X[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFilterResponse(t *testing.T) {
+
+	/* 10 ms SAMPLING */
+	quad := genTS(200, func(i int) float64 { return 0.001 * float64((i-100)*(i-100)) })
+	spike := genTS(200, func(i int) float64 {
+		if i == 100 {
+			return 50
+		}
+		return 1
+	})
+	step := genTS(200, func(i int) float64 { return float64(min(1, i/100)) })
+	for _, ts := range []*TSXY{&quad, &spike, &step} {
+		for i := range ts.X {
+			ts.X[i] *= 10
+		}
+	}
+
+	tests := []struct {
+		name  string
+		cfg   FilterConfig
+		ts    TSXY
+		check func(out TSXY) bool
+	}{
+		{"savgol keeps a quadratic", FilterConfig{Name: FILTER_SAVGOL, Window: 100}, quad, func(out TSXY) bool {
+			for i := range out.Y {
+				if math.Abs(float64(out.Y[i]-quad.Y[i])) > 1e-4 {
+					return false
+				}
+			}
+			return true
+		}},
+		{"median drops a spike", FilterConfig{Name: FILTER_MEDIAN, Window: 50}, spike, func(out TSXY) bool {
+			return out.Y[100] == 1
+		}},
+		/* THE EMA SEES THE STEP START AT THE LAST SAMPLE BEFORE IT */
+		{"ema reaches 1 - 1/e after tau", FilterConfig{Name: FILTER_EMA, Tau: 200}, step, func(out TSXY) bool {
+			return math.Abs(float64(out.Y[119])-(1-math.Exp(-1))) < 1e-5
+		}},
+		{"butterworth settles on a step", FilterConfig{Name: FILTER_BUTTERWORTH, Cutoff: 5}, step, func(out TSXY) bool {
+			return out.Y[100] < 0.1 && math.Abs(float64(out.Y[199])-1) < 0.01
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			tt.cfg.Defaults()
+			out := FilterChain{tt.cfg}.Apply(tt.ts)
+			if len(out.Y) != len(tt.ts.Y) || !tt.check(out) {
+				t.Fatalf("%v", out.Y)
+			}
+		})
+	}
+}
+
+func TestFilterWindowMaxSide(t *testing.T) {
+
+	/* 1 ms SAMPLING UNDER THE LONGEST WINDOW: THE WINDOW NARROWS TO FILTER_WINDOW_MAX_SIDE SAMPLES EITHER SIDE */
+	n := 4*FILTER_WINDOW_MAX_SIDE + 1
+	ramp := genTS(n, func(i int) float64 { return float64(i) })
+	out := FilterChain{{Name: FILTER_MEDIAN, Window: FILTER_MAX_WINDOW}}.Apply(ramp)
+	if len(out.Y) != n {
+		t.Fatalf("%d samples, want %d", len(out.Y), n)
+	}
+	/* A RAMP'S CENTERED MEDIAN IS ITSELF; THE ENDS SEE ONLY ONE SIDE */
+	if mid := n / 2; out.Y[mid] != ramp.Y[mid] {
+		t.Fatalf("median at %d is %g", mid, out.Y[mid])
+	}
+	if want := float32(FILTER_WINDOW_MAX_SIDE) / 2; out.Y[0] != want {
+		t.Fatalf("median at 0 is %g, want %g", out.Y[0], want)
+	}
+}
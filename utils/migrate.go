@@ -0,0 +1,218 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+
+	"gorm.io/gorm"
+)
+
+/* MIGRATE TO THE NEWEST VERSION */
+const MIGRATE_LATEST = int64(-1)
+
+/* 
+ONE SCHEMA CHANGE; Up AND Down RUN IN A TRANSACTION
+MIGRATIONS MUST NOT USE LIVE MODEL STRUCTS; THOSE CHANGE AFTER THE MIGRATION IS WRITTEN
+*/
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+/* ONE ROW PER APPLIED MIGRATION */
+type SchemaMigration struct {
+	Version   int64  `gorm:"primaryKey; autoIncrement:false" json:"version"`
+	Name      string `json:"name"`
+	AppliedAt int64  `json:"applied_at"` // Time:milli
+}
+func (SchemaMigration) TableName() string { return "schema_migrations" }
+
+/* HIGHEST APPLIED VERSION; 0 FOR AN UNVERSIONED DATABASE */
+func SchemaVersion(db *gorm.DB) (version int64, err error) {
+	if !db.Migrator().HasTable(SchemaMigration{}) {
+		return
+	}
+	res := db.Model(&SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version)
+	err = res.Error
+	return
+}
+
+func LatestMigration(migs []Migration) (version int64) {
+	for _, m := range migs {
+		if m.Version > version {
+			version = m.Version
+		}
+	}
+	return
+}
+
+/* 
+MOVES THE SCHEMA UP OR DOWN TO target AND RETURNS THE MIGRATIONS RUN, IN ORDER
+REFUSES TO RUN AGAINST A SCHEMA NEWER THAN THIS BUILD KNOWS ABOUT
+dryRun RUNS EVERYTHING IN ONE TRANSACTION AND ROLLS IT BACK
+*/
+func Migrate(db *gorm.DB, migs []Migration, target int64, dryRun bool) (ran []Migration, err error) {
+
+	sort.Slice(migs, func(i, j int) bool { return migs[i].Version < migs[j].Version })
+	for i := 1; i < len(migs); i++ {
+		if migs[i].Version == migs[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d", migs[i].Version)
+		}
+	}
+
+	latest := LatestMigration(migs)
+	if target == MIGRATE_LATEST {
+		target = latest
+	}
+	if target < 0 || target > latest {
+		return nil, fmt.Errorf("unknown migration target %d; latest is %d", target, latest)
+	}
+
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return
+	}
+	if current > latest {
+		return nil, fmt.Errorf("database schema version %d is newer than this build ( %d ); refusing to start", current, latest)
+	}
+
+	/* PICK THE MIGRATIONS TO RUN */
+	if target >= current {
+		for _, m := range migs {
+			if m.Version > current && m.Version <= target {
+				ran = append(ran, m)
+			}
+		}
+	} else {
+		for i := len(migs) - 1; i >= 0; i-- {
+			if migs[i].Version <= current && migs[i].Version > target {
+				ran = append(ran, migs[i])
+			}
+		}
+	}
+	up := target >= current
+
+	/* SQLITE REBUILDS A TABLE TO ALTER IT; THE DROP WOULD CASCADE INTO CHILD ROWS WITH FOREIGN KEYS ON */
+	if db.Dialector.Name() == DB_DRIVER_SQLITE {
+		err = db.Connection(func(conn *gorm.DB) (err error) {
+			if err = conn.Exec("PRAGMA foreign_keys = OFF").Error; err != nil {
+				return
+			}
+			defer conn.Exec("PRAGMA foreign_keys = ON")
+			return applyMigrations(conn, ran, up, dryRun, checkForeignKeys)
+		})
+		return
+	}
+	err = applyMigrations(db, ran, up, dryRun, nil)
+	return
+}
+
+/* check, IF GIVEN, RUNS IN EACH MIGRATION'S TRANSACTION AFTER ITS STEP */
+func applyMigrations(db *gorm.DB, ran []Migration, up, dryRun bool, check func(tx *gorm.DB) error) (err error) {
+
+	step := func(tx *gorm.DB, m Migration) (err error) {
+		if err = runMigration(tx, m, up); err != nil || check == nil {
+			return
+		}
+		if err = check(tx); err != nil {
+			err = fmt.Errorf("migration %04d %s %s failed: %s", m.Version, m.Name, direction(up), err.Error())
+		}
+		return
+	}
+
+	if dryRun {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if step_err := ensureSchemaMigrations(tx); step_err != nil {
+				return step_err
+			}
+			for _, m := range ran {
+				if step_err := step(tx, m); step_err != nil {
+					return step_err
+				}
+			}
+			return errDryRun
+		})
+		if errors.Is(err, errDryRun) {
+			err = nil
+		}
+		return
+	}
+
+	if err = ensureSchemaMigrations(db); err != nil {
+		return
+	}
+
+	for _, m := range ran {
+		if err = db.Transaction(func(tx *gorm.DB) error { return step(tx, m) }); err != nil {
+			return
+		}
+		log.Info(fmt.Sprintf("MIGRATION %04d %s : %s : OK", m.Version, direction(up), m.Name))
+	}
+	return
+}
+
+/* SQLITE ONLY; FAILS IF ANY ROW REFERENCES A MISSING PARENT */
+func checkForeignKeys(tx *gorm.DB) (err error) {
+	rows, err := tx.Raw("PRAGMA foreign_key_check").Rows()
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	bad := map[string]int{}
+	for rows.Next() {
+		var table, parent string
+		var rowid, fkid interface{}
+		if err = rows.Scan(&table, &rowid, &parent, &fkid); err != nil {
+			return
+		}
+		bad[table]++
+	}
+	if len(bad) > 0 {
+		err = fmt.Errorf("foreign key violations: %v", bad)
+	}
+	return
+}
+
+var errDryRun = errors.New("dry run")
+
+func ensureSchemaMigrations(db *gorm.DB) (err error) {
+	if err = db.Migrator().AutoMigrate(SchemaMigration{}); err != nil {
+		err = fmt.Errorf("failed to create %s: %s", SchemaMigration{}.TableName(), err.Error())
+	}
+	return
+}
+
+func direction(up bool) string {
+	if up {
+		return "UP"
+	}
+	return "DOWN"
+}
+
+func runMigration(tx *gorm.DB, m Migration, up bool) (err error) {
+
+	step := m.Up
+	if !up {
+		step = m.Down
+	}
+	if step == nil {
+		return fmt.Errorf("migration %04d %s has no %s step", m.Version, m.Name, direction(up))
+	}
+
+	if err = step(tx); err != nil {
+		return fmt.Errorf("migration %04d %s %s failed: %s", m.Version, m.Name, direction(up), err.Error())
+	}
+
+	if up {
+		res := tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now().UTC().UnixMilli()})
+		return res.Error
+	}
+	return tx.Delete(&SchemaMigration{}, m.Version).Error
+}
@@ -0,0 +1,271 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+)
+
+const OUTLIER_METHOD_HAMPEL string = "hampel"
+const OUTLIER_METHOD_MAD string = "mad"
+const OUTLIER_METHOD_IQR string = "iqr"
+
+/* DEFAULTS FOR ZERO OutlierConfig FIELDS */
+const OUTLIER_HAMPEL_WINDOW int = 7 // SAMPLES ON EACH SIDE
+const OUTLIER_HAMPEL_THRESHOLD float64 = 3
+const OUTLIER_MAD_THRESHOLD float64 = 3.5 // IGLEWICZ AND HOAGLIN
+const OUTLIER_IQR_THRESHOLD float64 = 1.5 // TUKEY FENCES
+const OUTLIER_BAD_FACTOR float64 = 2      // BadThreshold = Threshold * OUTLIER_BAD_FACTOR
+
+const OUTLIER_MAX_WINDOW int = 500 // SAMPLES ON EACH SIDE
+
+/* MAD TO STD DEV FOR NORMAL DATA */
+const MAD_SIGMA float64 = 1.4826
+
+/*
+hampel: DISTANCE FROM THE ROLLING MEDIAN OF 2 * Window + 1 SAMPLES, IN ROLLING MAD SIGMAS
+mad:    DISTANCE FROM THE MEDIAN, IN MAD SIGMAS ( ROBUST z-SCORE )
+iqr:    DISTANCE BEYOND THE QUARTILES, IN IQRs
+A SCORE ABOVE Threshold IS SUSPECT; ABOVE BadThreshold IT IS BAD
+*/
+type OutlierConfig struct {
+	Method       string  `json:"method"` // hampel ( DEFAULT ) | mad | iqr
+	Window       int     `json:"window"` // hampel ONLY
+	Threshold    float64 `json:"threshold"`
+	BadThreshold float64 `json:"bad_threshold"`
+}
+
+func (cfg *OutlierConfig) Defaults() {
+	if cfg.Method == "" {
+		cfg.Method = OUTLIER_METHOD_HAMPEL
+	}
+	if cfg.Window == 0 {
+		cfg.Window = OUTLIER_HAMPEL_WINDOW
+	}
+	if cfg.Threshold == 0 {
+		switch cfg.Method {
+		case OUTLIER_METHOD_MAD:
+			cfg.Threshold = OUTLIER_MAD_THRESHOLD
+		case OUTLIER_METHOD_IQR:
+			cfg.Threshold = OUTLIER_IQR_THRESHOLD
+		default:
+			cfg.Threshold = OUTLIER_HAMPEL_THRESHOLD
+		}
+	}
+	if cfg.BadThreshold == 0 {
+		cfg.BadThreshold = cfg.Threshold * OUTLIER_BAD_FACTOR
+	}
+}
+
+func (cfg *OutlierConfig) Validate() (err error) {
+	switch cfg.Method {
+	case "", OUTLIER_METHOD_HAMPEL, OUTLIER_METHOD_MAD, OUTLIER_METHOD_IQR:
+	default:
+		return fmt.Errorf("invalid outlier method: %s", cfg.Method)
+	}
+	switch {
+	case cfg.Window < 0 || cfg.Window > OUTLIER_MAX_WINDOW:
+		err = fmt.Errorf("outlier window must be 0 to %d", OUTLIER_MAX_WINDOW)
+	case cfg.Threshold < 0 || cfg.BadThreshold < 0:
+		err = fmt.Errorf("outlier thresholds must not be negative")
+	case cfg.BadThreshold != 0 && cfg.BadThreshold < cfg.Threshold:
+		err = fmt.Errorf("outlier bad_threshold is below threshold")
+	}
+	return
+}
+
+type Outlier struct {
+	Index   int     `json:"index"`
+	X       int64   `json:"x"`
+	Y       float32 `json:"y"`
+	Score   float64 `json:"score"`
+	Quality uint8   `json:"quality"` // QUALITY_SUSPECT OR QUALITY_BAD
+}
+
+/* SAMPLES OF ts SCORING ABOVE cfg.Threshold; cfg SHOULD HAVE ITS DEFAULTS */
+func FindOutliers(ts TSXY, cfg OutlierConfig) (outs []Outlier) {
+	for i, score := range OutlierScores(ts.Y, cfg) {
+		if score <= cfg.Threshold {
+			continue
+		}
+		q := QUALITY_SUSPECT
+		if score > cfg.BadThreshold {
+			q = QUALITY_BAD
+		}
+		outs = append(outs, Outlier{Index: i, X: ts.X[i], Y: ts.Y[i], Score: score, Quality: q})
+	}
+	return
+}
+
+func OutlierScores(ys []float32, cfg OutlierConfig) (scores []float64) {
+	switch cfg.Method {
+	case OUTLIER_METHOD_MAD:
+		return MADScores(ys)
+	case OUTLIER_METHOD_IQR:
+		return IQRScores(ys)
+	}
+	return HampelScores(ys, cfg.Window)
+}
+
+/*
+ROLLING MEDIAN AND MAD OVER half SAMPLES EACH SIDE; THE WINDOW SHRINKS AT THE ENDS
+A FLAT WINDOW FALLS BACK TO THE SERIES' NOISE SO A LONE SPIKE IN QUANTIZED DATA STILL SCORES
+THE WINDOW IS KEPT SORTED AS IT SLIDES, SO EACH SAMPLE COSTS ONE INSERT AND ONE DELETE
+*/
+func HampelScores(ys []float32, half int) (scores []float64) {
+
+	n := len(ys)
+	scores = make([]float64, n)
+	noise := NoiseSigma(ys)
+	/* NO WINDOW HOLDS MORE THAN THE WHOLE SERIES; half < n ALSO KEEPS 2 * half + 1 FROM OVERFLOWING */
+	size := n
+	if half < n {
+		size = min(2*half+1, n)
+	}
+	win := make([]float64, 0, size)
+	for _, y := range ys[:min(half, n)] {
+		win = insertSorted(win, float64(y))
+	}
+
+	for i := range ys {
+		if half < n-i {
+			win = insertSorted(win, float64(ys[i+half]))
+		}
+		if i > half {
+			win = deleteSorted(win, float64(ys[i-half-1]))
+		}
+
+		med := Quantile(win, 0.5)
+		scale := MAD_SIGMA * sortedMAD(win, med)
+		if scale == 0 {
+			scale = noise
+		}
+		if scale > 0 {
+			scores[i] = math.Abs(float64(ys[i])-med) / scale
+		}
+	}
+	return
+}
+
+func insertSorted(vs []float64, v float64) []float64 {
+	return slices.Insert(vs, sort.SearchFloat64s(vs, v), v)
+}
+
+func deleteSorted(vs []float64, v float64) []float64 {
+	i := sort.SearchFloat64s(vs, v)
+	return slices.Delete(vs, i, i+1)
+}
+
+/*
+MEDIAN ABSOLUTE DEVIATION OF SORTED vs ABOUT med, WITHOUT BUILDING THE DEVIATIONS
+THOSE BELOW med AND THOSE FROM med UP ARE EACH SORTED, SO THE MIDDLE ONES ARE FOUND BY BISECTION
+*/
+func sortedMAD(vs []float64, med float64) float64 {
+
+	n := len(vs)
+	if n == 0 {
+		return math.NaN()
+	}
+	p := sort.SearchFloat64s(vs, med)
+	below := func(i int) float64 { return med - vs[p-1-i] }
+	above := func(i int) float64 { return vs[p+i] - med }
+
+	/* k-TH SMALLEST DEVIATION: BISECT HOW MANY OF THE k + 1 SMALLEST LIE BELOW med */
+	kth := func(k int) float64 {
+		lo, hi := max(0, k+1-(n-p)), min(k+1, p)
+		for lo < hi {
+			i := (lo + hi) / 2
+			if above(k-i) > below(i) {
+				lo = i + 1
+			} else {
+				hi = i
+			}
+		}
+		d := math.Inf(-1)
+		if lo > 0 {
+			d = below(lo - 1)
+		}
+		if j := k + 1 - lo; j > 0 {
+			d = math.Max(d, above(j-1))
+		}
+		return d
+	}
+
+	if n%2 == 1 {
+		return kth(n / 2)
+	}
+	return (kth(n/2-1) + kth(n/2)) / 2
+}
+
+/* ROBUST z-SCORES ABOUT THE MEDIAN OF THE WHOLE SERIES */
+func MADScores(ys []float32) (scores []float64) {
+
+	vs := make([]float64, len(ys))
+	for i, y := range ys {
+		vs[i] = float64(y)
+	}
+	med := Median(append([]float64{}, vs...))
+	dev := make([]float64, len(vs))
+	for i, v := range vs {
+		dev[i] = math.Abs(v - med)
+	}
+	scale := MAD_SIGMA * Median(dev)
+	if scale == 0 {
+		scale = NoiseSigma(ys)
+	}
+
+	scores = make([]float64, len(ys))
+	if scale == 0 {
+		return
+	}
+	for i, v := range vs {
+		scores[i] = math.Abs(v-med) / scale
+	}
+	return
+}
+
+/* DISTANCE OUTSIDE [ Q1, Q3 ] IN IQRs; 0 INSIDE */
+func IQRScores(ys []float32) (scores []float64) {
+
+	vs := make([]float64, len(ys))
+	for i, y := range ys {
+		vs[i] = float64(y)
+	}
+	sort.Float64s(vs)
+	q1, q3 := Quantile(vs, 0.25), Quantile(vs, 0.75)
+	iqr := q3 - q1
+
+	scores = make([]float64, len(ys))
+	if iqr == 0 {
+		return
+	}
+	for i, y := range ys {
+		v := float64(y)
+		scores[i] = math.Max(0, math.Max(q1-v, v-q3)) / iqr
+	}
+	return
+}
+
+/* MEDIAN OF vs; REORDERS vs */
+func Median(vs []float64) float64 {
+	if len(vs) == 0 {
+		return math.NaN()
+	}
+	sort.Float64s(vs)
+	return Quantile(vs, 0.5)
+}
+
+/* LINEARLY INTERPOLATED QUANTILE OF SORTED vs */
+func Quantile(sorted []float64, p float64) float64 {
+	n := len(sorted)
+	if n == 0 {
+		return math.NaN()
+	}
+	pos := p * float64(n-1)
+	i := int(pos)
+	if i >= n-1 {
+		return sorted[n-1]
+	}
+	return sorted[i] + (pos-float64(i))*(sorted[i+1]-sorted[i])
+}
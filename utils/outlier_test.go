@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestFindOutliers(t *testing.T) {
+
+	/* A SLOW RAMP WITH 0.1 NOISE, ONE SAMPLE 1 HIGH AT 40 AND ONE 5 HIGH AT 70 */
+	rnd := rand.New(rand.NewSource(1))
+	ts := genTS(100, func(i int) float64 {
+		y := float64(i)/100 + 0.1*rnd.NormFloat64()
+		switch i {
+		case 40:
+			y += 1
+		case 70:
+			y += 5
+		}
+		return y
+	})
+
+	/* THE NOISE ALONE REACHES ABOUT 3.5 ROLLING SIGMAS, SO hampel JUDGES FROM 5 */
+	tests := []struct {
+		name string
+		cfg  OutlierConfig
+		want map[int]uint8
+	}{
+		{"hampel", OutlierConfig{Threshold: 5}, map[int]uint8{40: QUALITY_SUSPECT, 70: QUALITY_BAD}},
+		{"hampel high threshold", OutlierConfig{Threshold: 20, BadThreshold: 50}, map[int]uint8{70: QUALITY_BAD}},
+		{"mad misses the small bump against the ramp", OutlierConfig{Method: OUTLIER_METHOD_MAD}, map[int]uint8{70: QUALITY_BAD}},
+		{"iqr", OutlierConfig{Method: OUTLIER_METHOD_IQR}, map[int]uint8{40: QUALITY_SUSPECT, 70: QUALITY_BAD}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			tt.cfg.Defaults()
+			outs := FindOutliers(ts, tt.cfg)
+			got := map[int]uint8{}
+			for _, out := range outs {
+				got[out.Index] = out.Quality
+				if out.X != ts.X[out.Index] || out.Y != ts.Y[out.Index] {
+					t.Fatalf("outlier %v is not sample %d", out, out.Index)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("outliers %v, want %v", got, tt.want)
+			}
+			for i, q := range tt.want {
+				if got[i] != q {
+					t.Fatalf("outliers %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+/* THE SLIDING WINDOW AGAINST SORTING EVERY WINDOW AFRESH */
+func TestHampelScoresRolling(t *testing.T) {
+
+	rnd := rand.New(rand.NewSource(1))
+	for _, half := range []int{0, 1, 2, 7, 30, 1 << 40} {
+		/* QUANTIZED, SO WINDOWS HOLD TIES AND FLAT STRETCHES */
+		ys := make([]float32, 200)
+		for i := range ys {
+			ys[i] = float32(rnd.Intn(5))
+		}
+
+		noise := NoiseSigma(ys)
+		scores := HampelScores(ys, half)
+		for i := range ys {
+			lo, hi := max(0, i-half), min(len(ys), i+half+1)
+			win := []float64{}
+			for _, y := range ys[lo:hi] {
+				win = append(win, float64(y))
+			}
+			med := Median(win)
+			dev := []float64{}
+			for _, w := range win {
+				dev = append(dev, math.Abs(w-med))
+			}
+			scale := MAD_SIGMA * Median(dev)
+			if scale == 0 {
+				scale = noise
+			}
+			want := math.Abs(float64(ys[i])-med) / scale
+			if math.Abs(scores[i]-want) > 1e-9 {
+				t.Fatalf("half %d, sample %d: %g, want %g", half, i, scores[i], want)
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+/* PER-SAMPLE QUALITY FLAGS, STORED WITH THE RAW SAMPLES */
+const QUALITY_GOOD uint8 = 0
+const QUALITY_SUSPECT uint8 = 1
+const QUALITY_BAD uint8 = 2
+const QUALITY_INTERPOLATED uint8 = 3
+const QUALITY_EXCLUDED uint8 = 4 // SET BY HAND
+
+var QUALITY_NAMES = []string{"good", "suspect", "bad", "interpolated", "excluded"}
+
+func QualityName(q uint8) string {
+	if int(q) < len(QUALITY_NAMES) {
+		return QUALITY_NAMES[q]
+	}
+	return fmt.Sprintf("%d", q)
+}
+
+func ParseQuality(name string) (q uint8, err error) {
+	for i, n := range QUALITY_NAMES {
+		if strings.EqualFold(strings.TrimSpace(name), n) {
+			return uint8(i), nil
+		}
+	}
+	err = fmt.Errorf("invalid quality flag: %s", name)
+	return
+}
+
+/* A SET OF QUALITY FLAGS; BIT q IS SET FOR FLAG q */
+type QualityMask uint8
+
+/* COMMA SEPARATED FLAG NAMES; EMPTY GIVES AN EMPTY MASK */
+func ParseQualityMask(csv string) (mask QualityMask, err error) {
+	for _, name := range strings.Split(csv, ",") {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		q, q_err := ParseQuality(name)
+		if q_err != nil {
+			return 0, q_err
+		}
+		mask |= 1 << q
+	}
+	return
+}
+
+func (mask QualityMask) Has(q uint8) bool { return mask&(1<<q) != 0 }
+
+/* THE FLAGS IN THE MASK, LOWEST FIRST */
+func (mask QualityMask) Flags() (qs []uint8) {
+	for q := range QUALITY_NAMES {
+		if mask.Has(uint8(q)) {
+			qs = append(qs, uint8(q))
+		}
+	}
+	return
+}
@@ -0,0 +1,325 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+const REGRESS_POLY string = "poly"
+const REGRESS_WLS string = "wls"
+const REGRESS_THEIL_SEN string = "theil_sen"
+const REGRESS_HUBER string = "huber"
+
+const REGRESS_MAX_DEGREE int = 6
+const HUBER_K float64 = 1.345 // 95% EFFICIENT FOR NORMAL ERRORS
+const HUBER_MAX_ITER int = 50
+const HUBER_TOLERANCE float64 = 1e-8
+
+/* PAIRS OF POINTS THEIL-SEN LOOKS AT; LARGER INPUTS USE A FIXED PSEUDO-RANDOM SAMPLE OF PAIRS */
+const THEIL_SEN_MAX_PAIRS int = 1000000
+
+/* SAMPLING GIVES UP AFTER THIS MANY DRAWS, AND FAILS WITH FEWER THAN THEIL_SEN_MIN_PAIRS OF DISTINCT x */
+const THEIL_SEN_MAX_DRAWS int = 4 * THEIL_SEN_MAX_PAIRS
+const THEIL_SEN_MIN_PAIRS int = THEIL_SEN_MAX_PAIRS / 10
+
+/* y = Coefs[0] + Coefs[1] x + Coefs[2] x^2 ... */
+type Fit struct {
+	Method string    `json:"method"`
+	Coefs  []float64 `json:"coefs"`
+	N      int       `json:"n"`
+
+	R2          float64 `json:"r2"`
+	AdjR2       float64 `json:"adj_r2"`
+	RMSE        float64 `json:"rmse"` // RESIDUAL STD ERROR, sqrt( SSE / ( n - p ) )
+	MaxResidual float64 `json:"max_residual"`
+	Iterations  int     `json:"iterations,omitempty"` // huber ONLY
+}
+
+func (fit *Fit) Predict(x float64) (y float64) {
+	for k := len(fit.Coefs) - 1; k >= 0; k-- {
+		y = y*x + fit.Coefs[k]
+	}
+	return
+}
+
+/* dy/dx AT x */
+func (fit *Fit) Slope(x float64) (m float64) {
+	for k := len(fit.Coefs) - 1; k >= 1; k-- {
+		m = m*x + float64(k)*fit.Coefs[k]
+	}
+	return
+}
+
+/* ORDINARY LEAST SQUARES POLYNOMIAL OF degree */
+func PolyFit(xs, ys []float64, degree int) (fit Fit, err error) {
+	fit, err = WeightedPolyFit(xs, ys, nil, degree)
+	fit.Method = REGRESS_POLY
+	return
+}
+
+/*
+WEIGHTED LEAST SQUARES POLYNOMIAL OF degree; ws == nil WEIGHTS EVERY POINT EQUALLY
+SOLVED BY QR ON CENTERED AND SCALED x, THEN MAPPED BACK TO COEFFICIENTS IN x
+*/
+func WeightedPolyFit(xs, ys, ws []float64, degree int) (fit Fit, err error) {
+
+	fit.Method = REGRESS_WLS
+	if err = checkRegression(xs, ys, ws, degree); err != nil {
+		return
+	}
+
+	n, p := len(xs), degree+1
+	center, scale := spread(xs, ws)
+
+	A := mat.NewDense(n, p, nil)
+	b := mat.NewVecDense(n, nil)
+	for i := range xs {
+		w := 1.0
+		if ws != nil {
+			w = math.Sqrt(ws[i])
+		}
+		u, pow := (xs[i]-center)/scale, w
+		for k := 0; k < p; k++ {
+			A.Set(i, k, pow)
+			pow *= u
+		}
+		b.SetVec(i, w*ys[i])
+	}
+
+	var qr mat.QR
+	qr.Factorize(A)
+	c := mat.NewVecDense(p, nil)
+	if err = qr.SolveVecTo(c, false, b); err != nil {
+		err = fmt.Errorf("regression is singular: %s", err.Error())
+		return
+	}
+
+	fit.Coefs = unscale(c.RawVector().Data, center, scale)
+	fit.score(xs, ys, ws)
+	return
+}
+
+/* MEDIAN OF PAIRWISE SLOPES; TOLERATES UP TO ~29% OUTLIERS */
+func TheilSen(xs, ys []float64) (fit Fit, err error) {
+
+	fit.Method = REGRESS_THEIL_SEN
+	if err = checkRegression(xs, ys, nil, 1); err != nil {
+		return
+	}
+
+	n := len(xs)
+	slopes := []float64{}
+	pair := func(i, j int) {
+		if dx := xs[j] - xs[i]; dx != 0 {
+			slopes = append(slopes, (ys[j]-ys[i])/dx)
+		}
+	}
+	if n*(n-1)/2 <= THEIL_SEN_MAX_PAIRS {
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				pair(i, j)
+			}
+		}
+	} else {
+		rnd := rand.New(rand.NewSource(1))
+		for draw := 0; draw < THEIL_SEN_MAX_DRAWS && len(slopes) < THEIL_SEN_MAX_PAIRS; draw++ {
+			i, j := rnd.Intn(n), rnd.Intn(n)
+			if i != j {
+				pair(i, j)
+			}
+		}
+		if len(slopes) < THEIL_SEN_MIN_PAIRS {
+			err = fmt.Errorf("regression x has too little spread to sample: %d of %d pairs differ in x", len(slopes), THEIL_SEN_MAX_DRAWS)
+			return
+		}
+	}
+	if len(slopes) == 0 {
+		err = fmt.Errorf("regression x has no spread")
+		return
+	}
+
+	m := Median(slopes)
+	rs := make([]float64, n)
+	for i := range xs {
+		rs[i] = ys[i] - m*xs[i]
+	}
+	fit.Coefs = []float64{Median(rs), m}
+	fit.score(xs, ys, nil)
+	return
+}
+
+/*
+HUBER M-ESTIMATE OF A POLYNOMIAL BY ITERATIVELY REWEIGHTED LEAST SQUARES
+RESIDUALS BEYOND k ROBUST SIGMAS ( MAD ) ARE DOWN-WEIGHTED; k <= 0 TAKES HUBER_K
+*/
+func Huber(xs, ys []float64, degree int, k float64) (fit Fit, err error) {
+
+	if k <= 0 {
+		k = HUBER_K
+	}
+	if fit, err = WeightedPolyFit(xs, ys, nil, degree); err != nil {
+		fit.Method = REGRESS_HUBER
+		return
+	}
+
+	ws := make([]float64, len(xs))
+	rs := make([]float64, len(xs))
+	for iter := 1; iter <= HUBER_MAX_ITER; iter++ {
+
+		for i := range xs {
+			rs[i] = math.Abs(ys[i] - fit.Predict(xs[i]))
+		}
+		sigma := MAD_SIGMA * Median(append([]float64{}, rs...))
+		if sigma == 0 {
+			/* AT LEAST HALF THE POINTS FIT EXACTLY */
+			fit.Iterations = iter
+			break
+		}
+		for i, r := range rs {
+			ws[i] = 1
+			if r > k*sigma {
+				ws[i] = k * sigma / r
+			}
+		}
+
+		next, w_err := WeightedPolyFit(xs, ys, ws, degree)
+		if w_err != nil {
+			err = w_err
+			break
+		}
+		change := 0.0
+		for c := range next.Coefs {
+			change = math.Max(change, math.Abs(next.Coefs[c]-fit.Coefs[c])/math.Max(1, math.Abs(fit.Coefs[c])))
+		}
+		fit = next
+		fit.Iterations = iter
+		if change < HUBER_TOLERANCE {
+			break
+		}
+		if iter == HUBER_MAX_ITER {
+			err = fmt.Errorf("huber regression did not converge in %d iterations", HUBER_MAX_ITER)
+		}
+	}
+
+	/* GOODNESS OF FIT OVER EVERY POINT, UNWEIGHTED */
+	fit.Method = REGRESS_HUBER
+	fit.score(xs, ys, nil)
+	return
+}
+
+func checkRegression(xs, ys, ws []float64, degree int) (err error) {
+	switch {
+	case len(xs) != len(ys):
+		return fmt.Errorf("regression length mismatch: %d x / %d y", len(xs), len(ys))
+	case ws != nil && len(ws) != len(xs):
+		return fmt.Errorf("regression length mismatch: %d x / %d weights", len(xs), len(ws))
+	case degree < 0 || degree > REGRESS_MAX_DEGREE:
+		return fmt.Errorf("regression degree must be 0 to %d", REGRESS_MAX_DEGREE)
+	case len(xs) < degree+2:
+		return fmt.Errorf("regression of degree %d needs at least %d points, has %d", degree, degree+2, len(xs))
+	}
+
+	distinct := map[float64]bool{}
+	for i := range xs {
+		if math.IsNaN(xs[i]) || math.IsInf(xs[i], 0) || math.IsNaN(ys[i]) || math.IsInf(ys[i], 0) {
+			return fmt.Errorf("regression input is not finite at %d", i)
+		}
+		if ws != nil {
+			if ws[i] < 0 || math.IsNaN(ws[i]) || math.IsInf(ws[i], 0) {
+				return fmt.Errorf("regression weight %d is negative or not finite", i)
+			}
+			if ws[i] == 0 {
+				continue
+			}
+		}
+		distinct[xs[i]] = true
+	}
+	if len(distinct) <= degree {
+		if degree == 1 {
+			return fmt.Errorf("regression x has no spread")
+		}
+		return fmt.Errorf("regression of degree %d needs %d distinct x, has %d", degree, degree+1, len(distinct))
+	}
+	return
+}
+
+/* WEIGHTED MEAN AND HALF RANGE OF xs, SO THE SCALED x LIE IN ABOUT [ -1, 1 ] */
+func spread(xs, ws []float64) (center, scale float64) {
+	var sum, wsum float64
+	lo, hi := xs[0], xs[0]
+	for i, x := range xs {
+		w := 1.0
+		if ws != nil {
+			w = ws[i]
+		}
+		sum += w * x
+		wsum += w
+		lo, hi = math.Min(lo, x), math.Max(hi, x)
+	}
+	center = sum / wsum
+	if scale = (hi - lo) / 2; scale == 0 {
+		scale = 1
+	}
+	return
+}
+
+/* COEFFICIENTS IN u = ( x - center ) / scale TO COEFFICIENTS IN x */
+func unscale(cu []float64, center, scale float64) (cx []float64) {
+	cx = make([]float64, len(cu))
+	for k, c := range cu {
+		/* c ( x - center )^k / scale^k, EXPANDED BINOMIALLY */
+		f := c / math.Pow(scale, float64(k))
+		binom := 1.0
+		for j := 0; j <= k; j++ {
+			cx[j] += f * binom * math.Pow(-center, float64(k-j))
+			binom = binom * float64(k-j) / float64(j+1)
+		}
+	}
+	return
+}
+
+/* R2, ADJUSTED R2, RMSE AND THE LARGEST |RESIDUAL|; WEIGHTED WHEN ws IS GIVEN */
+func (fit *Fit) score(xs, ys, ws []float64) {
+
+	n, p := len(xs), len(fit.Coefs)
+	var wsum, ymean float64
+	for i := range ys {
+		w := 1.0
+		if ws != nil {
+			w = ws[i]
+		}
+		wsum += w
+		ymean += w * ys[i]
+	}
+	ymean /= wsum
+
+	var sse, sst float64
+	fit.MaxResidual = 0
+	for i := range xs {
+		w := 1.0
+		if ws != nil {
+			w = ws[i]
+		}
+		r := ys[i] - fit.Predict(xs[i])
+		sse += w * r * r
+		sst += w * (ys[i] - ymean) * (ys[i] - ymean)
+		fit.MaxResidual = math.Max(fit.MaxResidual, math.Abs(r))
+	}
+
+	fit.N = n
+	/* CONSTANT y: ANY OF THESE FITS IS EXACT UP TO ROUNDING */
+	fit.R2, fit.AdjR2 = 1, 1
+	if sst > 0 {
+		fit.R2 = 1 - sse/sst
+		fit.AdjR2 = fit.R2
+	}
+	if n > p {
+		if sst > 0 {
+			fit.AdjR2 = 1 - (1-fit.R2)*float64(n-1)/float64(n-p)
+		}
+		fit.RMSE = math.Sqrt(sse / wsum * float64(n) / float64(n-p))
+	}
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRegressionKnownFits(t *testing.T) {
+
+	/* y = 1 + 2x - 0.5x^2 EXACTLY, THEN WITH ONE GROSS OUTLIER */
+	xs := []float64{-3, -2, -1, 0, 1, 2, 3, 4, 5, 6}
+	quad := make([]float64, len(xs))
+	line := make([]float64, len(xs))
+	for i, x := range xs {
+		quad[i] = 1 + 2*x - 0.5*x*x
+		line[i] = 3 - 2*x
+	}
+	bent := append([]float64{}, line...)
+	bent[7] += 100
+
+	tests := []struct {
+		name  string
+		fit   func() (Fit, error)
+		coefs []float64
+		tol   float64
+	}{
+		{"poly line", func() (Fit, error) { return PolyFit(xs, line, 1) }, []float64{3, -2}, 1e-9},
+		{"poly quadratic", func() (Fit, error) { return PolyFit(xs, quad, 2) }, []float64{1, 2, -0.5}, 1e-9},
+		{"theil-sen line", func() (Fit, error) { return TheilSen(xs, line) }, []float64{3, -2}, 1e-9},
+		{"theil-sen outlier", func() (Fit, error) { return TheilSen(xs, bent) }, []float64{3, -2}, 1e-9},
+		{"huber line", func() (Fit, error) { return Huber(xs, line, 1, 0) }, []float64{3, -2}, 1e-9},
+		{"huber outlier", func() (Fit, error) { return Huber(xs, bent, 1, 0) }, []float64{3, -2}, 0.05},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fit, err := tt.fit()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(fit.Coefs) != len(tt.coefs) {
+				t.Fatalf("coefs %v, want %v", fit.Coefs, tt.coefs)
+			}
+			for i := range tt.coefs {
+				if math.Abs(fit.Coefs[i]-tt.coefs[i]) > tt.tol {
+					t.Fatalf("coefs %v, want %v", fit.Coefs, tt.coefs)
+				}
+			}
+			if fit.N != len(xs) {
+				t.Fatalf("n %d, want %d", fit.N, len(xs))
+			}
+		})
+	}
+}
+
+func TestRegressionDegenerateInput(t *testing.T) {
+
+	flat := []float64{2, 2, 2, 2}
+	ys := []float64{1, 2, 3, 4}
+
+	tests := []struct {
+		name string
+		fit  func() (Fit, error)
+	}{
+		{"poly constant x", func() (Fit, error) { return PolyFit(flat, ys, 1) }},
+		{"poly too few points", func() (Fit, error) { return PolyFit(ys[:3], ys[:3], 2) }},
+		{"poly length mismatch", func() (Fit, error) { return PolyFit(ys, ys[:3], 1) }},
+		{"poly degree too high", func() (Fit, error) { return PolyFit(ys, ys, REGRESS_MAX_DEGREE+1) }},
+		{"poly not finite", func() (Fit, error) { return PolyFit(ys, []float64{1, math.NaN(), 3, 4}, 1) }},
+		{"theil-sen constant x", func() (Fit, error) { return TheilSen(flat, ys) }},
+		{"huber constant x", func() (Fit, error) { return Huber(flat, ys, 1, 0) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.fit(); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
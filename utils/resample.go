@@ -0,0 +1,171 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+)
+
+const RESAMPLE_LINEAR string = "linear"
+const RESAMPLE_PREVIOUS string = "previous"
+const RESAMPLE_NEAREST string = "nearest"
+
+const RESAMPLE_MEAN string = "mean"
+const RESAMPLE_MIN string = "min"
+const RESAMPLE_MAX string = "max"
+const RESAMPLE_LAST string = "last"
+
+/*
+A GRID OF N Step-WIDE BUCKETS FROM Start; BUCKET k IS [ Start + k Step, Start + ( k + 1 ) Step )
+A BUCKET HOLDING SAMPLES TAKES THEIR Agg; AN EMPTY ONE IS INTERPOLATED AT ITS CENTER
+A VALUE THAT WOULD BRIDGE MORE THAN MaxGap IS MISSING ( NaN )
+*/
+type ResampleConfig struct {
+	Start  int64  `json:"start"`
+	Step   int64  `json:"step"` // ms
+	N      int    `json:"n"`
+	Interp string `json:"interp"`  // linear ( DEFAULT ) | previous | nearest
+	Agg    string `json:"agg"`     // mean ( DEFAULT ) | min | max | last
+	MaxGap int64  `json:"max_gap"` // ms; 0 IS UNLIMITED
+}
+
+func (cfg *ResampleConfig) Validate() (err error) {
+	switch cfg.Interp {
+	case "", RESAMPLE_LINEAR, RESAMPLE_PREVIOUS, RESAMPLE_NEAREST:
+	default:
+		return fmt.Errorf("invalid resample interpolation: %s", cfg.Interp)
+	}
+	switch cfg.Agg {
+	case "", RESAMPLE_MEAN, RESAMPLE_MIN, RESAMPLE_MAX, RESAMPLE_LAST:
+	default:
+		return fmt.Errorf("invalid resample aggregation: %s", cfg.Agg)
+	}
+	switch {
+	case cfg.Step <= 0:
+		err = fmt.Errorf("resample step must be positive")
+	case cfg.N < 0:
+		err = fmt.Errorf("resample grid size is negative")
+	case cfg.MaxGap < 0:
+		err = fmt.Errorf("resample max_gap is negative")
+	}
+	return
+}
+
+/* BUCKET START TIMES */
+func (cfg *ResampleConfig) Grid() (xs []int64) {
+	xs = make([]int64, cfg.N)
+	for k := range xs {
+		xs[k] = cfg.Start + int64(k)*cfg.Step
+	}
+	return
+}
+
+/* THE SAMPLES Resample CAN USE: THE GRID PLUS A STEP AND MaxGap OF MARGIN EACH SIDE FOR THE EDGE STEPS */
+func (cfg *ResampleConfig) Span() (start, end int64) {
+	margin := cfg.Step + cfg.MaxGap
+	return cfg.Start - margin, cfg.Start + int64(cfg.N)*cfg.Step + margin
+}
+
+/* ts ON THE GRID IN ONE PASS; ts.X MUST BE SORTED */
+func Resample(ts TSXY, cfg ResampleConfig) (ys []float64) {
+
+	ys = make([]float64, cfg.N)
+	n := len(ts.X)
+	j := 0
+
+	for k := range ys {
+		lo := cfg.Start + int64(k)*cfg.Step
+		hi := lo + cfg.Step
+
+		for j < n && ts.X[j] < lo {
+			j++
+		}
+		e := j
+		for e < n && ts.X[e] < hi {
+			e++
+		}
+		if e > j {
+			ys[k] = aggregate(ts.Y[j:e], cfg.Agg)
+			j = e
+			continue
+		}
+
+		/* EMPTY: j-1 IS THE LAST SAMPLE BEFORE THE BUCKET, j THE FIRST AFTER IT */
+		ys[k] = interpolate(ts, j-1, j, lo+cfg.Step/2, cfg)
+	}
+	return
+}
+
+/* EVERY SERIES ON THE SAME GRID; ys[i] BELONGS TO tss[i] */
+func Align(tss []TSXY, cfg ResampleConfig) (xs []int64, ys [][]float64) {
+	xs = cfg.Grid()
+	ys = make([][]float64, len(tss))
+	for i, ts := range tss {
+		ys[i] = Resample(ts, cfg)
+	}
+	return
+}
+
+func aggregate(vs []float32, agg string) float64 {
+	switch agg {
+	case RESAMPLE_LAST:
+		return float64(vs[len(vs)-1])
+	case RESAMPLE_MIN, RESAMPLE_MAX:
+		lo, hi := MinMaxFloat32(vs, 0)
+		if agg == RESAMPLE_MIN {
+			return float64(lo)
+		}
+		return float64(hi)
+	}
+	var sum float64
+	for _, v := range vs {
+		sum += float64(v)
+	}
+	return sum / float64(len(vs))
+}
+
+/* VALUE AT t FROM THE SAMPLES prev < t < next; EITHER INDEX MAY BE OUT OF RANGE */
+func interpolate(ts TSXY, prev, next int, t int64, cfg ResampleConfig) float64 {
+
+	hasPrev, hasNext := prev >= 0, next < len(ts.X)
+	within := func(d int64) bool { return cfg.MaxGap == 0 || d <= cfg.MaxGap }
+
+	switch cfg.Interp {
+
+	case RESAMPLE_PREVIOUS:
+		if hasPrev && within(t-ts.X[prev]) {
+			return float64(ts.Y[prev])
+		}
+
+	case RESAMPLE_NEAREST:
+		use := -1
+		switch {
+		case hasPrev && hasNext:
+			use = prev
+			if ts.X[next]-t < t-ts.X[prev] {
+				use = next
+			}
+		case hasPrev:
+			use = prev
+		case hasNext:
+			use = next
+		}
+		if use >= 0 {
+			d := t - ts.X[use]
+			if d < 0 {
+				d = -d
+			}
+			if within(d) {
+				return float64(ts.Y[use])
+			}
+		}
+
+	default:
+		/* NO EXTRAPOLATION */
+		if hasPrev && hasNext && within(ts.X[next]-ts.X[prev]) {
+			x0, x1 := ts.X[prev], ts.X[next]
+			y0, y1 := float64(ts.Y[prev]), float64(ts.Y[next])
+			return y0 + (y1-y0)*float64(t-x0)/float64(x1-x0)
+		}
+	}
+	return math.NaN()
+}
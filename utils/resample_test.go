@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestResample(t *testing.T) {
+
+	ts := TSXY{X: []int64{0, 5, 20, 60}, Y: []float32{2, 4, 10, 0}}
+	nan := math.NaN()
+
+	tests := []struct {
+		name string
+		cfg  ResampleConfig
+		ys   []float64
+	}{
+		{"linear", ResampleConfig{Step: 10, N: 7},
+			[]float64{3, 8, 10, 6.25, 3.75, 1.25, 0}},
+		{"linear max gap", ResampleConfig{Step: 10, N: 7, MaxGap: 20},
+			[]float64{3, 8, 10, nan, nan, nan, 0}},
+		{"previous", ResampleConfig{Step: 10, N: 7, Interp: RESAMPLE_PREVIOUS},
+			[]float64{3, 4, 10, 10, 10, 10, 0}},
+		{"previous max gap", ResampleConfig{Step: 10, N: 7, Interp: RESAMPLE_PREVIOUS, MaxGap: 20},
+			[]float64{3, 4, 10, 10, nan, nan, 0}},
+		{"nearest", ResampleConfig{Step: 10, N: 7, Interp: RESAMPLE_NEAREST},
+			[]float64{3, 10, 10, 10, 0, 0, 0}},
+		{"max", ResampleConfig{Step: 10, N: 1, Agg: RESAMPLE_MAX}, []float64{4}},
+		{"min", ResampleConfig{Step: 10, N: 1, Agg: RESAMPLE_MIN}, []float64{2}},
+		{"last", ResampleConfig{Step: 10, N: 1, Agg: RESAMPLE_LAST}, []float64{4}},
+		{"no extrapolation", ResampleConfig{Start: -20, Step: 10, N: 1}, []float64{nan}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			ys := Resample(ts, tt.cfg)
+			if len(ys) != len(tt.ys) {
+				t.Fatalf("%v, want %v", ys, tt.ys)
+			}
+			for k := range ys {
+				if math.IsNaN(ys[k]) != math.IsNaN(tt.ys[k]) || math.Abs(ys[k]-tt.ys[k]) > 1e-9 {
+					t.Fatalf("%v, want %v", ys, tt.ys)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+)
+
+const SCORE_PASS string = "pass"
+const SCORE_WARN string = "warn"
+const SCORE_FAIL string = "fail"
+
+/* AGGREGATE STATISTICS A RULE CAN JUDGE; duration IS End - Start IN ms */
+const SCORE_FIELD_MIN string = "min"
+const SCORE_FIELD_MAX string = "max"
+const SCORE_FIELD_MEAN string = "mean"
+const SCORE_FIELD_SLOPE string = "slope"
+const SCORE_FIELD_DEVI string = "devi"
+const SCORE_FIELD_SIZE string = "size"
+const SCORE_FIELD_DURATION string = "duration"
+
+/* DEFAULTS FOR ZERO ScoreRules THRESHOLDS */
+const SCORE_PASS_THRESHOLD float64 = 0.8
+const SCORE_WARN_THRESHOLD float64 = 0.5
+
+const SCORE_RULES_MAX int = 32
+
+/*
+ONE ACCEPTANCE CRITERION: Field SHOULD LIE IN [ Min, Max ], EITHER SIDE MAY BE OPEN
+INSIDE SCORES 1; OUTSIDE, THE SCORE FALLS LINEARLY TO 0 OVER Tolerance, OR AT ONCE IF Tolerance IS 0
+*/
+type ScoreRule struct {
+	Name      string   `json:"name"`
+	Field     string   `json:"field"` // min | max | mean | slope | devi | size | duration
+	Abs       bool     `json:"abs"`   // JUDGE |value|, e.g. slope IN EITHER DIRECTION
+	Min       *float64 `json:"min"`
+	Max       *float64 `json:"max"`
+	Tolerance float64  `json:"tolerance"`
+	Weight    float64  `json:"weight"`   // 0 IS 1
+	Critical  bool     `json:"critical"` // SCORING 0 FAILS THE AGGREGATE WHATEVER THE TOTAL
+}
+
+func (rule *ScoreRule) Validate() (err error) {
+	switch rule.Field {
+	case SCORE_FIELD_MIN, SCORE_FIELD_MAX, SCORE_FIELD_MEAN, SCORE_FIELD_SLOPE,
+		SCORE_FIELD_DEVI, SCORE_FIELD_SIZE, SCORE_FIELD_DURATION:
+	default:
+		return fmt.Errorf("invalid score field: %s", rule.Field)
+	}
+	for _, v := range []*float64{rule.Min, rule.Max, &rule.Tolerance, &rule.Weight} {
+		if v != nil && (math.IsNaN(*v) || math.IsInf(*v, 0)) {
+			return fmt.Errorf("score rule values must be finite")
+		}
+	}
+	switch {
+	case rule.Min == nil && rule.Max == nil:
+		err = fmt.Errorf("score rule needs a min or max")
+	case rule.Min != nil && rule.Max != nil && *rule.Max < *rule.Min:
+		err = fmt.Errorf("score rule max is below min")
+	case rule.Tolerance < 0:
+		err = fmt.Errorf("score rule tolerance is negative")
+	case rule.Weight < 0:
+		err = fmt.Errorf("score rule weight is negative")
+	}
+	return
+}
+
+func (rule *ScoreRule) weight() float64 {
+	if rule.Weight == 0 {
+		return 1
+	}
+	return rule.Weight
+}
+
+/* 1 INSIDE THE RANGE, FALLING TO 0 AT Tolerance OUTSIDE IT */
+func (rule *ScoreRule) Score(st ClusterStats) float64 {
+
+	v := rule.value(st)
+	if rule.Abs {
+		v = math.Abs(v)
+	}
+
+	var dist float64
+	switch {
+	case math.IsNaN(v):
+		return 0
+	case rule.Min != nil && v < *rule.Min:
+		dist = *rule.Min - v
+	case rule.Max != nil && v > *rule.Max:
+		dist = v - *rule.Max
+	default:
+		return 1
+	}
+	if rule.Tolerance == 0 {
+		return 0
+	}
+	return math.Max(0, 1-dist/rule.Tolerance)
+}
+
+func (rule *ScoreRule) value(st ClusterStats) float64 {
+	switch rule.Field {
+	case SCORE_FIELD_MIN:
+		return float64(st.Min)
+	case SCORE_FIELD_MAX:
+		return float64(st.Max)
+	case SCORE_FIELD_MEAN:
+		return float64(st.Mean)
+	case SCORE_FIELD_SLOPE:
+		return float64(st.Slope)
+	case SCORE_FIELD_DEVI:
+		return float64(st.Devi)
+	case SCORE_FIELD_SIZE:
+		return float64(st.Size)
+	case SCORE_FIELD_DURATION:
+		return float64(st.End - st.Start)
+	}
+	return math.NaN()
+}
+
+/*
+THE SCORE IS THE WEIGHTED MEAN OF THE RULE SCORES, IN [ 0, 1 ]
+AT OR ABOVE Pass IS pass, AT OR ABOVE Warn IS warn, BELOW IS fail
+*/
+type ScoreRules struct {
+	Rules []ScoreRule `gorm:"serializer:json" json:"rules"`
+	Pass  float64     `json:"pass"`
+	Warn  float64     `json:"warn"`
+}
+
+func (rs *ScoreRules) Defaults() {
+	if rs.Pass == 0 {
+		rs.Pass = SCORE_PASS_THRESHOLD
+	}
+	if rs.Warn == 0 {
+		rs.Warn = math.Min(SCORE_WARN_THRESHOLD, rs.Pass)
+	}
+	for i := range rs.Rules {
+		if rs.Rules[i].Name == "" {
+			rs.Rules[i].Name = rs.Rules[i].Field
+		}
+	}
+}
+
+func (rs *ScoreRules) Validate() (err error) {
+
+	if len(rs.Rules) == 0 || len(rs.Rules) > SCORE_RULES_MAX {
+		return fmt.Errorf("score rule set needs 1 to %d rules", SCORE_RULES_MAX)
+	}
+	for i := range rs.Rules {
+		if err = rs.Rules[i].Validate(); err != nil {
+			return fmt.Errorf("score rule %d: %s", i+1, err.Error())
+		}
+	}
+
+	pass := rs.Pass
+	if pass == 0 {
+		pass = SCORE_PASS_THRESHOLD
+	}
+	switch {
+	case rs.Pass < 0 || rs.Pass > 1 || rs.Warn < 0 || rs.Warn > 1:
+		err = fmt.Errorf("score pass and warn thresholds must be between 0 and 1")
+	case rs.Warn > pass:
+		err = fmt.Errorf("score warn threshold is above pass")
+	}
+	return
+}
+
+/* rs MUST BE VALID, WITH DEFAULTS APPLIED */
+func (rs *ScoreRules) Evaluate(st ClusterStats) (score float64, verdict string) {
+
+	var sum, weight float64
+	critical := false
+	for i := range rs.Rules {
+		rule := &rs.Rules[i]
+		s := rule.Score(st)
+		sum += rule.weight() * s
+		weight += rule.weight()
+		critical = critical || (rule.Critical && s == 0)
+	}
+	score = sum / weight
+
+	switch {
+	case critical:
+		verdict = SCORE_FAIL
+	case score >= rs.Pass:
+		verdict = SCORE_PASS
+	case score >= rs.Warn:
+		verdict = SCORE_WARN
+	default:
+		verdict = SCORE_FAIL
+	}
+	return
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScoreVerdicts(t *testing.T) {
+
+	/* mean IN [ 10, 20 ] WEIGHS 4, FALLING TO 0 AT 8 OUTSIDE; |slope| AT MOST 1 WEIGHS 1 AND IS CRITICAL */
+	rs := ScoreRules{Rules: []ScoreRule{
+		{Field: SCORE_FIELD_MEAN, Min: ptr(10.0), Max: ptr(20.0), Tolerance: 8, Weight: 4},
+		{Field: SCORE_FIELD_SLOPE, Abs: true, Max: ptr(1.0), Critical: true},
+	}}
+	if err := rs.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	rs.Defaults()
+
+	tests := []struct {
+		name    string
+		mean    float32
+		slope   float32
+		score   float64
+		verdict string
+	}{
+		{"inside", 15, 0.5, 1, SCORE_PASS},
+		{"on the pass threshold", 22, -0.5, 0.8, SCORE_PASS},
+		{"under pass", 22.5, 0, 0.75, SCORE_WARN},
+		{"on the warn threshold", 5, 0, 0.5, SCORE_WARN},
+		{"under warn", 26, 0, 0.4, SCORE_FAIL},
+		{"beyond tolerance", 40, 0, 0.2, SCORE_FAIL},
+		{"critical rule fails", 15, -2, 0.8, SCORE_FAIL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, verdict := rs.Evaluate(ClusterStats{Mean: tt.mean, Slope: tt.slope})
+			if math.Abs(score-tt.score) > 1e-6 || verdict != tt.verdict {
+				t.Fatalf("%g %s, want %g %s", score, verdict, tt.score, tt.verdict)
+			}
+		})
+	}
+}
+
+func TestScoreRulesValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		rs   ScoreRules
+	}{
+		{"no rules", ScoreRules{}},
+		{"unknown field", ScoreRules{Rules: []ScoreRule{{Field: "median", Max: ptr(1.0)}}}},
+		{"open both sides", ScoreRules{Rules: []ScoreRule{{Field: SCORE_FIELD_MEAN}}}},
+		{"max below min", ScoreRules{Rules: []ScoreRule{{Field: SCORE_FIELD_MEAN, Min: ptr(2.0), Max: ptr(1.0)}}}},
+		{"not finite", ScoreRules{Rules: []ScoreRule{{Field: SCORE_FIELD_MEAN, Max: ptr(math.Inf(1))}}}},
+		{"warn above pass", ScoreRules{Rules: []ScoreRule{{Field: SCORE_FIELD_MEAN, Max: ptr(1.0)}}, Pass: 0.6, Warn: 0.7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rs.Validate(); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
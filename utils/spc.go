@@ -0,0 +1,491 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+)
+
+const SPC_CHART_XBAR_R string = "xbar_r"
+const SPC_CHART_XBAR_S string = "xbar_s"
+const SPC_CHART_IMR string = "imr"
+const SPC_CHART_EWMA string = "ewma"
+
+const SPC_SOURCE_AGGREGATES string = "aggregates"
+const SPC_SOURCE_SAMPLES string = "samples"
+
+const SPC_RULES_WESTERN_ELECTRIC string = "western_electric"
+const SPC_RULES_NELSON string = "nelson"
+
+/* DEFAULTS FOR ZERO SPCConfig FIELDS */
+const SPC_SUBGROUP_SIZE int = 5
+const SPC_EWMA_LAMBDA float64 = 0.2
+const SPC_EWMA_L float64 = 3
+
+/* RULE IDS RECORDED ON FLAGGED POINTS */
+const SPC_RULE_SPREAD string = "spread" // R, S OR MR BEYOND ITS LIMITS
+const SPC_RULE_EWMA string = "ewma"     // EWMA STATISTIC BEYOND ITS LIMITS
+
+var SPC_RULE_TEXT = map[string]string{
+	"we1":           "1 point beyond 3 sigma",
+	"we2":           "2 of 3 points beyond 2 sigma on one side",
+	"we3":           "4 of 5 points beyond 1 sigma on one side",
+	"we4":           "8 points in a row on one side of the center line",
+	"n1":            "1 point beyond 3 sigma",
+	"n2":            "9 points in a row on one side of the center line",
+	"n3":            "6 points in a row steadily increasing or decreasing",
+	"n4":            "14 points in a row alternating up and down",
+	"n5":            "2 of 3 points beyond 2 sigma on one side",
+	"n6":            "4 of 5 points beyond 1 sigma on one side",
+	"n7":            "15 points in a row within 1 sigma",
+	"n8":            "8 points in a row beyond 1 sigma on either side",
+	SPC_RULE_SPREAD: "dispersion beyond its control limits",
+	SPC_RULE_EWMA:   "EWMA beyond its control limits",
+}
+
+/*
+CONTROL LIMITS COME FROM [ BaselineStart, BaselineEnd ); BaselineEnd == 0 USES THE CHARTED WINDOW ITSELF
+aggregates: EACH AGGREGATE IS A SUBGROUP; samples: Subgroup CONSECUTIVE SAMPLES ( 1 FOR imr / ewma )
+*/
+type SPCConfig struct {
+	Chart         string  `json:"chart"`    // xbar_r | xbar_s | imr | ewma; EMPTY DISABLES SPC
+	Source        string  `json:"source"`   // aggregates ( DEFAULT ) | samples
+	Subgroup      int     `json:"subgroup"` // SAMPLES PER SUBGROUP FOR xbar CHARTS ON samples
+	Rules         string  `json:"rules"`    // western_electric ( DEFAULT ) | nelson
+	BaselineStart int64   `json:"baseline_start"`
+	BaselineEnd   int64   `json:"baseline_end"`
+	Lambda        float64 `json:"lambda"` // EWMA WEIGHT OF THE NEWEST POINT
+	L             float64 `json:"l"`      // EWMA LIMIT WIDTH IN SIGMAS
+}
+
+func (cfg *SPCConfig) Enabled() bool { return cfg.Chart != "" }
+
+/* FILLS ZERO FIELDS WITH THE DEFAULTS */
+func (cfg *SPCConfig) Defaults() {
+	if cfg.Source == "" {
+		cfg.Source = SPC_SOURCE_AGGREGATES
+	}
+	if cfg.Rules == "" {
+		cfg.Rules = SPC_RULES_WESTERN_ELECTRIC
+	}
+	switch {
+	case cfg.Chart == SPC_CHART_IMR || cfg.Chart == SPC_CHART_EWMA:
+		cfg.Subgroup = 1
+	case cfg.Subgroup == 0:
+		cfg.Subgroup = SPC_SUBGROUP_SIZE
+	}
+	if cfg.Lambda == 0 {
+		cfg.Lambda = SPC_EWMA_LAMBDA
+	}
+	if cfg.L == 0 {
+		cfg.L = SPC_EWMA_L
+	}
+}
+
+func (cfg *SPCConfig) Validate() (err error) {
+	switch cfg.Chart {
+	case "", SPC_CHART_XBAR_R, SPC_CHART_XBAR_S, SPC_CHART_IMR, SPC_CHART_EWMA:
+	default:
+		return fmt.Errorf("invalid spc chart: %s", cfg.Chart)
+	}
+	switch cfg.Source {
+	case "", SPC_SOURCE_AGGREGATES, SPC_SOURCE_SAMPLES:
+	default:
+		return fmt.Errorf("invalid spc source: %s", cfg.Source)
+	}
+	switch cfg.Rules {
+	case "", SPC_RULES_WESTERN_ELECTRIC, SPC_RULES_NELSON:
+	default:
+		return fmt.Errorf("invalid spc rules: %s", cfg.Rules)
+	}
+	xbar := cfg.Chart == SPC_CHART_XBAR_R || cfg.Chart == SPC_CHART_XBAR_S
+	switch {
+	case cfg.Subgroup < 0 || (xbar && cfg.Subgroup == 1):
+		err = fmt.Errorf("spc subgroup must be at least 2")
+	case cfg.Chart == SPC_CHART_XBAR_R && cfg.Subgroup > len(SPC_D2)+1:
+		err = fmt.Errorf("spc subgroup above %d; use %s", len(SPC_D2)+1, SPC_CHART_XBAR_S)
+	case cfg.Lambda < 0 || cfg.Lambda > 1:
+		err = fmt.Errorf("spc lambda must be in ( 0, 1 ]")
+	case cfg.L < 0:
+		err = fmt.Errorf("spc l is negative")
+	case cfg.BaselineEnd != 0 && cfg.BaselineEnd <= cfg.BaselineStart:
+		err = fmt.Errorf("spc baseline_end must be after baseline_start")
+	}
+	return
+}
+
+/* d2 AND d3 FOR SUBGROUPS OF 2 TO 25; INDEX n - 2 */
+var SPC_D2 = []float64{
+	1.128, 1.693, 2.059, 2.326, 2.534, 2.704, 2.847, 2.970, 3.078, 3.173, 3.258, 3.336,
+	3.407, 3.472, 3.532, 3.588, 3.640, 3.689, 3.735, 3.778, 3.819, 3.858, 3.895, 3.931,
+}
+var SPC_D3 = []float64{
+	0.853, 0.888, 0.880, 0.864, 0.848, 0.833, 0.820, 0.808, 0.797, 0.787, 0.778, 0.770,
+	0.763, 0.756, 0.750, 0.744, 0.739, 0.734, 0.729, 0.724, 0.720, 0.716, 0.712, 0.708,
+}
+
+/* UNBIASING CONSTANT FOR THE SAMPLE STD DEV OF n VALUES */
+func SPCC4(n int) float64 {
+	if n < 2 {
+		return 1
+	}
+	g1, _ := math.Lgamma(float64(n) / 2)
+	g2, _ := math.Lgamma(float64(n-1) / 2)
+	return math.Sqrt(2/float64(n-1)) * math.Exp(g1-g2)
+}
+
+/* ONE RATIONAL SUBGROUP; X AND End ARE ITS FIRST AND LAST SAMPLE TIMES */
+type Subgroup struct {
+	X     int64
+	End   int64
+	N     int
+	Mean  float64
+	Range float64
+	Devi  float64 // SAMPLE STD DEV
+}
+
+/* SPLITS ts INTO CONSECUTIVE SUBGROUPS OF size; A SHORT TAIL IS DROPPED */
+func Subgroups(ts TSXY, size int) (sgs []Subgroup) {
+	if size < 1 {
+		return
+	}
+	for i := 0; i+size <= len(ts.Y); i += size {
+		ys := ts.Y[i : i+size]
+		mean, variance := MeanVariance(ys)
+		lo, hi := MinMaxFloat32(ys, 0)
+		sg := Subgroup{X: ts.X[i], End: ts.X[i+size-1], N: size, Mean: mean, Range: float64(hi - lo)}
+		if size > 1 {
+			sg.Devi = math.Sqrt(variance)
+		}
+		sgs = append(sgs, sg)
+	}
+	return
+}
+
+/* PHASE I ESTIMATES; Sigma IS THE WITHIN SUBGROUP ( SHORT TERM ) STD DEV OF ONE SAMPLE */
+type SPCLimits struct {
+	Center float64 `json:"center"`
+	Sigma  float64 `json:"sigma"`
+	Groups int     `json:"groups"` // BASELINE SUBGROUPS
+}
+
+func SPCEstimate(cfg SPCConfig, base []Subgroup) (lim SPCLimits, err error) {
+
+	lim.Groups = len(base)
+	if len(base) < 2 {
+		err = fmt.Errorf("spc baseline needs at least 2 subgroups, has %d", len(base))
+		return
+	}
+
+	var sum, weight, sigma float64
+	switch cfg.Chart {
+
+	case SPC_CHART_XBAR_R, SPC_CHART_XBAR_S:
+		for _, sg := range base {
+			sum += float64(sg.N) * sg.Mean
+			weight += float64(sg.N)
+			if cfg.Chart == SPC_CHART_XBAR_S {
+				if sg.N < 2 {
+					err = fmt.Errorf("spc subgroup at %d has fewer than 2 samples", sg.X)
+					return
+				}
+				sigma += sg.Devi / SPCC4(sg.N)
+				continue
+			}
+			if sg.N < 2 || sg.N > len(SPC_D2)+1 {
+				err = fmt.Errorf("spc subgroup at %d has %d samples; %s needs 2 to %d, use %s",
+					sg.X, sg.N, SPC_CHART_XBAR_R, len(SPC_D2)+1, SPC_CHART_XBAR_S)
+				return
+			}
+			sigma += sg.Range / SPC_D2[sg.N-2]
+		}
+		sigma /= float64(len(base))
+
+	case SPC_CHART_IMR, SPC_CHART_EWMA:
+		for i, sg := range base {
+			sum += sg.Mean
+			weight++
+			if i > 0 {
+				sigma += math.Abs(sg.Mean - base[i-1].Mean)
+			}
+		}
+		sigma /= float64(len(base)-1) * SPC_D2[0]
+
+	default:
+		err = fmt.Errorf("invalid spc chart: %s", cfg.Chart)
+		return
+	}
+
+	lim.Center = sum / weight
+	lim.Sigma = sigma
+	if lim.Sigma == 0 || math.IsNaN(lim.Sigma) {
+		err = fmt.Errorf("spc baseline has no variation")
+	}
+	return
+}
+
+/* ONE PLOTTED POINT; Value IS THE SUBGROUP MEAN, THE INDIVIDUAL, OR THE EWMA STATISTIC */
+type SPCPoint struct {
+	X      int64   `json:"x"`
+	End    int64   `json:"end"`
+	N      int     `json:"n"`
+	Value  float64 `json:"value"`
+	Center float64 `json:"center"`
+	UCL    float64 `json:"ucl"`
+	LCL    float64 `json:"lcl"`
+
+	/* R, S OR MR; THE FIRST imr / ewma POINT HAS NO MOVING RANGE */
+	Spread       float64 `json:"spread"`
+	SpreadCenter float64 `json:"spread_center"`
+	SpreadUCL    float64 `json:"spread_ucl"`
+	SpreadLCL    float64 `json:"spread_lcl"`
+
+	Rules []string `json:"rules"` // EMPTY WHEN IN CONTROL
+}
+
+func (pt *SPCPoint) InControl() bool { return len(pt.Rules) == 0 }
+
+type SPCChart struct {
+	Chart  string     `json:"chart"`
+	Rules  string     `json:"rules"`
+	Limits SPCLimits  `json:"limits"`
+	Points []SPCPoint `json:"points"`
+	Flags  int        `json:"flags"` // POINTS OUT OF CONTROL
+}
+
+/* ESTIMATES LIMITS FROM base AND PLOTS sgs AGAINST THEM */
+func BuildSPCChart(cfg SPCConfig, base, sgs []Subgroup) (chart SPCChart, err error) {
+
+	cfg.Defaults()
+	lim, err := SPCEstimate(cfg, base)
+	if err != nil {
+		return
+	}
+
+	chart = SPCChart{Chart: cfg.Chart, Rules: cfg.Rules, Limits: lim, Points: []SPCPoint{}}
+	mon := NewSPCMonitor(cfg, lim)
+	for _, sg := range sgs {
+		pt := mon.Next(sg)
+		if !pt.InControl() {
+			chart.Flags++
+		}
+		chart.Points = append(chart.Points, pt)
+	}
+	return
+}
+
+/* PLOTS SUBGROUPS ONE AT A TIME AGAINST FIXED LIMITS, KEEPING THE HISTORY THE RUN RULES NEED */
+type SPCMonitor struct {
+	Config SPCConfig
+	Limits SPCLimits
+
+	vals []float64 // LAST SPC_RULE_HISTORY VALUES AND THEIR ZONE SCORES
+	zs   []float64
+	prev *Subgroup
+	ewma float64
+	i    int
+}
+
+const SPC_RULE_HISTORY int = 15
+
+func NewSPCMonitor(cfg SPCConfig, lim SPCLimits) *SPCMonitor {
+	cfg.Defaults()
+	return &SPCMonitor{Config: cfg, Limits: lim, ewma: lim.Center}
+}
+
+func (mon *SPCMonitor) Next(sg Subgroup) (pt SPCPoint) {
+
+	cfg, lim := mon.Config, mon.Limits
+	pt = SPCPoint{X: sg.X, End: sg.End, N: sg.N, Value: sg.Mean, Center: lim.Center, Rules: []string{}}
+	mon.i++
+
+	/* LOCATION */
+	sigma := lim.Sigma
+	switch cfg.Chart {
+	case SPC_CHART_XBAR_R, SPC_CHART_XBAR_S:
+		sigma /= math.Sqrt(float64(sg.N))
+	case SPC_CHART_EWMA:
+		mon.ewma = cfg.Lambda*sg.Mean + (1-cfg.Lambda)*mon.ewma
+		pt.Value = mon.ewma
+		sigma *= math.Sqrt(cfg.Lambda / (2 - cfg.Lambda) * (1 - math.Pow(1-cfg.Lambda, 2*float64(mon.i))))
+	}
+	width := 3.0
+	if cfg.Chart == SPC_CHART_EWMA {
+		width = cfg.L
+	}
+	pt.UCL = lim.Center + width*sigma
+	pt.LCL = lim.Center - width*sigma
+
+	/* DISPERSION */
+	spread := false
+	switch cfg.Chart {
+	case SPC_CHART_XBAR_R:
+		if sg.N >= 2 && sg.N <= len(SPC_D2)+1 {
+			d2, d3 := SPC_D2[sg.N-2], SPC_D3[sg.N-2]
+			pt.Spread = sg.Range
+			pt.SpreadCenter = d2 * lim.Sigma
+			pt.SpreadUCL = (d2 + 3*d3) * lim.Sigma
+			pt.SpreadLCL = math.Max(0, (d2-3*d3)*lim.Sigma)
+			spread = true
+		}
+	case SPC_CHART_XBAR_S:
+		if sg.N >= 2 {
+			c4 := SPCC4(sg.N)
+			pt.Spread = sg.Devi
+			pt.SpreadCenter = c4 * lim.Sigma
+			pt.SpreadUCL = (c4 + 3*math.Sqrt(1-c4*c4)) * lim.Sigma
+			pt.SpreadLCL = math.Max(0, (c4-3*math.Sqrt(1-c4*c4))*lim.Sigma)
+			spread = true
+		}
+	case SPC_CHART_IMR, SPC_CHART_EWMA:
+		pt.SpreadCenter = SPC_D2[0] * lim.Sigma
+		pt.SpreadUCL = (SPC_D2[0] + 3*SPC_D3[0]) * lim.Sigma
+		if mon.prev != nil {
+			pt.Spread = math.Abs(sg.Mean - mon.prev.Mean)
+			spread = true
+		}
+	}
+	prev := sg
+	mon.prev = &prev
+
+	if spread && (pt.Spread > pt.SpreadUCL || pt.Spread < pt.SpreadLCL) {
+		pt.Rules = append(pt.Rules, SPC_RULE_SPREAD)
+	}
+
+	/* ZONE AND RUN RULES; EWMA POINTS ARE AUTOCORRELATED SO ONLY ITS LIMITS APPLY */
+	z := (pt.Value - lim.Center) / sigma
+	if cfg.Chart == SPC_CHART_EWMA {
+		if math.Abs(z) > cfg.L {
+			pt.Rules = append(pt.Rules, SPC_RULE_EWMA)
+		}
+		return
+	}
+
+	mon.vals = append(mon.vals, pt.Value)
+	mon.zs = append(mon.zs, z)
+	if len(mon.zs) > SPC_RULE_HISTORY {
+		mon.vals = mon.vals[1:]
+		mon.zs = mon.zs[1:]
+	}
+	if cfg.Rules == SPC_RULES_NELSON {
+		pt.Rules = append(pt.Rules, NelsonRules(mon.vals, mon.zs)...)
+	} else {
+		pt.Rules = append(pt.Rules, WesternElectricRules(mon.zs)...)
+	}
+	return
+}
+
+/* RULES COMPLETED BY THE LAST OF zs, THE ZONE SCORES ( VALUE - CENTER ) / SIGMA, OLDEST FIRST */
+func WesternElectricRules(zs []float64) (fired []string) {
+	z := zs[len(zs)-1]
+	if math.Abs(z) > 3 {
+		fired = append(fired, "we1")
+	}
+	if beyond(zs, 3, 2, 2) {
+		fired = append(fired, "we2")
+	}
+	if beyond(zs, 5, 4, 1) {
+		fired = append(fired, "we3")
+	}
+	if sameSide(zs, 8) {
+		fired = append(fired, "we4")
+	}
+	return
+}
+
+/* AS WesternElectricRules; vals ARE THE PLOTTED VALUES, FOR THE TREND RULES */
+func NelsonRules(vals, zs []float64) (fired []string) {
+	z := zs[len(zs)-1]
+	if math.Abs(z) > 3 {
+		fired = append(fired, "n1")
+	}
+	if sameSide(zs, 9) {
+		fired = append(fired, "n2")
+	}
+	if trend(vals, 6) {
+		fired = append(fired, "n3")
+	}
+	if alternating(vals, 14) {
+		fired = append(fired, "n4")
+	}
+	if beyond(zs, 3, 2, 2) {
+		fired = append(fired, "n5")
+	}
+	if beyond(zs, 5, 4, 1) {
+		fired = append(fired, "n6")
+	}
+	if tail := last(zs, 15); tail != nil && all(tail, func(z float64) bool { return math.Abs(z) < 1 }) {
+		fired = append(fired, "n7")
+	}
+	if tail := last(zs, 8); tail != nil && all(tail, func(z float64) bool { return math.Abs(z) > 1 }) {
+		fired = append(fired, "n8")
+	}
+	return
+}
+
+/* THE LAST n OF vs, OR nil IF THERE ARE FEWER */
+func last(vs []float64, n int) []float64 {
+	if len(vs) < n {
+		return nil
+	}
+	return vs[len(vs)-n:]
+}
+
+func all(vs []float64, fn func(v float64) bool) bool {
+	for _, v := range vs {
+		if !fn(v) {
+			return false
+		}
+	}
+	return true
+}
+
+/* k OF THE LAST n BEYOND limit ON THE SAME SIDE AS THE NEWEST, WHICH MUST BE ONE OF THEM */
+func beyond(zs []float64, n, k int, limit float64) bool {
+	tail := last(zs, n)
+	if tail == nil {
+		return false
+	}
+	side := math.Copysign(1, tail[n-1])
+	if tail[n-1]*side <= limit {
+		return false
+	}
+	count := 0
+	for _, z := range tail {
+		if z*side > limit {
+			count++
+		}
+	}
+	return count >= k
+}
+
+func sameSide(zs []float64, n int) bool {
+	tail := last(zs, n)
+	return tail != nil && (all(tail, func(z float64) bool { return z > 0 }) || all(tail, func(z float64) bool { return z < 0 }))
+}
+
+/* n POINTS, EACH ABOVE ( OR EACH BELOW ) THE ONE BEFORE */
+func trend(vals []float64, n int) bool {
+	tail := last(vals, n)
+	if tail == nil {
+		return false
+	}
+	up, down := true, true
+	for i := 1; i < n; i++ {
+		up = up && tail[i] > tail[i-1]
+		down = down && tail[i] < tail[i-1]
+	}
+	return up || down
+}
+
+func alternating(vals []float64, n int) bool {
+	tail := last(vals, n)
+	if tail == nil {
+		return false
+	}
+	for i := 2; i < n; i++ {
+		if (tail[i]-tail[i-1])*(tail[i-1]-tail[i-2]) >= 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"slices"
+	"testing"
+)
+
+/* n COPIES OF THE PATTERN ps, REPEATED */
+func repeat(n int, ps ...float64) (vs []float64) {
+	for i := 0; i < n; i++ {
+		vs = append(vs, ps[i%len(ps)])
+	}
+	return
+}
+
+func TestWesternElectricRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		zs    []float64
+		fired []string
+	}{
+		{"in control", []float64{0.5, -0.5, 0.2}, nil},
+		{"beyond 3 sigma", []float64{0.5, 3.5}, []string{"we1"}},
+		{"2 of 3 beyond 2 sigma", []float64{0, 2.5, 2.2}, []string{"we2"}},
+		{"2 of 3 on opposite sides", []float64{-2.5, 0, 2.2}, nil},
+		{"4 of 5 beyond 1 sigma", []float64{1.5, 1.2, 0.5, 1.8, 1.1}, []string{"we3"}},
+		{"8 on one side", repeat(8, 0.5), []string{"we4"}},
+		{"7 on one side", append([]float64{-0.5}, repeat(7, 0.5)...), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if fired := WesternElectricRules(tt.zs); !slices.Equal(fired, tt.fired) {
+				t.Fatalf("fired %v, want %v", fired, tt.fired)
+			}
+		})
+	}
+}
+
+func TestNelsonRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		vals  []float64
+		zs    []float64
+		fired []string
+	}{
+		{"6 increasing", []float64{1, 2, 3, 4, 5, 6}, repeat(6, 0.1, -0.1), []string{"n3"}},
+		{"14 alternating", repeat(14, 0, 1), repeat(14, 0.5, -0.5), []string{"n4"}},
+		{"15 within 1 sigma", repeat(15, 0, 0, 1, 1), repeat(15, 0.5, 0.5, -0.5, -0.5), []string{"n7"}},
+		{"8 beyond 1 sigma", repeat(8, 0, 0, 1, 1), repeat(8, -1.5, 1.5), []string{"n8"}},
+		{"9 on one side", repeat(9, 0, 0, 1, 1), repeat(9, 0.5), []string{"n2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if fired := NelsonRules(tt.vals, tt.zs); !slices.Equal(fired, tt.fired) {
+				t.Fatalf("fired %v, want %v", fired, tt.fired)
+			}
+		})
+	}
+}
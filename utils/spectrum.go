@@ -0,0 +1,258 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"sort"
+
+	"gonum.org/v1/gonum/dsp/fourier"
+	"gonum.org/v1/gonum/dsp/window"
+)
+
+const PSD_WINDOW_HANN string = "hann"
+const PSD_WINDOW_HAMMING string = "hamming"
+const PSD_WINDOW_BLACKMAN string = "blackman"
+const PSD_WINDOW_RECT string = "rect"
+
+const PSD_DETREND_NONE string = "none"
+const PSD_DETREND_CONSTANT string = "constant"
+const PSD_DETREND_LINEAR string = "linear"
+
+/* DEFAULTS FOR ZERO OR nil PSDConfig FIELDS */
+const PSD_SEGMENT int = 256
+const PSD_OVERLAP float64 = 0.5
+const PSD_PEAKS int = 5
+
+const PSD_MIN_SEGMENT int = 8
+const PSD_MAX_SEGMENT int = 1 << 16
+const PSD_MAX_PEAKS int = 50
+
+/* HOP AT LEAST AN EIGHTH OF A SEGMENT; MORE OVERLAP ONLY MULTIPLIES THE FFTs */
+const PSD_MAX_OVERLAP float64 = 0.875
+
+var PSD_WINDOWS = map[string]func([]float64) []float64{
+	PSD_WINDOW_HANN:     window.Hann,
+	PSD_WINDOW_HAMMING:  window.Hamming,
+	PSD_WINDOW_BLACKMAN: window.Blackman,
+	PSD_WINDOW_RECT:     window.Rectangular,
+}
+
+/*
+WELCH'S METHOD: Segment-LONG SEGMENTS OVERLAPPING BY Overlap, EACH DETRENDED AND WINDOWED,
+THEIR PERIODOGRAMS AVERAGED; Rate IS THE SAMPLE RATE OF THE EVENLY SPACED INPUT
+*/
+type PSDConfig struct {
+	Rate    float64  `json:"rate"`    // Hz
+	Segment int      `json:"segment"` // SAMPLES PER SEGMENT
+	Overlap *float64 `json:"overlap"` // FRACTION OF A SEGMENT, [ 0, PSD_MAX_OVERLAP ]; nil IS PSD_OVERLAP
+	Window  string   `json:"window"`  // hann ( DEFAULT ) | hamming | blackman | rect
+	Detrend string   `json:"detrend"` // linear ( DEFAULT ) | constant | none
+	Peaks   int      `json:"peaks"`   // DOMINANT FREQUENCIES TO REPORT
+}
+
+func (cfg *PSDConfig) Defaults() {
+	if cfg.Segment == 0 {
+		cfg.Segment = PSD_SEGMENT
+	}
+	if cfg.Overlap == nil {
+		cfg.Overlap = ptr(PSD_OVERLAP)
+	}
+	if cfg.Window == "" {
+		cfg.Window = PSD_WINDOW_HANN
+	}
+	if cfg.Detrend == "" {
+		cfg.Detrend = PSD_DETREND_LINEAR
+	}
+	if cfg.Peaks == 0 {
+		cfg.Peaks = PSD_PEAKS
+	}
+}
+
+func (cfg *PSDConfig) Validate() (err error) {
+	if _, ok := PSD_WINDOWS[cfg.Window]; cfg.Window != "" && !ok {
+		return fmt.Errorf("invalid psd window: %s", cfg.Window)
+	}
+	switch cfg.Detrend {
+	case "", PSD_DETREND_NONE, PSD_DETREND_CONSTANT, PSD_DETREND_LINEAR:
+	default:
+		return fmt.Errorf("invalid psd detrend: %s", cfg.Detrend)
+	}
+	switch {
+	case !(cfg.Rate > 0) || math.IsInf(cfg.Rate, 0):
+		err = fmt.Errorf("psd rate must be positive")
+	case cfg.Segment != 0 && (cfg.Segment < PSD_MIN_SEGMENT || cfg.Segment > PSD_MAX_SEGMENT):
+		err = fmt.Errorf("psd segment must be %d to %d samples", PSD_MIN_SEGMENT, PSD_MAX_SEGMENT)
+	case cfg.Overlap != nil && !(*cfg.Overlap >= 0 && *cfg.Overlap <= PSD_MAX_OVERLAP):
+		err = fmt.Errorf("psd overlap must be 0 to %g", PSD_MAX_OVERLAP)
+	case cfg.Peaks < 0 || cfg.Peaks > PSD_MAX_PEAKS:
+		err = fmt.Errorf("psd peaks must be 0 to %d", PSD_MAX_PEAKS)
+	}
+	return
+}
+
+type SpectralPoint struct {
+	Freq  float64 `json:"freq"`  // Hz
+	Power float64 `json:"power"` // UNIT^2 / Hz
+}
+
+/* Ratio IS Power OVER THE MEDIAN POWER OF THE SPECTRUM, A ROUGH SIGNAL TO NOISE */
+type SpectralPeak struct {
+	Freq  float64 `json:"freq"` // Hz, INTERPOLATED BETWEEN BINS
+	Power float64 `json:"power"`
+	Ratio float64 `json:"ratio"`
+}
+
+/* ONE SIDED POWER SPECTRAL DENSITY */
+type Spectrum struct {
+	Rate       float64         `json:"rate"`       // Hz
+	Resolution float64         `json:"resolution"` // Hz BETWEEN BINS
+	Segments   int             `json:"segments"`   // AVERAGED
+	Skipped    int             `json:"skipped"`    // SEGMENTS WITH MISSING SAMPLES
+	Data       []SpectralPoint `json:"data"`
+	Peaks      []SpectralPeak  `json:"peaks"`
+}
+
+/* PSD OF EVENLY SPACED ys; NaN MARKS A MISSING SAMPLE AND SEGMENTS HOLDING ONE ARE LEFT OUT */
+func Welch(ys []float64, cfg PSDConfig) (sp Spectrum, err error) {
+
+	if err = cfg.Validate(); err != nil {
+		return
+	}
+	cfg.Defaults()
+
+	n := cfg.Segment
+	if len(ys) < n {
+		err = fmt.Errorf("psd needs at least one segment of %d samples, has %d", n, len(ys))
+		return
+	}
+	hop := max(1, int(float64(n)*(1-*cfg.Overlap)))
+
+	/* WINDOW POWER, FOR DENSITY SCALING */
+	win := make([]float64, n)
+	for i := range win {
+		win[i] = 1
+	}
+	PSD_WINDOWS[cfg.Window](win)
+	var wss float64
+	for _, w := range win {
+		wss += w * w
+	}
+
+	xs := make([]float64, n)
+	for i := range xs {
+		xs[i] = float64(i)
+	}
+	fft := fourier.NewFFT(n)
+	bins := n/2 + 1
+	power := make([]float64, bins)
+	seg := make([]float64, n)
+	coef := make([]complex128, bins)
+
+	for at := 0; at+n <= len(ys); at += hop {
+		copy(seg, ys[at:at+n])
+		if !finite(seg) {
+			sp.Skipped++
+			continue
+		}
+		if err = detrend(xs, seg, cfg.Detrend); err != nil {
+			return
+		}
+		for i := range seg {
+			seg[i] *= win[i]
+		}
+		fft.Coefficients(coef, seg)
+		for k, c := range coef {
+			power[k] += real(c * cmplx.Conj(c))
+		}
+		sp.Segments++
+	}
+	if sp.Segments == 0 {
+		err = fmt.Errorf("psd has no segment of %d samples without gaps", n)
+		return
+	}
+
+	sp.Rate = cfg.Rate
+	sp.Resolution = cfg.Rate / float64(n)
+	sp.Data = make([]SpectralPoint, bins)
+	for k := range power {
+		p := power[k] / (float64(sp.Segments) * cfg.Rate * wss)
+		/* FOLD THE NEGATIVE FREQUENCIES; DC AND NYQUIST HAVE NONE */
+		if k > 0 && !(n%2 == 0 && k == bins-1) {
+			p *= 2
+		}
+		sp.Data[k] = SpectralPoint{Freq: float64(k) * sp.Resolution, Power: p}
+	}
+	sp.Peaks = DominantFrequencies(sp.Data, cfg.Peaks)
+	return
+}
+
+func finite(vs []float64) bool {
+	for _, v := range vs {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+/* SUBTRACTS THE MEAN OR LEAST SQUARES LINE FROM ys IN PLACE */
+func detrend(xs, ys []float64, how string) (err error) {
+	degree := 1
+	switch how {
+	case PSD_DETREND_NONE:
+		return
+	case PSD_DETREND_CONSTANT:
+		degree = 0
+	}
+	fit, err := PolyFit(xs, ys, degree)
+	if err != nil {
+		return
+	}
+	for i := range ys {
+		ys[i] -= fit.Predict(xs[i])
+	}
+	return
+}
+
+/*
+THE k STRONGEST LOCAL MAXIMA ABOVE DC, STRONGEST FIRST
+EACH FREQUENCY IS REFINED BY A PARABOLA THROUGH THE LOG POWER OF THE PEAK BIN AND ITS NEIGHBOURS
+*/
+func DominantFrequencies(data []SpectralPoint, k int) (peaks []SpectralPeak) {
+
+	peaks = []SpectralPeak{}
+	if len(data) < 3 || k <= 0 {
+		return
+	}
+
+	ps := make([]float64, len(data))
+	for i := range data {
+		ps[i] = data[i].Power
+	}
+	med := Median(ps)
+
+	for i := 1; i < len(data)-1; i++ {
+		p := data[i].Power
+		if p <= 0 || p <= data[i-1].Power || p < data[i+1].Power {
+			continue
+		}
+		peak := SpectralPeak{Freq: data[i].Freq, Power: p}
+		if data[i-1].Power > 0 && data[i+1].Power > 0 {
+			a, b, c := math.Log(data[i-1].Power), math.Log(p), math.Log(data[i+1].Power)
+			if den := a - 2*b + c; den < 0 {
+				peak.Freq += 0.5 * (a - c) / den * (data[i+1].Freq - data[i].Freq)
+			}
+		}
+		if med > 0 {
+			peak.Ratio = p / med
+		}
+		peaks = append(peaks, peak)
+	}
+
+	sort.Slice(peaks, func(a, b int) bool { return peaks[a].Power > peaks[b].Power })
+	if len(peaks) > k {
+		peaks = peaks[:k]
+	}
+	return
+}
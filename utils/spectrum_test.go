@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWelchSine(t *testing.T) {
+
+	/* 2 sin( 2 pi 12.5 t ) AT 100 Hz; ITS POWER IS 2 */
+	const rate, freq, amp = 100.0, 12.5, 2.0
+	ys := make([]float64, 4096)
+	for i := range ys {
+		ys[i] = amp * math.Sin(2*math.Pi*freq*float64(i)/rate)
+	}
+	gappy := append([]float64{}, ys...)
+	gappy[300] = math.NaN()
+
+	tests := []struct {
+		name     string
+		ys       []float64
+		overlap  *float64
+		segments int
+		skipped  int
+	}{
+		{"half overlap", ys, nil, 31, 0},
+		{"no overlap", ys, ptr(0.0), 16, 0},
+		{"most overlap", ys, ptr(PSD_MAX_OVERLAP), 121, 0},
+		{"one gap", gappy, ptr(0.0), 15, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp, err := Welch(tt.ys, PSDConfig{Rate: rate, Overlap: tt.overlap})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sp.Segments != tt.segments || sp.Skipped != tt.skipped {
+				t.Fatalf("%d segments / %d skipped, want %d / %d", sp.Segments, sp.Skipped, tt.segments, tt.skipped)
+			}
+			if len(sp.Peaks) == 0 || math.Abs(sp.Peaks[0].Freq-freq) > sp.Resolution/2 {
+				t.Fatalf("peaks %v, want one at %g Hz", sp.Peaks, freq)
+			}
+			var total float64
+			for _, pt := range sp.Data {
+				total += pt.Power * sp.Resolution
+			}
+			if want := amp * amp / 2; math.Abs(total-want) > 0.05*want {
+				t.Fatalf("total power %g, want %g", total, want)
+			}
+		})
+	}
+}
+
+func TestWelchErrors(t *testing.T) {
+	ys := make([]float64, 100)
+	tests := []struct {
+		name string
+		ys   []float64
+		cfg  PSDConfig
+	}{
+		{"no rate", ys, PSDConfig{}},
+		{"shorter than a segment", ys, PSDConfig{Rate: 1}},
+		{"overlap too high", make([]float64, 1000), PSDConfig{Rate: 1, Overlap: ptr(0.9)}},
+		{"segment too short", ys, PSDConfig{Rate: 1, Segment: PSD_MIN_SEGMENT - 1}},
+		{"unknown window", make([]float64, 1000), PSDConfig{Rate: 1, Window: "triangle"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Welch(tt.ys, tt.cfg); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const TIME_FMT_AUTO string = "auto"
+const TIME_FMT_RFC3339 string = "rfc3339"
+const TIME_FMT_UNIX string = "unix" // SECONDS, MAY HAVE A FRACTION
+const TIME_FMT_UNIX_MS string = "unix_ms"
+const TIME_FMT_UNIX_US string = "unix_us"
+const TIME_FMT_UNIX_NS string = "unix_ns"
+const TIME_FMT_EXCEL string = "excel" // DAYS SINCE 1899-12-30, AS SPREADSHEETS EXPORT THEM
+
+/* TRIED IN ORDER BY TIME_FMT_AUTO; ParseInLocation HONOURS AN OFFSET WHEN THE LAYOUT HAS ONE */
+var TIME_LAYOUTS = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006/01/02 15:04:05.999999999",
+	"01/02/2006 15:04:05.999999999",
+	"01/02/2006 15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+/* LOADS AN IANA ZONE; BLANK MEANS UTC */
+func LoadTimeZone(name string) (loc *time.Location, err error) {
+	if strings.TrimSpace(name) == "" {
+		return time.UTC, nil
+	}
+	if loc, err = time.LoadLocation(name); err != nil {
+		err = fmt.Errorf("invalid time zone: %s", name)
+	}
+	return
+}
+
+/* VALIDATES A time_format; ANYTHING NOT NAMED ABOVE IS TREATED AS A GO LAYOUT */
+func ValidateTimeFormat(format string) (err error) {
+	switch format {
+	case "", TIME_FMT_AUTO, TIME_FMT_RFC3339, TIME_FMT_UNIX, TIME_FMT_UNIX_MS, TIME_FMT_UNIX_US, TIME_FMT_UNIX_NS, TIME_FMT_EXCEL:
+		return
+	}
+	if !strings.Contains(format, "2006") && !strings.Contains(format, "06") {
+		err = fmt.Errorf("invalid time format: %s", format)
+	}
+	return
+}
+
+/* PARSES A TIMESTAMP TO UTC MILLISECONDS; loc APPLIES WHEN THE TEXT CARRIES NO OFFSET */
+func ParseTimestamp(str, format string, loc *time.Location) (ms int64, err error) {
+
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return 0, fmt.Errorf("blank timestamp")
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	switch format {
+
+	case "", TIME_FMT_AUTO:
+		if num, num_err := strconv.ParseFloat(str, 64); num_err == nil {
+			return unixGuessMilli(num), nil
+		}
+		for _, layout := range TIME_LAYOUTS {
+			if t, t_err := time.ParseInLocation(layout, str, loc); t_err == nil {
+				return t.UnixMilli(), nil
+			}
+		}
+		return 0, fmt.Errorf("unrecognized timestamp: %s", str)
+
+	case TIME_FMT_RFC3339:
+		t, t_err := time.ParseInLocation(time.RFC3339Nano, str, loc)
+		if t_err != nil {
+			return 0, fmt.Errorf("invalid rfc3339 timestamp: %s", str)
+		}
+		return t.UnixMilli(), nil
+
+	case TIME_FMT_UNIX, TIME_FMT_UNIX_MS, TIME_FMT_UNIX_US, TIME_FMT_UNIX_NS, TIME_FMT_EXCEL:
+		num, num_err := strconv.ParseFloat(str, 64)
+		if num_err != nil || math.IsNaN(num) || math.IsInf(num, 0) {
+			return 0, fmt.Errorf("invalid %s timestamp: %s", format, str)
+		}
+		return NumericTimestampMilli(num, format)
+	}
+
+	t, t_err := time.ParseInLocation(format, str, loc)
+	if t_err != nil {
+		return 0, fmt.Errorf("timestamp %s does not match layout %s", str, format)
+	}
+	return t.UnixMilli(), nil
+}
+
+/* FOR SOURCES THAT HAND US NUMBERS ( NDJSON ); TIME_FMT_AUTO GUESSES THE UNIT FROM THE MAGNITUDE */
+func NumericTimestampMilli(num float64, format string) (ms int64, err error) {
+	switch format {
+	case "", TIME_FMT_AUTO:
+		return unixGuessMilli(num), nil
+	case TIME_FMT_UNIX:
+		return int64(math.Round(num * 1e3)), nil
+	case TIME_FMT_UNIX_MS:
+		return int64(math.Round(num)), nil
+	case TIME_FMT_UNIX_US:
+		return int64(math.Round(num / 1e3)), nil
+	case TIME_FMT_UNIX_NS:
+		return int64(math.Round(num / 1e6)), nil
+	case TIME_FMT_EXCEL:
+		return excelEpoch.UnixMilli() + int64(math.Round(num*86400e3)), nil
+	}
+	return 0, fmt.Errorf("numeric timestamp given for time format %s", format)
+}
+
+/* SECONDS UNTIL YEAR ~5138, THEN MILLI, MICRO, NANO */
+func unixGuessMilli(num float64) int64 {
+	abs := math.Abs(num)
+	switch {
+	case abs < 1e11:
+		return int64(math.Round(num * 1e3))
+	case abs < 1e14:
+		return int64(math.Round(num))
+	case abs < 1e17:
+		return int64(math.Round(num / 1e3))
+	}
+	return int64(math.Round(num / 1e6))
+}
+
+/* FORMATS UTC MILLISECONDS FOR EXPORT; BLANK OR auto IS RFC3339 WITH MILLISECONDS IN loc */
+func FormatTimestamp(ms int64, format string, loc *time.Location) string {
+
+	if loc == nil {
+		loc = time.UTC
+	}
+	t := time.UnixMilli(ms).In(loc)
+
+	switch format {
+	case "", TIME_FMT_AUTO, TIME_FMT_RFC3339:
+		return t.Format("2006-01-02T15:04:05.000Z07:00")
+	case TIME_FMT_UNIX:
+		return strconv.FormatFloat(float64(ms)/1e3, 'f', -1, 64)
+	case TIME_FMT_UNIX_MS:
+		return strconv.FormatInt(ms, 10)
+	case TIME_FMT_UNIX_US:
+		return strconv.FormatInt(ms*1e3, 10)
+	case TIME_FMT_UNIX_NS:
+		return strconv.FormatInt(ms*1e6, 10)
+	case TIME_FMT_EXCEL:
+		/* SPREADSHEETS HAVE NO ZONES; THE SERIAL IS THE WALL CLOCK IN loc */
+		_, offset := t.Zone()
+		wall := ms + int64(offset)*1e3
+		return strconv.FormatFloat(float64(wall-excelEpoch.UnixMilli())/86400e3, 'f', -1, 64)
+	}
+	return t.Format(format)
+}
+
+/* FORMATS THAT COME OUT OF FormatTimestamp AS NUMBERS */
+func IsNumericTimeFormat(format string) bool {
+	switch format {
+	case TIME_FMT_UNIX, TIME_FMT_UNIX_MS, TIME_FMT_UNIX_US, TIME_FMT_UNIX_NS, TIME_FMT_EXCEL:
+		return true
+	}
+	return false
+}
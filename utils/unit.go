@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+/* base = v * Scale + Offset, IN THE DIMENSION'S BASE UNIT */
+type UnitDef struct {
+	Dimension string
+	Scale     float64
+	Offset    float64
+}
+
+var UNITS = map[string]UnitDef{
+	/* TEMPERATURE ( °C ) */
+	"C": {"temperature", 1, 0},
+	"F": {"temperature", 5.0 / 9.0, -32 * 5.0 / 9.0},
+	"K": {"temperature", 1, -273.15},
+
+	/* PRESSURE ( Pa ) */
+	"Pa":    {"pressure", 1, 0},
+	"kPa":   {"pressure", 1e3, 0},
+	"MPa":   {"pressure", 1e6, 0},
+	"bar":   {"pressure", 1e5, 0},
+	"mbar":  {"pressure", 1e2, 0},
+	"psi":   {"pressure", 6894.757293168, 0},
+	"atm":   {"pressure", 101325, 0},
+	"mmHg":  {"pressure", 133.322387415, 0},
+	"inH2O": {"pressure", 249.08891, 0},
+
+	/* LENGTH ( m ) */
+	"m":  {"length", 1, 0},
+	"mm": {"length", 1e-3, 0},
+	"cm": {"length", 1e-2, 0},
+	"um": {"length", 1e-6, 0},
+	"km": {"length", 1e3, 0},
+	"in": {"length", 0.0254, 0},
+	"ft": {"length", 0.3048, 0},
+
+	/* MASS ( kg ) */
+	"kg": {"mass", 1, 0},
+	"g":  {"mass", 1e-3, 0},
+	"mg": {"mass", 1e-6, 0},
+	"lb": {"mass", 0.45359237, 0},
+	"oz": {"mass", 0.028349523125, 0},
+
+	/* VOLUME ( L ) */
+	"L":   {"volume", 1, 0},
+	"mL":  {"volume", 1e-3, 0},
+	"m3":  {"volume", 1e3, 0},
+	"gal": {"volume", 3.785411784, 0},
+
+	/* FLOW ( L/min ) */
+	"L/min":  {"flow", 1, 0},
+	"L/h":    {"flow", 1.0 / 60, 0},
+	"mL/min": {"flow", 1e-3, 0},
+	"m3/h":   {"flow", 1e3 / 60, 0},
+	"gpm":    {"flow", 3.785411784, 0},
+
+	/* TIME ( s ) */
+	"s":   {"time", 1, 0},
+	"ms":  {"time", 1e-3, 0},
+	"min": {"time", 60, 0},
+	"h":   {"time", 3600, 0},
+
+	/* ELECTRICAL */
+	"V":  {"voltage", 1, 0},
+	"mV": {"voltage", 1e-3, 0},
+	"kV": {"voltage", 1e3, 0},
+	"A":  {"current", 1, 0},
+	"mA": {"current", 1e-3, 0},
+	"uA": {"current", 1e-6, 0},
+	"W":  {"power", 1, 0},
+	"kW": {"power", 1e3, 0},
+
+	/* FREQUENCY ( Hz ) */
+	"Hz":  {"frequency", 1, 0},
+	"kHz": {"frequency", 1e3, 0},
+	"rpm": {"frequency", 1.0 / 60, 0},
+
+	/* RATIO */
+	"%":   {"ratio", 1e-2, 0},
+	"ppm": {"ratio", 1e-6, 0},
+}
+
+/* SPELLINGS SEEN IN INSTRUMENT EXPORTS */
+var UNIT_ALIASES = map[string]string{
+	"°C": "C", "degC": "C", "celsius": "C",
+	"°F": "F", "degF": "F", "fahrenheit": "F",
+	"kelvin": "K",
+	"µm":     "um", "µA": "uA",
+	"l": "L", "ml": "mL",
+	"lpm": "L/min", "l/min": "L/min",
+	"sec": "s", "hr": "h",
+	"percent": "%", "pct": "%",
+	"lbs":  "lb",
+	"psig": "psi",
+}
+
+func NormalizeUnit(unit string) string {
+	unit = strings.TrimSpace(unit)
+	if alias, ok := UNIT_ALIASES[unit]; ok {
+		return alias
+	}
+	return unit
+}
+
+/* RETURNS A FUNCTION CONVERTING from -> to; BLANK OR EQUAL UNITS CONVERT AS IDENTITY */
+func UnitConverter(from, to string) (conv func(float64) float64, err error) {
+
+	from, to = NormalizeUnit(from), NormalizeUnit(to)
+	if from == "" || to == "" || from == to {
+		return func(v float64) float64 { return v }, nil
+	}
+
+	a, a_ok := UNITS[from]
+	b, b_ok := UNITS[to]
+	if !a_ok || !b_ok {
+		return nil, fmt.Errorf("cannot convert unit %s to %s: unknown unit", from, to)
+	}
+	if a.Dimension != b.Dimension {
+		return nil, fmt.Errorf("cannot convert unit %s ( %s ) to %s ( %s )", from, a.Dimension, to, b.Dimension)
+	}
+
+	return func(v float64) float64 {
+		return (v*a.Scale + a.Offset - b.Offset) / b.Scale
+	}, nil
+}
+
+func ConvertUnit(v float64, from, to string) (out float64, err error) {
+	conv, err := UnitConverter(from, to)
+	if err != nil {
+		return
+	}
+	return conv(v), nil
+}